@@ -0,0 +1,201 @@
+package appdir
+
+import (
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	// AppName 数据目录名称
+	AppName = "tiktok_tool"
+
+	// PortableMarkerName 程序所在目录存在该文件时使用便携模式, 所有数据保存在程序所在目录
+	PortableMarkerName = "portable.txt"
+
+	// 已迁移旧版本数据的标记文件
+	migratedMarkerName = ".migrated"
+)
+
+// 旧版本保存在工作目录下的文件和目录
+var legacyItems = []string{"config", "logs", "plugin", "tiktok_tool_cfg.toml", "panic_error.log"}
+
+// 旧版本的配置文件, 工作目录下存在其中之一时才认为是旧版本的数据
+var legacyConfigFiles = []string{filepath.Join("config", "tiktok_tool_cfg.toml"), "tiktok_tool_cfg.toml"}
+
+var (
+	rootOnce sync.Once
+	root     string
+	portable bool
+)
+
+// Root 数据根目录
+// 便携模式为程序所在目录, 否则 Windows 为 %APPDATA%\tiktok_tool, Linux 为 $XDG_CONFIG_HOME/tiktok_tool
+func Root() string {
+	rootOnce.Do(func() {
+		exeDir := executableDir()
+		if _, err := os.Stat(filepath.Join(exeDir, PortableMarkerName)); err == nil {
+			root, portable = exeDir, true
+			return
+		}
+		if dir, err := os.UserConfigDir(); err == nil {
+			root = filepath.Join(dir, AppName)
+			return
+		}
+		root, portable = exeDir, true
+	})
+	return root
+}
+
+// IsPortable 是否为便携模式
+func IsPortable() bool {
+	Root()
+	return portable
+}
+
+// Join 拼接数据根目录下的路径
+func Join(elem ...string) string {
+	return filepath.Join(append([]string{Root()}, elem...)...)
+}
+
+// Resolve 相对路径按数据根目录解析, 绝对路径保持不变
+func Resolve(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	return Join(path)
+}
+
+// executableDir 程序所在目录, 获取失败时使用工作目录
+func executableDir() string {
+	if exe, err := os.Executable(); err == nil {
+		if exe, err = filepath.EvalSymlinks(exe); err == nil {
+			return filepath.Dir(exe)
+		}
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// Init 创建数据根目录, 首次运行时将程序所在目录和工作目录下旧版本的数据迁移到数据根目录
+// 返回已迁移的文件, 日志系统尚未初始化, 由调用方在初始化日志后记录
+func Init() ([]string, error) {
+	dir := Root()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("创建数据目录失败: %v", err)
+	}
+
+	marker := filepath.Join(dir, migratedMarkerName)
+	if _, err := os.Stat(marker); err == nil {
+		return nil, nil
+	}
+
+	wd, _ := os.Getwd()
+	migrated, err := migrateLegacy(legacySources(executableDir(), wd), dir)
+	if err != nil {
+		return migrated, err
+	}
+	return migrated, os.WriteFile(marker, nil, 0644)
+}
+
+// legacySources 需要迁移旧版本数据的目录
+// 程序目录和工作目录下都可能有无关的 config、logs 目录(如放在公共目录或从命令行启动), 只有存在旧版本的配置文件时才迁移
+func legacySources(exeDir, wd string) []string {
+	var sources []string
+	for _, dir := range []string{exeDir, wd} {
+		if dir != "" && hasLegacyConfig(dir) {
+			sources = append(sources, dir)
+		}
+	}
+	return sources
+}
+
+// hasLegacyConfig 目录下是否有旧版本的配置文件
+func hasLegacyConfig(dir string) bool {
+	for _, name := range legacyConfigFiles {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// migrateLegacy 将旧版本的数据移动到数据根目录, 数据根目录中已存在的不会被覆盖
+func migrateLegacy(sources []string, dst string) ([]string, error) {
+	var migrated []string
+	seen := make(map[string]bool)
+	for _, src := range sources {
+		if src == "" || seen[src] || samePath(src, dst) {
+			continue
+		}
+		seen[src] = true
+
+		for _, item := range legacyItems {
+			from, to := filepath.Join(src, item), filepath.Join(dst, item)
+			if _, err := os.Stat(from); err != nil {
+				continue
+			}
+			if _, err := os.Stat(to); err == nil {
+				continue
+			}
+			if err := move(from, to); err != nil {
+				return migrated, fmt.Errorf("迁移 %s 失败: %v", from, err)
+			}
+			migrated = append(migrated, from)
+		}
+	}
+	return migrated, nil
+}
+
+// samePath 判断两个路径是否为同一目录
+func samePath(a, b string) bool {
+	infoA, errA := os.Stat(a)
+	infoB, errB := os.Stat(b)
+	if errA != nil || errB != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(infoA, infoB)
+}
+
+// move 移动文件或目录, 无法直接移动(如跨磁盘)时复制, 复制时保留原文件
+func move(from, to string) error {
+	if err := os.Rename(from, to); err == nil {
+		return nil
+	}
+	return filepath.WalkDir(from, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(from, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(to, rel)
+		if entry.IsDir() {
+			return os.MkdirAll(target, 0755)
+		}
+		return copyFile(path, target)
+	})
+}
+
+// copyFile 复制文件
+func copyFile(from, to string) error {
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	dst, err := os.Create(to)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	return dst.Close()
+}
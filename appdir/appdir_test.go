@@ -0,0 +1,66 @@
+package appdir
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMigrateLegacy(t *testing.T) {
+	legacy, dst := t.TempDir(), t.TempDir()
+	if err := os.MkdirAll(filepath.Join(legacy, "config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(legacy, "config", "tiktok_tool_cfg.toml"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// 数据根目录中已存在的不会被覆盖
+	if err := os.WriteFile(filepath.Join(legacy, "panic_error.log"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dst, "panic_error.log"), []byte("new"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := migrateLegacy([]string{legacy, legacy, dst}, dst)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrated) != 1 {
+		t.Errorf("迁移的文件错误: %v", migrated)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dst, "config", "tiktok_tool_cfg.toml")); string(content) != "old" {
+		t.Errorf("配置文件未迁移: %s", content)
+	}
+	if content, _ := os.ReadFile(filepath.Join(dst, "panic_error.log")); string(content) != "new" {
+		t.Errorf("已存在的文件被覆盖: %s", content)
+	}
+}
+
+func TestLegacySources(t *testing.T) {
+	exeDir, wd := t.TempDir(), t.TempDir()
+	// 程序目录下无关的 logs 目录和工作目录下无关的 config 目录不会被迁移
+	if err := os.MkdirAll(filepath.Join(exeDir, "logs"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(wd, "config"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if sources := legacySources(exeDir, wd); len(sources) != 0 {
+		t.Errorf("迁移目录错误: %v", sources)
+	}
+
+	if err := os.WriteFile(filepath.Join(wd, "config", "tiktok_tool_cfg.toml"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if sources := legacySources(exeDir, wd); len(sources) != 1 || sources[0] != wd {
+		t.Errorf("迁移目录错误: %v", sources)
+	}
+
+	if err := os.WriteFile(filepath.Join(exeDir, "tiktok_tool_cfg.toml"), []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if sources := legacySources(exeDir, wd); len(sources) != 2 || sources[0] != exeDir || sources[1] != wd {
+		t.Errorf("迁移目录错误: %v", sources)
+	}
+}
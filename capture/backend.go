@@ -0,0 +1,231 @@
+package capture
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"runtime"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"tiktok_tool/config"
+)
+
+const (
+	BackendPcap     = "pcap"     // libpcap / Npcap
+	BackendAFPacket = "afpacket" // Linux AF_PACKET, 不依赖libpcap
+	BackendReplay   = "replay"   // 从抓包文件(pcap/pcapng)回放
+	BackendMemory   = "memory"   // 内存数据, 用于测试
+
+	// 读取超时, 便于抓包循环及时响应停止信号
+	readTimeout = 200 * time.Millisecond
+)
+
+// ErrNpcapNotInstalled 未安装Npcap
+var ErrNpcapNotInstalled = errors.New("未安装Npcap")
+
+// Device 网卡信息
+type Device struct {
+	Name        string // 打开网卡时使用的名称
+	Description string // 展示给用户以及配置中保存的名称
+}
+
+func (d Device) String() string {
+	if d.Description == "" || d.Description == d.Name {
+		return d.Name
+	}
+	return fmt.Sprintf("%s(%s)", d.Description, d.Name)
+}
+
+// Filter 抓包过滤条件, 只抓取TCP数据包
+// Host/Port 为空时不做限制
+type Filter struct {
+	Host net.IP
+	Port uint16
+}
+
+// BPF 转换为BPF过滤表达式
+func (f Filter) BPF() string {
+	expr := "tcp"
+	if f.Host != nil {
+		expr += " and host " + f.Host.String()
+	}
+	if f.Port != 0 {
+		expr += fmt.Sprintf(" and port %d", f.Port)
+	}
+	return expr
+}
+
+// Match 在用户态判断数据包是否满足过滤条件, 用于不支持内核过滤的后端
+func (f Filter) Match(packet gopacket.Packet) bool {
+	tcpLayer := packet.Layer(layers.LayerTypeTCP)
+	if tcpLayer == nil {
+		return false
+	}
+	tcp, ok := tcpLayer.(*layers.TCP)
+	if !ok {
+		return false
+	}
+	if f.Port != 0 && uint16(tcp.SrcPort) != f.Port && uint16(tcp.DstPort) != f.Port {
+		return false
+	}
+	if f.Host == nil {
+		return true
+	}
+
+	netLayer := packet.NetworkLayer()
+	if netLayer == nil {
+		return false
+	}
+	flow := netLayer.NetworkFlow()
+	return f.Host.Equal(flow.Src().Raw()) || f.Host.Equal(flow.Dst().Raw())
+}
+
+// PacketSource 数据包来源, 由具体的抓包后端提供
+type PacketSource interface {
+	gopacket.PacketDataSource
+
+	// LinkType 链路层类型
+	LinkType() layers.LinkType
+	// SetFilter 设置过滤条件
+	SetFilter(filter Filter) error
+	// Close 关闭数据源, 之后 ReadPacketData 返回 io.EOF
+	Close()
+}
+
+// Backend 抓包后端
+type Backend interface {
+	// Name 后端名称
+	Name() string
+	// Available 检查后端在当前环境是否可用
+	Available() error
+	// Devices 列出可抓包的网卡
+	Devices() ([]Device, error)
+	// Open 打开网卡, snaplen 为单个数据包的最大抓取长度
+	Open(device Device, snaplen int) (PacketSource, error)
+}
+
+var (
+	backendMutex    sync.Mutex
+	backendFactory  = make(map[string]func() Backend)
+	overrideBackend Backend
+)
+
+// registerBackend 注册抓包后端, 在各实现文件的init中调用
+func registerBackend(name string, factory func() Backend) {
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+	backendFactory[name] = factory
+}
+
+// Backends 返回当前平台支持的后端名称
+func Backends() []string {
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+
+	names := make([]string, 0, len(backendFactory))
+	for name := range backendFactory {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SetBackend 强制使用指定的后端(主要用于测试), 传入nil恢复按配置选择
+func SetBackend(backend Backend) {
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+	overrideBackend = backend
+}
+
+// defaultBackendName 当前平台默认的后端
+func defaultBackendName() string {
+	if runtime.GOOS == "linux" {
+		return BackendAFPacket
+	}
+	return BackendPcap
+}
+
+// CurrentBackend 根据配置获取抓包后端
+func CurrentBackend() (Backend, error) {
+	backendMutex.Lock()
+	defer backendMutex.Unlock()
+
+	if overrideBackend != nil {
+		return overrideBackend, nil
+	}
+
+	name := config.GetConfig().BaseSettings.CaptureBackend
+	if name == "" {
+		name = defaultBackendName()
+	}
+	factory, ok := backendFactory[name]
+	if !ok && name == BackendPcap && runtime.GOOS == "linux" {
+		return nil, fmt.Errorf("当前程序未编译pcap后端, 请在配置中使用 capture_backend = \"afpacket\" 或使用 -tags pcap 重新编译")
+	}
+	if !ok {
+		return nil, fmt.Errorf("当前平台不支持抓包后端: %s", name)
+	}
+	return factory(), nil
+}
+
+// CheckBackend 检查当前配置的抓包后端是否可用
+func CheckBackend() error {
+	backend, err := CurrentBackend()
+	if err != nil {
+		return err
+	}
+	return backend.Available()
+}
+
+// ListDevices 列出当前后端可用的网卡
+func ListDevices() ([]Device, error) {
+	backend, err := CurrentBackend()
+	if err != nil {
+		return nil, err
+	}
+	return backend.Devices()
+}
+
+// rawSource 不支持过滤的原始数据源
+type rawSource interface {
+	gopacket.PacketDataSource
+	Close()
+}
+
+// filteredSource 在用户态过滤数据包的包装, 用于不支持BPF表达式的后端
+type filteredSource struct {
+	source   rawSource
+	linkType layers.LinkType
+	filter   *Filter
+}
+
+func (s *filteredSource) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	for {
+		data, ci, err := s.source.ReadPacketData()
+		if err != nil || s.filter == nil {
+			return data, ci, err
+		}
+		packet := gopacket.NewPacket(data, s.linkType, gopacket.DecodeOptions{Lazy: true, NoCopy: true})
+		if s.filter.Match(packet) {
+			return data, ci, nil
+		}
+	}
+}
+
+func (s *filteredSource) LinkType() layers.LinkType {
+	return s.linkType
+}
+
+func (s *filteredSource) SetFilter(filter Filter) error {
+	s.filter = &filter
+	return nil
+}
+
+func (s *filteredSource) Close() {
+	s.source.Close()
+}
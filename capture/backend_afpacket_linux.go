@@ -0,0 +1,113 @@
+package capture
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/afpacket"
+	"github.com/google/gopacket/layers"
+)
+
+func init() {
+	registerBackend(BackendAFPacket, func() Backend { return afpacketBackend{} })
+}
+
+// afpacketBackend 基于 Linux AF_PACKET 的抓包后端, 不依赖 libpcap
+type afpacketBackend struct{}
+
+func (afpacketBackend) Name() string {
+	return BackendAFPacket
+}
+
+func (afpacketBackend) Available() error {
+	// 以只打开一次socket的方式检查权限(需要root或CAP_NET_RAW)
+	handle, err := afpacket.NewTPacket(afpacket.OptPollTimeout(readTimeout))
+	if err != nil {
+		return fmt.Errorf("无法打开AF_PACKET套接字(需要root或CAP_NET_RAW权限): %v", err)
+	}
+	handle.Close()
+	return nil
+}
+
+func (afpacketBackend) Devices() ([]Device, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]Device, 0, len(interfaces))
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 {
+			continue
+		}
+		description := iface.Name
+		if iface.Flags&net.FlagLoopback != 0 {
+			description += " (loopback)"
+		}
+		devices = append(devices, Device{Name: iface.Name, Description: description})
+	}
+	return devices, nil
+}
+
+func (afpacketBackend) Open(device Device, snaplen int) (PacketSource, error) {
+	handle, err := afpacket.NewTPacket(
+		afpacket.OptInterface(device.Name),
+		afpacket.OptPollTimeout(readTimeout),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("打开网卡 %s 失败: %v", device.Name, err)
+	}
+	return &afpacketFilteredSource{
+		filteredSource: filteredSource{source: &afpacketSource{handle: handle}, linkType: layers.LinkTypeEthernet},
+		handle:         handle,
+		snaplen:        snaplen,
+	}, nil
+}
+
+// afpacketFilteredSource 在内核中使用BPF过滤数据包
+// 附加过滤器之前已进入环形缓冲区的数据包仍由用户态过滤
+type afpacketFilteredSource struct {
+	filteredSource
+	handle  *afpacket.TPacket
+	snaplen int
+}
+
+func (s *afpacketFilteredSource) SetFilter(filter Filter) error {
+	program, err := compileBPF(filter, s.snaplen)
+	if err != nil {
+		return err
+	}
+	if err = s.handle.SetBPF(program); err != nil {
+		return fmt.Errorf("设置BPF过滤器失败: %v", err)
+	}
+	return s.filteredSource.SetFilter(filter)
+}
+
+// afpacketSource 保证关闭时没有正在进行的读取(关闭会释放内存映射的环形缓冲区)
+type afpacketSource struct {
+	mu     sync.Mutex
+	handle *afpacket.TPacket
+	closed bool
+}
+
+func (s *afpacketSource) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, gopacket.CaptureInfo{}, io.EOF
+	}
+	return s.handle.ReadPacketData()
+}
+
+func (s *afpacketSource) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	s.handle.Close()
+}
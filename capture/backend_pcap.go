@@ -0,0 +1,56 @@
+//go:build windows || darwin || pcap
+
+package capture
+
+import (
+	"github.com/google/gopacket/pcap"
+)
+
+func init() {
+	registerBackend(BackendPcap, func() Backend { return pcapBackend{} })
+}
+
+// pcapBackend 基于 libpcap / Npcap 的抓包后端
+type pcapBackend struct{}
+
+func (pcapBackend) Name() string {
+	return BackendPcap
+}
+
+func (pcapBackend) Available() error {
+	return checkPcapInstalled()
+}
+
+func (pcapBackend) Devices() ([]Device, error) {
+	allDevices, err := pcap.FindAllDevs()
+	if err != nil {
+		return nil, err
+	}
+
+	devices := make([]Device, 0, len(allDevices))
+	for _, device := range allDevices {
+		description := device.Description
+		if description == "" {
+			description = device.Name
+		}
+		devices = append(devices, Device{Name: device.Name, Description: description})
+	}
+	return devices, nil
+}
+
+func (pcapBackend) Open(device Device, snaplen int) (PacketSource, error) {
+	handle, err := pcap.OpenLive(device.Name, int32(snaplen), true, readTimeout)
+	if err != nil {
+		return nil, err
+	}
+	return &pcapSource{Handle: handle}, nil
+}
+
+// pcapSource pcap.Handle 的包装
+type pcapSource struct {
+	*pcap.Handle
+}
+
+func (s *pcapSource) SetFilter(filter Filter) error {
+	return s.SetBPFFilter(filter.BPF())
+}
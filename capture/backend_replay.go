@@ -0,0 +1,170 @@
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"tiktok_tool/config"
+)
+
+// pcapng 文件头的块类型
+var pcapngMagic = []byte{0x0a, 0x0d, 0x0d, 0x0a}
+
+func init() {
+	registerBackend(BackendReplay, func() Backend {
+		return replayBackend{path: config.GetConfig().BaseSettings.ReplayFile}
+	})
+}
+
+// replayBackend 从抓包文件回放数据包, 用于离线调试正则或排查问题
+type replayBackend struct {
+	path string
+}
+
+func (b replayBackend) Name() string {
+	return BackendReplay
+}
+
+func (b replayBackend) Available() error {
+	if b.path == "" {
+		return fmt.Errorf("未配置回放文件路径")
+	}
+	if _, err := os.Stat(b.path); err != nil {
+		return fmt.Errorf("回放文件不可用: %v", err)
+	}
+	return nil
+}
+
+func (b replayBackend) Devices() ([]Device, error) {
+	if err := b.Available(); err != nil {
+		return nil, err
+	}
+	return []Device{{Name: b.path, Description: "回放文件: " + b.path}}, nil
+}
+
+func (b replayBackend) Open(device Device, _ int) (PacketSource, error) {
+	file, err := os.Open(device.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(file)
+	magic, err := reader.Peek(4)
+	if err != nil {
+		_ = file.Close()
+		return nil, fmt.Errorf("读取回放文件失败: %v", err)
+	}
+
+	var source gopacket.PacketDataSource
+	var linkType layers.LinkType
+	if bytes.Equal(magic, pcapngMagic) {
+		ngReader, err := pcapgo.NewNgReader(reader, pcapgo.DefaultNgReaderOptions)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("解析pcapng文件失败: %v", err)
+		}
+		source, linkType = ngReader, ngReader.LinkType()
+	} else {
+		pcapReader, err := pcapgo.NewReader(reader)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("解析pcap文件失败: %v", err)
+		}
+		source, linkType = pcapReader, pcapReader.LinkType()
+	}
+
+	return &filteredSource{
+		source:   &closableSource{PacketDataSource: source, closer: file},
+		linkType: linkType,
+	}, nil
+}
+
+// closableSource 为文件读取器增加关闭功能
+type closableSource struct {
+	gopacket.PacketDataSource
+	closer io.Closer
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (s *closableSource) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, gopacket.CaptureInfo{}, io.EOF
+	}
+	return s.PacketDataSource.ReadPacketData()
+}
+
+func (s *closableSource) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	s.closed = true
+	_ = s.closer.Close()
+}
+
+// MemoryBackend 内存中的数据包后端, 用于测试
+// 每次 Open 都会从头回放全部数据包
+type MemoryBackend struct {
+	LinkType layers.LinkType
+	Packets  [][]byte
+}
+
+// NewMemoryBackend 创建以太网链路类型的内存后端
+func NewMemoryBackend(packets ...[]byte) *MemoryBackend {
+	return &MemoryBackend{LinkType: layers.LinkTypeEthernet, Packets: packets}
+}
+
+func (b *MemoryBackend) Name() string {
+	return BackendMemory
+}
+
+func (b *MemoryBackend) Available() error {
+	return nil
+}
+
+func (b *MemoryBackend) Devices() ([]Device, error) {
+	return []Device{{Name: BackendMemory, Description: BackendMemory}}, nil
+}
+
+func (b *MemoryBackend) Open(Device, int) (PacketSource, error) {
+	return &filteredSource{source: &memorySource{packets: b.Packets}, linkType: b.LinkType}, nil
+}
+
+// memorySource 按顺序返回内存中的数据包
+type memorySource struct {
+	mu      sync.Mutex
+	packets [][]byte
+	index   int
+	closed  bool
+}
+
+func (s *memorySource) ReadPacketData() ([]byte, gopacket.CaptureInfo, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed || s.index >= len(s.packets) {
+		return nil, gopacket.CaptureInfo{}, io.EOF
+	}
+	data := s.packets[s.index]
+	s.index++
+	return data, gopacket.CaptureInfo{Timestamp: time.Now(), CaptureLength: len(data), Length: len(data)}, nil
+}
+
+func (s *memorySource) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+}
@@ -0,0 +1,203 @@
+package capture
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"golang.org/x/net/bpf"
+)
+
+// 以太网帧中各字段的偏移
+const (
+	bpfEtherType = 12
+	bpfIPv4Start = 14
+	bpfIPv4Frag  = bpfIPv4Start + 6
+	bpfIPv4Proto = bpfIPv4Start + 9
+	bpfIPv4Src   = bpfIPv4Start + 12
+	bpfIPv4Dst   = bpfIPv4Start + 16
+	bpfIPv6Next  = bpfIPv4Start + 6
+	bpfIPv6Src   = bpfIPv4Start + 8
+	bpfIPv6Dst   = bpfIPv4Start + 24
+	bpfIPv6TCP   = bpfIPv4Start + 40
+)
+
+// 跳转目标
+const (
+	bpfNext   = ""
+	bpfAccept = "accept"
+	bpfDrop   = "drop"
+)
+
+// compileBPF 将过滤条件编译为以太网帧上的BPF程序, 效果与 Filter.BPF 表达式相同
+// 不依赖libpcap, 用于 AF_PACKET 后端在内核中过滤数据包, snaplen 为匹配时保留的最大长度
+func compileBPF(filter Filter, snaplen int) ([]bpf.RawInstruction, error) {
+	var hostV4, hostV6 []byte
+	if filter.Host != nil {
+		if hostV4 = filter.Host.To4(); hostV4 == nil {
+			hostV6 = filter.Host.To16()
+		}
+		if hostV4 == nil && hostV6 == nil {
+			return nil, fmt.Errorf("过滤地址格式错误: %v", filter.Host)
+		}
+	}
+
+	if snaplen <= 0 {
+		snaplen = 0x40000
+	}
+
+	// 指定了地址时只需要处理对应版本的IP
+	ipv4, ipv6 := "ipv4", "ipv6"
+	if hostV4 != nil {
+		ipv6 = bpfDrop
+	}
+	if hostV6 != nil {
+		ipv4 = bpfDrop
+	}
+
+	b := &bpfBuilder{labels: make(map[string]int)}
+	b.add(bpf.LoadAbsolute{Off: bpfEtherType, Size: 2})
+	b.jump(bpf.JumpEqual, 0x0800, ipv4, bpfNext)
+	b.jump(bpf.JumpEqual, 0x86dd, ipv6, bpfDrop)
+	if ipv4 != bpfDrop {
+		b.compileIPv4(hostV4, filter.Port)
+	}
+	if ipv6 != bpfDrop {
+		b.compileIPv6(hostV6, filter.Port)
+	}
+
+	b.label(bpfAccept)
+	b.add(bpf.RetConstant{Val: uint32(snaplen)})
+	b.label(bpfDrop)
+	b.add(bpf.RetConstant{Val: 0})
+
+	return b.assemble()
+}
+
+// compileIPv4 匹配IPv4的TCP数据包, 非首个分片没有TCP头, 与libpcap的处理一致不匹配端口
+func (b *bpfBuilder) compileIPv4(host []byte, port uint16) {
+	b.label("ipv4")
+	b.add(bpf.LoadAbsolute{Off: bpfIPv4Proto, Size: 1})
+	b.jump(bpf.JumpEqual, 6, bpfNext, bpfDrop)
+	if host != nil {
+		addr := binary.BigEndian.Uint32(host)
+		b.add(bpf.LoadAbsolute{Off: bpfIPv4Src, Size: 4})
+		b.jump(bpf.JumpEqual, addr, "ipv4_port", bpfNext)
+		b.add(bpf.LoadAbsolute{Off: bpfIPv4Dst, Size: 4})
+		b.jump(bpf.JumpEqual, addr, "ipv4_port", bpfDrop)
+	}
+	b.label("ipv4_port")
+	if port != 0 {
+		b.add(bpf.LoadAbsolute{Off: bpfIPv4Frag, Size: 2})
+		b.jump(bpf.JumpBitsSet, 0x1fff, bpfDrop, bpfNext)
+		b.add(bpf.LoadMemShift{Off: bpfIPv4Start})
+		b.add(bpf.LoadIndirect{Off: bpfIPv4Start, Size: 2})
+		b.jump(bpf.JumpEqual, uint32(port), bpfAccept, bpfNext)
+		b.add(bpf.LoadIndirect{Off: bpfIPv4Start + 2, Size: 2})
+		b.jump(bpf.JumpEqual, uint32(port), bpfAccept, bpfDrop)
+	} else {
+		b.add(bpf.Jump{}, bpfAccept)
+	}
+}
+
+// compileIPv6 匹配IPv6的TCP数据包, 只处理TCP头紧跟在固定头部之后的数据包
+func (b *bpfBuilder) compileIPv6(host []byte, port uint16) {
+	b.label("ipv6")
+	b.add(bpf.LoadAbsolute{Off: bpfIPv6Next, Size: 1})
+	b.jump(bpf.JumpEqual, 6, bpfNext, bpfDrop)
+	if host != nil {
+		for i := 0; i < 4; i++ {
+			next := bpfNext
+			if i == 3 {
+				next = "ipv6_port"
+			}
+			b.add(bpf.LoadAbsolute{Off: bpfIPv6Src + uint32(i*4), Size: 4})
+			b.jump(bpf.JumpEqual, binary.BigEndian.Uint32(host[i*4:]), next, "ipv6_dst")
+		}
+		b.label("ipv6_dst")
+		for i := 0; i < 4; i++ {
+			next := bpfNext
+			if i == 3 {
+				next = "ipv6_port"
+			}
+			b.add(bpf.LoadAbsolute{Off: bpfIPv6Dst + uint32(i*4), Size: 4})
+			b.jump(bpf.JumpEqual, binary.BigEndian.Uint32(host[i*4:]), next, bpfDrop)
+		}
+	}
+	b.label("ipv6_port")
+	if port != 0 {
+		b.add(bpf.LoadAbsolute{Off: bpfIPv6TCP, Size: 2})
+		b.jump(bpf.JumpEqual, uint32(port), bpfAccept, bpfNext)
+		b.add(bpf.LoadAbsolute{Off: bpfIPv6TCP + 2, Size: 2})
+		b.jump(bpf.JumpEqual, uint32(port), bpfAccept, bpfDrop)
+	} else {
+		b.add(bpf.Jump{}, bpfAccept)
+	}
+}
+
+// bpfJump 待确定跳转距离的指令
+type bpfJump struct {
+	index   int
+	ifTrue  string
+	ifFalse string
+}
+
+// bpfBuilder 按标签生成BPF程序, 最后统一计算跳转距离
+type bpfBuilder struct {
+	insts  []bpf.Instruction
+	labels map[string]int
+	jumps  []bpfJump
+}
+
+func (b *bpfBuilder) label(name string) {
+	b.labels[name] = len(b.insts)
+}
+
+// add 添加指令, 无条件跳转指令需要传入跳转目标
+func (b *bpfBuilder) add(inst bpf.Instruction, target ...string) {
+	if len(target) > 0 {
+		b.jumps = append(b.jumps, bpfJump{index: len(b.insts), ifTrue: target[0]})
+	}
+	b.insts = append(b.insts, inst)
+}
+
+// jump 添加条件跳转指令, 目标为 bpfNext 时继续执行下一条指令
+func (b *bpfBuilder) jump(cond bpf.JumpTest, val uint32, ifTrue, ifFalse string) {
+	b.jumps = append(b.jumps, bpfJump{index: len(b.insts), ifTrue: ifTrue, ifFalse: ifFalse})
+	b.insts = append(b.insts, bpf.JumpIf{Cond: cond, Val: val})
+}
+
+func (b *bpfBuilder) assemble() ([]bpf.RawInstruction, error) {
+	skip := func(from int, target string) (uint32, error) {
+		if target == bpfNext {
+			return 0, nil
+		}
+		to, ok := b.labels[target]
+		if !ok || to <= from {
+			return 0, fmt.Errorf("BPF跳转目标错误: %s", target)
+		}
+		return uint32(to - from - 1), nil
+	}
+
+	for _, jump := range b.jumps {
+		ifTrue, err := skip(jump.index, jump.ifTrue)
+		if err != nil {
+			return nil, err
+		}
+		switch inst := b.insts[jump.index].(type) {
+		case bpf.Jump:
+			inst.Skip = ifTrue
+			b.insts[jump.index] = inst
+		case bpf.JumpIf:
+			ifFalse, err := skip(jump.index, jump.ifFalse)
+			if err != nil {
+				return nil, err
+			}
+			if ifTrue > 0xff || ifFalse > 0xff {
+				return nil, fmt.Errorf("BPF跳转距离过长")
+			}
+			inst.SkipTrue, inst.SkipFalse = uint8(ifTrue), uint8(ifFalse)
+			b.insts[jump.index] = inst
+		}
+	}
+	return bpf.Assemble(b.insts)
+}
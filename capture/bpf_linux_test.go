@@ -0,0 +1,80 @@
+package capture
+
+import (
+	"net"
+	"testing"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"golang.org/x/net/bpf"
+)
+
+// buildTCP6Packet 构造一个以太网/IPv6/TCP数据包
+func buildTCP6Packet(t *testing.T, src, dst net.IP) []byte {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		DstMAC:       net.HardwareAddr{6, 7, 8, 9, 10, 11},
+		EthernetType: layers.EthernetTypeIPv6,
+	}
+	ip := &layers.IPv6{Version: 6, HopLimit: 64, NextHeader: layers.IPProtocolTCP, SrcIP: src, DstIP: dst}
+	tcp := &layers.TCP{SrcPort: 50000, DstPort: 1935, Seq: 1, ACK: true, Window: 1024}
+	_ = tcp.SetNetworkLayerForChecksum(ip)
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, eth, ip, tcp, gopacket.Payload("data")); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestCompileBPF(t *testing.T) {
+	tcp4 := buildTCPPacket(t, "data")
+	tcp6 := buildTCP6Packet(t, net.ParseIP("fd00::2"), net.ParseIP("fd00::1"))
+	udp4 := append([]byte(nil), tcp4...)
+	udp4[bpfIPv4Proto] = byte(layers.IPProtocolUDP)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		packet []byte
+		want   bool
+	}{
+		{"只过滤TCP", Filter{}, tcp4, true},
+		{"IPv6 TCP", Filter{}, tcp6, true},
+		{"UDP", Filter{}, udp4, false},
+		{"目的地址", Filter{Host: net.IPv4(10, 0, 0, 1), Port: 1935}, tcp4, true},
+		{"源地址", Filter{Host: net.IPv4(192, 168, 1, 2)}, tcp4, true},
+		{"地址不匹配", Filter{Host: net.IPv4(10, 0, 0, 2), Port: 1935}, tcp4, false},
+		{"源端口", Filter{Port: 50000}, tcp4, true},
+		{"端口不匹配", Filter{Port: 443}, tcp4, false},
+		{"IPv6地址", Filter{Host: net.ParseIP("fd00::1"), Port: 1935}, tcp6, true},
+		{"IPv6地址不匹配", Filter{Host: net.ParseIP("fd00::3")}, tcp6, false},
+		{"IPv6端口不匹配", Filter{Port: 443}, tcp6, false},
+		{"IPv4地址不匹配IPv6数据包", Filter{Host: net.IPv4(10, 0, 0, 1)}, tcp6, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := compileBPF(tt.filter, 128)
+			if err != nil {
+				t.Fatal(err)
+			}
+			program, ok := bpf.Disassemble(raw)
+			if !ok {
+				t.Fatal("BPF程序无法解析")
+			}
+			vm, err := bpf.NewVM(program)
+			if err != nil {
+				t.Fatal(err)
+			}
+			n, err := vm.Run(tt.packet)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if (n > 0) != tt.want {
+				t.Errorf("过滤结果错误: %d, 过滤条件 %s", n, tt.filter.BPF())
+			}
+		})
+	}
+}
@@ -1,31 +1,41 @@
 package capture
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"regexp"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/google/gopacket"
 	"github.com/google/gopacket/layers"
-	"github.com/google/gopacket/pcap"
 
 	"tiktok_tool/config"
 	"tiktok_tool/lkit"
 	"tiktok_tool/llog"
 )
 
+// logger 抓包模块日志
+var logger = llog.Named("capture")
+
 var (
-	handles     []*pcap.Handle
+	handles     []PacketSource
 	handleMutex sync.Mutex
 
-	IsCapturing bool
-	StopCapture chan struct{}
+	// 界面、一键开播、抓包协程和退出时的清理都会开始或停止抓包, 抓包状态由 captureMutex 保护
+	captureMutex sync.Mutex
+	capturing    bool
+	stopCapture  chan struct{}
+	// unbindSession 停止抓包时移除日志中的抓包会话ID
+	unbindSession func()
 
-	allReadyGetServer = false
-	allReadyGetStream = false
+	// 多个网卡的抓包协程会同时读写
+	allReadyGetServer atomic.Bool
+	allReadyGetStream atomic.Bool
 
 	SrcIPAddr     = ""
 	SrcIPPort     uint16
@@ -35,24 +45,54 @@ var (
 	DstIPAddrPort = ""
 )
 
-// CheckNpcapInstalled 检查是否安装了Npcap
-func CheckNpcapInstalled() bool {
-	_, err := os.Stat("C:\\Windows\\System32\\Npcap")
-	return err == nil
+func init() {
+	// 程序退出时停止抓包, 关闭网卡句柄
+	lkit.OnShutdown("capture", StopCapturing)
+}
+
+// IsCapturing 是否正在抓包
+func IsCapturing() bool {
+	captureMutex.Lock()
+	defer captureMutex.Unlock()
+	return capturing
+}
+
+// BeginCapture 标记开始抓包, 已在抓包时返回false, 返回true后需调用 StartCapture
+func BeginCapture() bool {
+	captureMutex.Lock()
+	defer captureMutex.Unlock()
+	if capturing {
+		return false
+	}
+	capturing = true
+	stopCapture = make(chan struct{})
+	return true
 }
 
-// StopCapturing 停止抓包
+// StopCapturing 停止抓包, 可以在多个协程中同时调用
 func StopCapturing() {
-	if IsCapturing == false {
+	stopCapturing(nil)
+}
+
+// stopCapturing 停止抓包, stop 不为nil时只停止该次抓包, 已开始新的抓包时不处理
+func stopCapturing(stop <-chan struct{}) {
+	captureMutex.Lock()
+	if !capturing || (stop != nil && stop != stopCapture) {
+		captureMutex.Unlock()
 		return
 	}
-	llog.Debug("停止抓包")
-	IsCapturing = false
-	close(StopCapture)
+	logger.Debug("停止抓包")
+	capturing = false
+	close(stopCapture)
+	if unbindSession != nil {
+		unbindSession()
+		unbindSession = nil
+	}
+	captureMutex.Unlock()
 
 	// 重置状态变量
-	allReadyGetServer = false
-	allReadyGetStream = false
+	allReadyGetServer.Store(false)
+	allReadyGetStream.Store(false)
 
 	handleMutex.Lock()
 	for _, handle := range handles {
@@ -62,20 +102,71 @@ func StopCapturing() {
 	handleMutex.Unlock()
 }
 
-// StartCapture 开始抓包
+// StartCapture 开始抓包, 需先调用 BeginCapture
 func StartCapture(onServerFound, onStreamKeyFound, onStreamIpFound func(string), onError func(error), onGetAll func()) {
+	// 本次抓包期间的日志都会带上抓包会话ID
+	captureMutex.Lock()
+	stop := stopCapture
+	if unbindSession != nil {
+		unbindSession()
+	}
+	unbindSession = llog.Bind(llog.CaptureSessionKey, llog.NewID())
+	captureMutex.Unlock()
+
 	// 重置状态变量
-	llog.Debug("开始抓包")
-	allReadyGetServer = false
-	allReadyGetStream = false
-	baseCfg := config.GetConfig().BaseSettings
-	allDevices, err := pcap.FindAllDevs()
+	logger.Debug("开始抓包", llog.String("profile", config.GetConfig().Profile().Name))
+	allReadyGetServer.Store(false)
+	allReadyGetStream.Store(false)
+
+	profile := config.GetConfig().Profile()
+	serverRegex, err := regexp.Compile(profile.ServerRegex)
+	if err != nil {
+		onError(fmt.Errorf("服务器地址正则无效: %v", err))
+		return
+	}
+	streamRegex, err := regexp.Compile(profile.StreamKeyRegex)
+	if err != nil {
+		onError(fmt.Errorf("推流码正则无效: %v", err))
+		return
+	}
+
+	backend, devices, err := findDevices()
 	if err != nil {
 		onError(err)
 		return
 	}
 
-	devices := make([]pcap.Interface, 0)
+	names := make([]string, 0, len(devices))
+	for _, device := range devices {
+		names = append(names, device.String())
+	}
+	logger.Info("正在监听网络接口", llog.String("backend", backend.Name()), llog.Strings("devices", names))
+
+	for _, device := range devices {
+		lkit.Go("capture.device."+device.String(), func(ctx context.Context) {
+			captureDevice(ctx, stop, backend, device, serverRegex, streamRegex, onServerFound, onStreamKeyFound, onStreamIpFound, onGetAll)
+		})
+	}
+}
+
+// findDevices 根据配置获取抓包后端以及需要监听的网卡
+func findDevices() (Backend, []Device, error) {
+	backend, err := CurrentBackend()
+	if err != nil {
+		return nil, nil, err
+	}
+	allDevices, err := backend.Devices()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 回放文件/内存数据只有一个虚拟网卡, 不受网卡选择影响
+	if name := backend.Name(); name == BackendReplay || name == BackendMemory {
+		return backend, allDevices, nil
+	}
+
+	baseCfg := config.GetConfig().BaseSettings
+	devices := make([]Device, 0)
 	for _, device := range allDevices {
 		if len(baseCfg.NetworkInterfaces) == 0 {
 			if strings.Contains(device.Description, "Bluetooth") ||
@@ -91,22 +182,15 @@ func StartCapture(onServerFound, onStreamKeyFound, onStreamIpFound func(string),
 	}
 
 	if len(devices) == 0 {
-		onError(fmt.Errorf("未找到可用的网络接口"))
-		return
-	}
-
-	llog.Info("正在监听网络接口:", devices)
-
-	for _, device := range devices {
-		lkit.SafeGo(func() {
-			captureDevice(device.Name, onServerFound, onStreamKeyFound, onStreamIpFound, onGetAll)
-		})
+		return nil, nil, fmt.Errorf("未找到可用的网络接口")
 	}
+	return backend, devices, nil
 }
 
-func captureDevice(deviceName string, onServerFound, onStreamKeyFound, onStreamIpFound func(string), onGetAll func()) {
-	handle, err := pcap.OpenLive(deviceName, 65535, true, pcap.BlockForever)
+func captureDevice(ctx context.Context, stop <-chan struct{}, backend Backend, device Device, serverRegex, streamRegex *regexp.Regexp, onServerFound, onStreamKeyFound, onStreamIpFound func(string), onGetAll func()) {
+	handle, err := backend.Open(device, 65535)
 	if err != nil {
+		logger.Warn("打开网卡失败", llog.String("device", device.String()), llog.Err(err))
 		return
 	}
 
@@ -114,65 +198,95 @@ func captureDevice(deviceName string, onServerFound, onStreamKeyFound, onStreamI
 	handles = append(handles, handle)
 	handleMutex.Unlock()
 
+	// 回放文件或内存数据读取完毕时, 最后一个数据源结束后停止本次抓包
+	eof := false
 	defer func() {
 		handleMutex.Lock()
 		handle.Close()
+		handles = slices.DeleteFunc(handles, func(h PacketSource) bool { return h == handle })
+		last := len(handles) == 0
 		handleMutex.Unlock()
+
+		if eof && last {
+			logger.Info("数据源已读取完毕, 停止抓包", llog.String("device", device.String()))
+			stopCapturing(stop)
+		}
 	}()
 
-	err = handle.SetBPFFilter("tcp")
+	err = handle.SetFilter(Filter{})
 	if err != nil {
 		return
 	}
 
-	baseCfg := config.GetConfig().BaseSettings
+	wsTracker := newWSTracker()
+
+	// matchPayload 在数据中匹配服务器地址和推流码, 全部找到时返回true
+	matchPayload := func(packet gopacket.Packet, payload string) bool {
+		if !allReadyGetServer.Load() && strings.Contains(strings.ToLower(payload), "rtmp://") {
+			matches := serverRegex.FindStringSubmatch(payload)
+
+			if len(matches) >= 1 {
+				serverUrl := matches[0]
+				onServerFound(serverUrl)
+				allReadyGetServer.Store(true)
+				logger.Info("找到服务器地址", llog.String("server", serverUrl))
+			}
+		}
+
+		if !allReadyGetStream.Load() {
+			matches := streamRegex.FindStringSubmatch(payload)
+
+			if len(matches) >= 1 {
+				streamStr := matches[0]
+				onStreamKeyFound(streamStr)
+				allReadyGetStream.Store(true)
+				logger.Info("找到推流码字符串", llog.String("stream_key", streamStr))
+				lkit.Go("capture.dst_info", func(context.Context) {
+					getDstInfo(packet, onStreamIpFound)
+				})
+			}
+		}
+
+		return allReadyGetServer.Load() && allReadyGetStream.Load()
+	}
+
 	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
 	for {
 		select {
-		case <-StopCapture:
+		case <-stop:
+			return
+		case <-ctx.Done():
 			return
 		default:
 			packet, err := packetSource.NextPacket()
+			if errors.Is(err, io.EOF) {
+				// 数据源已关闭或回放结束
+				eof = true
+				return
+			}
 			if err != nil {
 				continue
 			}
 
 			appLayer := packet.ApplicationLayer()
 			if appLayer == nil {
+				// FIN/RST 等无负载的包也需要交给WebSocket跟踪器清理连接
+				wsTracker.Feed(packet)
 				continue
 			}
 
-			payload := string(appLayer.Payload())
-
-			if !allReadyGetServer && strings.Contains(strings.ToLower(payload), "rtmp://") {
-				serverRegex := regexp.MustCompile(baseCfg.ServerRegex)
-				matches := serverRegex.FindStringSubmatch(payload)
-
-				if len(matches) >= 1 {
-					serverUrl := matches[0]
-					onServerFound(serverUrl)
-					allReadyGetServer = true
-					llog.InfoF("找到服务器地址: %s", serverUrl)
-				}
-			}
+			found := matchPayload(packet, string(appLayer.Payload()))
 
-			if !allReadyGetStream {
-				streamRegex := regexp.MustCompile(baseCfg.StreamKeyRegex)
-				matches := streamRegex.FindStringSubmatch(payload)
-
-				if len(matches) >= 1 {
-					streamStr := matches[0]
-					onStreamKeyFound(streamStr)
-					allReadyGetStream = true
-					llog.InfoF("找到推流码字符串: %s", streamStr)
-					lkit.SafeGo(func() {
-						getDstInfo(packet, onStreamIpFound)
-					})
+			// WebSocket 帧经过掩码和压缩, 需要还原后再匹配
+			for _, message := range wsTracker.Feed(packet) {
+				if found {
+					break
 				}
+				found = matchPayload(packet, string(message))
 			}
 
-			if allReadyGetServer && allReadyGetStream {
-				llog.Debug("已找到服务器地址和推流码字符串, 停止抓包")
+			if found {
+				logger.Debug("已找到服务器地址和推流码字符串, 停止抓包")
 				onGetAll()
 				StopCapturing()
 			}
@@ -200,6 +314,5 @@ func getDstInfo(packet gopacket.Packet, onStreamIpFound func(string)) {
 	DstIPAddrPort = lkit.GetAddr(ipv4.DstIP, tcp.DstPort)
 	onStreamIpFound(DstIPAddrPort)
 
-	llog.Info("本地IP: ", SrcIPAddrPort)
-	llog.Info("推流目标IP: ", DstIPAddrPort)
+	logger.Info("找到推流IP", llog.String("local", SrcIPAddrPort), llog.String("remote", DstIPAddrPort))
 }
@@ -0,0 +1,203 @@
+package capture
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+	"github.com/google/gopacket/pcapgo"
+
+	"tiktok_tool/config"
+)
+
+const testPayload = "rtmp://push-rtmp-l1.douyincdn.com/thirdgame stream-123456?expire=1700000000&sign=abcdef\r\n"
+
+// buildTCPPacket 构造一个以太网/IPv4/TCP数据包
+func buildTCPPacket(t *testing.T, payload string) []byte {
+	eth := &layers.Ethernet{
+		SrcMAC:       net.HardwareAddr{0, 1, 2, 3, 4, 5},
+		DstMAC:       net.HardwareAddr{6, 7, 8, 9, 10, 11},
+		EthernetType: layers.EthernetTypeIPv4,
+	}
+	ip := &layers.IPv4{
+		Version:  4,
+		TTL:      64,
+		Protocol: layers.IPProtocolTCP,
+		SrcIP:    net.IPv4(192, 168, 1, 2),
+		DstIP:    net.IPv4(10, 0, 0, 1),
+	}
+	tcp := &layers.TCP{SrcPort: 50000, DstPort: 1935, Seq: 1, ACK: true, PSH: true, Window: 1024}
+	_ = tcp.SetNetworkLayerForChecksum(ip)
+
+	buf := gopacket.NewSerializeBuffer()
+	opts := gopacket.SerializeOptions{FixLengths: true, ComputeChecksums: true}
+	if err := gopacket.SerializeLayers(buf, opts, eth, ip, tcp, gopacket.Payload(payload)); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// writePcap 将数据包写入临时的pcap文件, 返回文件路径
+func writePcap(t *testing.T, payloads ...string) string {
+	path := filepath.Join(t.TempDir(), "capture.pcap")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	writer := pcapgo.NewWriter(file)
+	if err = writer.WriteFileHeader(65535, layers.LinkTypeEthernet); err != nil {
+		t.Fatal(err)
+	}
+	for _, payload := range payloads {
+		data := buildTCPPacket(t, payload)
+		ci := gopacket.CaptureInfo{Timestamp: time.Now(), CaptureLength: len(data), Length: len(data)}
+		if err = writer.WritePacket(ci, data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return path
+}
+
+func TestStartCaptureWithMemoryBackend(t *testing.T) {
+	SetBackend(NewMemoryBackend(
+		buildTCPPacket(t, "GET / HTTP/1.1\r\n\r\n"),
+		buildTCPPacket(t, testPayload),
+	))
+	defer SetBackend(nil)
+
+	if !BeginCapture() {
+		t.Fatal("已在抓包")
+	}
+
+	servers := make(chan string, 1)
+	keys := make(chan string, 1)
+	ips := make(chan string, 1)
+	done := make(chan struct{})
+	StartCapture(
+		func(server string) { servers <- server },
+		func(key string) { keys <- key },
+		func(ip string) { ips <- ip },
+		func(err error) { t.Errorf("抓包失败: %v", err) },
+		func() { close(done) },
+	)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("等待抓包结果超时")
+	}
+
+	if server := <-servers; server != "rtmp://push-rtmp-l1.douyincdn.com/thirdgame" {
+		t.Errorf("服务器地址错误: %s", server)
+	}
+	if key := <-keys; key != "stream-123456?expire=1700000000&sign=abcdef" {
+		t.Errorf("推流码错误: %s", key)
+	}
+	select {
+	case ip := <-ips:
+		if ip != "10.0.0.1:1935" {
+			t.Errorf("推流IP错误: %s", ip)
+		}
+	case <-time.After(time.Second):
+		t.Error("未获取到推流IP")
+	}
+}
+
+func TestReplayBackendFilter(t *testing.T) {
+	backend := replayBackend{path: writePcap(t, "first", testPayload)}
+	devices, err := backend.Devices()
+	if err != nil || len(devices) != 1 {
+		t.Fatalf("获取回放设备失败: %v", err)
+	}
+	source, err := backend.Open(devices[0], 65535)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	// 端口不匹配时应过滤掉全部数据包
+	if err = source.SetFilter(Filter{Port: 443}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = source.ReadPacketData(); err == nil {
+		t.Fatal("过滤条件未生效")
+	}
+}
+
+func TestStopCapturingConcurrently(t *testing.T) {
+	for i := 0; i < 3; i++ {
+		if !BeginCapture() {
+			t.Fatal("已在抓包")
+		}
+		if BeginCapture() {
+			t.Fatal("重复开始抓包")
+		}
+
+		// 退出时的清理、抓包协程和界面可能同时停止抓包
+		var wg sync.WaitGroup
+		for j := 0; j < 8; j++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				StopCapturing()
+			}()
+		}
+		wg.Wait()
+		if IsCapturing() {
+			t.Fatal("未停止抓包")
+		}
+	}
+}
+
+func TestStopCapturingAfterReplayEnds(t *testing.T) {
+	SetBackend(replayBackend{path: writePcap(t, "first", "second")})
+	defer SetBackend(nil)
+
+	if !BeginCapture() {
+		t.Fatal("已在抓包")
+	}
+	StartCapture(
+		func(string) {}, func(string) {}, func(string) {},
+		func(err error) { t.Errorf("抓包失败: %v", err) },
+		func() { t.Error("不应获取到推流信息") },
+	)
+
+	// 回放文件读取完毕后应停止抓包并移除已关闭的数据源
+	deadline := time.Now().Add(5 * time.Second)
+	for IsCapturing() {
+		if time.Now().After(deadline) {
+			t.Fatal("回放结束后未停止抓包")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	handleMutex.Lock()
+	defer handleMutex.Unlock()
+	if len(handles) != 0 {
+		t.Errorf("已关闭的数据源未移除: %d", len(handles))
+	}
+}
+
+func TestCurrentBackendWithoutPcap(t *testing.T) {
+	if slices.Contains(Backends(), BackendPcap) || runtime.GOOS != "linux" {
+		t.Skip("仅检查未编译pcap后端的Linux")
+	}
+	cfg := config.DefaultConfig.Clone()
+	cfg.BaseSettings.CaptureBackend = BackendPcap
+	config.SetConfig(cfg)
+	defer config.SetConfig(config.DefaultConfig.Clone())
+
+	// 未编译pcap后端时提示改用afpacket后端, 不检查libpcap
+	if _, err := CurrentBackend(); err == nil || !strings.Contains(err.Error(), BackendAFPacket) {
+		t.Errorf("错误提示不正确: %v", err)
+	}
+}
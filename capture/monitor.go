@@ -0,0 +1,428 @@
+package capture
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+
+	"tiktok_tool/config"
+	"tiktok_tool/lkit"
+	"tiktok_tool/llog"
+)
+
+const (
+	monitorInterval   = time.Second
+	monitorMaxPending = 4096 // RTT 计算时最多记录的未确认分段数
+)
+
+// HealthSample 推流连接在一个统计周期内的健康数据
+type HealthSample struct {
+	Time           time.Time
+	BitrateKbps    float64       // 上传码率
+	Segments       int           // 发送的数据分段数
+	Retransmits    int           // 重传分段数
+	RetransPercent float64       // 重传率
+	ZeroWindows    int           // 服务端零窗口次数
+	RTT            time.Duration // 平滑后的RTT估计值, 0 表示暂无样本
+	Warnings       []string      // 告警信息
+}
+
+// flowState 单条推流TCP连接的状态(以本地端口区分)
+type flowState struct {
+	seqEnd   uint32 // 已发送的最大序号
+	hasSeq   bool
+	pending  map[uint32]time.Time // 未确认分段的结束序号 -> 发送时间
+	lastData time.Time            // 最后一次上传数据的时间
+	closed   bool                 // 已收到FIN/RST
+}
+
+// Monitor 推流连接健康监控
+// 在获取到推流服务器地址后, 使用仅匹配该服务器的BPF过滤器持续抓取TCP头部,
+// 统计健康数据并跟踪推流连接的生命周期
+type Monitor struct {
+	serverIP   net.IP
+	serverPort layers.TCPPort
+	onSample   func(HealthSample)
+	onEvent    func(PushEvent)
+
+	mu          sync.Mutex
+	flows       map[layers.TCPPort]*flowState
+	bytes       int
+	segments    int
+	retransmits int
+	zeroWindows int
+	srtt        time.Duration
+	session     pushSession
+
+	handles []PacketSource
+	stop    chan struct{}
+	once    sync.Once
+}
+
+// StartMonitor 开始监控到 serverAddr(ip:port) 的推流连接
+// 每个统计周期回调一次 onSample, 推流开始/中断/结束时回调 onEvent, 两者都可以为nil
+func StartMonitor(serverAddr string, onSample func(HealthSample), onEvent func(PushEvent)) (*Monitor, error) {
+	host, portStr, err := net.SplitHostPort(serverAddr)
+	if err != nil {
+		return nil, fmt.Errorf("推流地址格式错误: %v", err)
+	}
+	serverIP := net.ParseIP(host)
+	if serverIP == nil {
+		return nil, fmt.Errorf("推流地址格式错误: %s", serverAddr)
+	}
+
+	backend, devices, err := findDevices()
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Monitor{
+		serverIP:   serverIP,
+		serverPort: layers.TCPPort(lkit.Str2UInt32(portStr)),
+		onSample:   onSample,
+		onEvent:    onEvent,
+		flows:      make(map[layers.TCPPort]*flowState),
+		session:    pushSession{server: serverAddr},
+		stop:       make(chan struct{}),
+	}
+
+	filter := Filter{Host: serverIP, Port: uint16(m.serverPort)}
+	for _, device := range devices {
+		// 只需要TCP头部, 截断长度足够覆盖以太网+IP+TCP头即可
+		handle, err := backend.Open(device, 128)
+		if err != nil {
+			continue
+		}
+		if err = handle.SetFilter(filter); err != nil {
+			handle.Close()
+			continue
+		}
+		m.handles = append(m.handles, handle)
+	}
+
+	if len(m.handles) == 0 {
+		return nil, fmt.Errorf("没有可用于监控的网络接口")
+	}
+
+	logger.Info("开始监控推流连接", llog.String("server", serverAddr))
+
+	for _, handle := range m.handles {
+		lkit.Go("monitor.device", func(ctx context.Context) {
+			m.run(ctx, handle)
+		})
+	}
+	lkit.Go("monitor.report", m.report)
+
+	return m, nil
+}
+
+// Stop 停止监控并关闭网卡, 正在进行的推流会话会被记录为结束
+func (m *Monitor) Stop() {
+	m.once.Do(func() {
+		close(m.stop)
+		// 关闭网卡使阻塞中的读取立即返回
+		for _, handle := range m.handles {
+			handle.Close()
+		}
+		logger.Debug("停止监控推流连接")
+
+		m.mu.Lock()
+		event := m.session.end(time.Now(), "停止监控")
+		m.mu.Unlock()
+		m.emit(event)
+	})
+}
+
+// emit 记录并回调推流事件, 调用时不能持有 m.mu
+func (m *Monitor) emit(events ...*PushEvent) {
+	for _, event := range events {
+		if event == nil {
+			continue
+		}
+		logger.Info(FormatPushEvent(*event), llog.String("event", event.Type.String()))
+		// 会话记录在释放锁后写入, 避免文件读写阻塞抓包
+		if event.record != nil {
+			if err := appendSessionRecord(*event.record); err != nil {
+				logger.Warn("记录推流会话失败", llog.Err(err))
+			}
+		}
+		if m.onEvent != nil {
+			m.onEvent(*event)
+		}
+	}
+}
+
+func (m *Monitor) run(ctx context.Context, handle PacketSource) {
+	defer handle.Close()
+
+	packetSource := gopacket.NewPacketSource(handle, handle.LinkType())
+	packetSource.DecodeOptions = gopacket.DecodeOptions{Lazy: true, NoCopy: true}
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ctx.Done():
+			return
+		default:
+			packet, err := packetSource.NextPacket()
+			if errors.Is(err, io.EOF) {
+				return
+			}
+			if err != nil {
+				continue
+			}
+			m.handlePacket(packet)
+		}
+	}
+}
+
+func (m *Monitor) handlePacket(packet gopacket.Packet) {
+	tcpLayer := packet.Layer(layers.LayerTypeTCP)
+	netLayer := packet.NetworkLayer()
+	if tcpLayer == nil || netLayer == nil {
+		return
+	}
+	tcp, ok := tcpLayer.(*layers.TCP)
+	if !ok {
+		return
+	}
+
+	// 截断抓包时负载长度需要根据IP头计算
+	payloadLen := tcpPayloadLen(netLayer, tcp)
+	ts := packet.Metadata().Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	m.mu.Lock()
+	var event *PushEvent
+	if tcp.DstPort == m.serverPort && net.IP(netLayer.NetworkFlow().Dst().Raw()).Equal(m.serverIP) {
+		event = m.handleOutgoing(tcp, payloadLen, ts)
+	} else {
+		event = m.handleIncoming(tcp, ts)
+	}
+	m.mu.Unlock()
+
+	m.emit(event)
+}
+
+// handleOutgoing 处理本地发往推流服务器的分段
+func (m *Monitor) handleOutgoing(tcp *layers.TCP, payloadLen int, ts time.Time) *PushEvent {
+	flow := m.flows[tcp.SrcPort]
+	if flow == nil || (tcp.SYN && !tcp.ACK) {
+		// 新连接(本地端口可能被复用)
+		flow = &flowState{pending: make(map[uint32]time.Time)}
+		m.flows[tcp.SrcPort] = flow
+	}
+
+	if tcp.RST || tcp.FIN {
+		reason := "本地关闭推流连接"
+		if tcp.RST {
+			reason = "本地重置推流连接(RST)"
+		}
+		return m.closeFlow(flow, ts, reason)
+	}
+
+	if payloadLen <= 0 {
+		return nil
+	}
+
+	end := tcp.Seq + uint32(payloadLen)
+	m.segments++
+	if flow.hasSeq && int32(end-flow.seqEnd) <= 0 {
+		// 序号没有前进, 视为重传; 按Karn算法, 重传分段不参与RTT计算
+		m.retransmits++
+		delete(flow.pending, end)
+		return nil
+	}
+
+	m.bytes += payloadLen
+	flow.seqEnd, flow.hasSeq = end, true
+	flow.lastData = ts
+	if len(flow.pending) < monitorMaxPending {
+		flow.pending[end] = ts
+	}
+
+	return m.session.started(ts)
+}
+
+// handleIncoming 处理推流服务器返回的分段(ACK/窗口/FIN/RST)
+func (m *Monitor) handleIncoming(tcp *layers.TCP, ts time.Time) *PushEvent {
+	flow := m.flows[tcp.DstPort]
+
+	if tcp.RST || tcp.FIN {
+		if flow == nil {
+			return nil
+		}
+		reason := "服务器关闭推流连接(直播可能已结束或推流码已过期)"
+		if tcp.RST {
+			reason = "服务器重置推流连接(RST)"
+		}
+		return m.closeFlow(flow, ts, reason)
+	}
+
+	if tcp.Window == 0 && !tcp.SYN {
+		m.zeroWindows++
+	}
+	if !tcp.ACK || flow == nil {
+		return nil
+	}
+
+	// 累积确认会覆盖多个分段, 只取被确认的最新分段计算RTT,
+	// 较早的分段可能等待了延迟确认或前面的丢包, 其采样值偏大
+	var newest uint32
+	var sent time.Time
+	for end, at := range flow.pending {
+		if int32(tcp.Ack-end) < 0 {
+			continue
+		}
+		if sent.IsZero() || int32(end-newest) > 0 {
+			newest, sent = end, at
+		}
+		delete(flow.pending, end)
+	}
+	if sent.IsZero() {
+		return nil
+	}
+	sample := ts.Sub(sent)
+	if sample <= 0 {
+		return nil
+	}
+
+	if m.srtt == 0 {
+		m.srtt = sample
+	} else {
+		m.srtt = (7*m.srtt + sample) / 8
+	}
+	return nil
+}
+
+// closeFlow 连接关闭, 若没有其他仍在推流的连接则视为推流中断
+func (m *Monitor) closeFlow(flow *flowState, ts time.Time, reason string) *PushEvent {
+	if flow.closed {
+		return nil
+	}
+	flow.closed = true
+	flow.pending = make(map[uint32]time.Time)
+
+	if flow.lastData.IsZero() || m.hasActiveFlow(ts) {
+		return nil
+	}
+	return m.session.interrupted(ts, reason)
+}
+
+// hasActiveFlow 是否存在仍在上传数据的连接
+func (m *Monitor) hasActiveFlow(now time.Time) bool {
+	for _, flow := range m.flows {
+		if !flow.closed && !flow.lastData.IsZero() && now.Sub(flow.lastData) < sessionStallTimeout {
+			return true
+		}
+	}
+	return false
+}
+
+// report 按统计周期汇总数据并回调, 程序退出时停止监控并记录推流会话结束
+func (m *Monitor) report(ctx context.Context) {
+	ticker := time.NewTicker(monitorInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.stop:
+			return
+		case <-ctx.Done():
+			m.Stop()
+			return
+		case now := <-ticker.C:
+			m.emit(m.checkSession(now)...)
+
+			sample := m.collect(now)
+			if m.onSample != nil {
+				m.onSample(sample)
+			}
+		}
+	}
+}
+
+// checkSession 检查长时间没有上传数据的中断和中断后未恢复的结束, 并清理已关闭的连接
+func (m *Monitor) checkSession(now time.Time) []*PushEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var events []*PushEvent
+	if m.session.active && !m.hasActiveFlow(now) {
+		events = append(events, m.session.interrupted(now, fmt.Sprintf("超过%d秒没有上传数据", int(sessionStallTimeout.Seconds()))))
+	}
+	events = append(events, m.session.checkEnd(now))
+	for port, flow := range m.flows {
+		if flow.closed {
+			delete(m.flows, port)
+		}
+	}
+	return events
+}
+
+func (m *Monitor) collect(now time.Time) HealthSample {
+	m.mu.Lock()
+	sample := HealthSample{
+		Time:        now,
+		BitrateKbps: float64(m.bytes*8) / 1000 / monitorInterval.Seconds(),
+		Segments:    m.segments,
+		Retransmits: m.retransmits,
+		ZeroWindows: m.zeroWindows,
+		RTT:         m.srtt,
+	}
+	pushing := m.session.active && m.session.interruptedAt.IsZero()
+	m.bytes, m.segments, m.retransmits, m.zeroWindows = 0, 0, 0, 0
+	m.mu.Unlock()
+
+	if sample.Segments > 0 {
+		sample.RetransPercent = float64(sample.Retransmits) * 100 / float64(sample.Segments)
+	}
+	if pushing {
+		sample.Warnings = checkHealth(sample)
+	}
+	return sample
+}
+
+// checkHealth 根据配置的阈值检查推流健康状况
+func checkHealth(sample HealthSample) []string {
+	cfg := config.GetConfig().MonitorSettings
+	if cfg == nil {
+		cfg = config.DefaultConfig.MonitorSettings
+	}
+
+	var warnings []string
+	if cfg.MinBitrateKbps > 0 && sample.BitrateKbps < float64(cfg.MinBitrateKbps) {
+		warnings = append(warnings, fmt.Sprintf("上传码率过低: %.0fkbps", sample.BitrateKbps))
+	}
+	if cfg.MaxRetransPercent > 0 && sample.RetransPercent > float64(cfg.MaxRetransPercent) {
+		warnings = append(warnings, fmt.Sprintf("重传率过高: %.1f%%", sample.RetransPercent))
+	}
+	if sample.ZeroWindows > 0 {
+		warnings = append(warnings, fmt.Sprintf("服务器零窗口: %d次", sample.ZeroWindows))
+	}
+	if cfg.MaxRTTMs > 0 && sample.RTT > time.Duration(cfg.MaxRTTMs)*time.Millisecond {
+		warnings = append(warnings, fmt.Sprintf("RTT过高: %dms", sample.RTT.Milliseconds()))
+	}
+	return warnings
+}
+
+// tcpPayloadLen 根据IP头中的长度计算TCP负载长度(抓包被截断时 tcp.Payload 不完整)
+func tcpPayloadLen(netLayer gopacket.NetworkLayer, tcp *layers.TCP) int {
+	headerLen := int(tcp.DataOffset) * 4
+	switch ip := netLayer.(type) {
+	case *layers.IPv4:
+		return int(ip.Length) - int(ip.IHL)*4 - headerLen
+	case *layers.IPv6:
+		return int(ip.Length) - headerLen
+	}
+	return len(tcp.Payload)
+}
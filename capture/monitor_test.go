@@ -0,0 +1,186 @@
+package capture
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	testLocalPort  layers.TCPPort = 50000
+	testServerPort layers.TCPPort = 1935
+)
+
+func newTestMonitor() *Monitor {
+	return &Monitor{
+		serverIP:   net.IPv4(10, 0, 0, 1),
+		serverPort: testServerPort,
+		flows:      make(map[layers.TCPPort]*flowState),
+		stop:       make(chan struct{}),
+	}
+}
+
+// send 本地发送 [seq, seq+length) 的数据分段
+func send(m *Monitor, seq uint32, length int, ts time.Time) {
+	m.handleOutgoing(&layers.TCP{SrcPort: testLocalPort, DstPort: testServerPort, Seq: seq, ACK: true}, length, ts)
+}
+
+// ack 服务器确认 ack 之前的数据
+func ack(m *Monitor, ack uint32, ts time.Time) {
+	m.handleIncoming(&layers.TCP{SrcPort: testServerPort, DstPort: testLocalPort, Ack: ack, ACK: true, Window: 1024}, ts)
+}
+
+func TestMonitorRTT(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	at := func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+
+	tests := []struct {
+		name    string
+		run     func(m *Monitor)
+		want    time.Duration
+		pending int // 剩余未确认的分段数
+	}{
+		{
+			name: "单个分段",
+			run: func(m *Monitor) {
+				send(m, 0, 100, at(0))
+				ack(m, 100, at(40))
+			},
+			want: 40 * time.Millisecond,
+		},
+		{
+			// 累积确认只取最新的分段, 不取等待最久的分段
+			name: "累积确认",
+			run: func(m *Monitor) {
+				send(m, 0, 100, at(0))
+				send(m, 100, 100, at(50))
+				send(m, 200, 100, at(60))
+				ack(m, 300, at(90))
+			},
+			want: 30 * time.Millisecond,
+		},
+		{
+			// 确认号落在分段中间时只确认之前的完整分段
+			name: "部分确认",
+			run: func(m *Monitor) {
+				send(m, 0, 100, at(0))
+				send(m, 100, 100, at(10))
+				ack(m, 150, at(30))
+			},
+			want:    30 * time.Millisecond,
+			pending: 1,
+		},
+		{
+			// Karn算法: 重传的分段不参与RTT计算
+			name: "重传分段",
+			run: func(m *Monitor) {
+				send(m, 0, 100, at(0))
+				send(m, 0, 100, at(200))
+				ack(m, 100, at(220))
+			},
+			want: 0,
+		},
+		{
+			name: "序号回绕",
+			run: func(m *Monitor) {
+				send(m, 0xffffff00, 0x80, at(0))
+				send(m, 0xffffff80, 0x100, at(20))
+				ack(m, 0x80, at(45))
+			},
+			want: 25 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := newTestMonitor()
+			tt.run(m)
+			if m.srtt != tt.want {
+				t.Errorf("RTT错误: %v, 期望 %v", m.srtt, tt.want)
+			}
+			if pending := m.flows[testLocalPort].pending; len(pending) != tt.pending {
+				t.Errorf("未确认的分段错误: %v", pending)
+			}
+		})
+	}
+}
+
+func TestMonitorSmoothedRTT(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	m := newTestMonitor()
+	send(m, 0, 100, start)
+	ack(m, 100, start.Add(80*time.Millisecond))
+	send(m, 100, 100, start.Add(100*time.Millisecond))
+	ack(m, 200, start.Add(140*time.Millisecond))
+
+	// (7*80 + 40) / 8 = 75ms
+	if m.srtt != 75*time.Millisecond {
+		t.Errorf("平滑RTT错误: %v", m.srtt)
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	tests := []struct {
+		name   string
+		sample HealthSample
+		want   int
+	}{
+		{"正常", HealthSample{BitrateKbps: 3000, RetransPercent: 1, RTT: 50 * time.Millisecond}, 0},
+		{"码率过低", HealthSample{BitrateKbps: 100, RTT: 50 * time.Millisecond}, 1},
+		{"重传率过高", HealthSample{BitrateKbps: 3000, RetransPercent: 10}, 1},
+		{"零窗口", HealthSample{BitrateKbps: 3000, ZeroWindows: 2}, 1},
+		{"RTT过高", HealthSample{BitrateKbps: 3000, RTT: time.Second}, 1},
+		{"多项异常", HealthSample{BitrateKbps: 0, RetransPercent: 20, ZeroWindows: 1, RTT: time.Second}, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if warnings := checkHealth(tt.sample); len(warnings) != tt.want {
+				t.Errorf("告警错误: %v", warnings)
+			}
+		})
+	}
+}
+
+func TestMonitorCollect(t *testing.T) {
+	start := time.Unix(1700000000, 0)
+	m := newTestMonitor()
+	m.session = pushSession{server: "10.0.0.1:1935"}
+	for i := 0; i < 10; i++ {
+		send(m, uint32(i*1000), 1000, start)
+	}
+	send(m, 0, 1000, start)
+
+	sample := m.collect(start.Add(time.Second))
+	if sample.Segments != 11 || sample.Retransmits != 1 || sample.BitrateKbps != 80 {
+		t.Errorf("统计数据错误: %+v", sample)
+	}
+	if sample.RetransPercent < 9 || sample.RetransPercent > 10 {
+		t.Errorf("重传率错误: %v", sample.RetransPercent)
+	}
+	// 推流中才检查健康状况, 码率低于默认阈值
+	if len(sample.Warnings) == 0 {
+		t.Errorf("未产生告警: %+v", sample)
+	}
+
+	// 每个统计周期重新计数
+	if sample = m.collect(start.Add(2 * time.Second)); sample.Segments != 0 || sample.BitrateKbps != 0 {
+		t.Errorf("统计数据未重置: %+v", sample)
+	}
+}
+
+func TestMonitorStopClosesHandles(t *testing.T) {
+	handle, err := NewMemoryBackend(buildTCPPacket(t, "data")).Open(Device{}, 128)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := newTestMonitor()
+	m.handles = append(m.handles, handle)
+	m.Stop()
+
+	if _, _, err = handle.ReadPacketData(); err == nil {
+		t.Error("停止监控后未关闭网卡")
+	}
+}
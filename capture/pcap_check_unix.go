@@ -0,0 +1,41 @@
+//go:build !windows && (darwin || pcap)
+
+package capture
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+)
+
+// 只在编译了pcap后端时检查libpcap, Linux 默认编译的是afpacket后端, 使用 -tags pcap 编译时才需要libpcap
+
+// libpcap 动态库的常见位置
+var libpcapPatterns = []string{
+	"/usr/lib/libpcap.so*",
+	"/usr/lib64/libpcap.so*",
+	"/usr/lib/*-linux-gnu/libpcap.so*",
+	"/lib/*-linux-gnu/libpcap.so*",
+	"/usr/local/lib/libpcap.so*",
+}
+
+// CheckPcapInstalled 检查是否安装了libpcap
+func CheckPcapInstalled() bool {
+	// macOS 系统自带libpcap(位于dyld共享缓存中, 磁盘上不一定有文件)
+	if runtime.GOOS == "darwin" {
+		return true
+	}
+	for _, pattern := range libpcapPatterns {
+		if matches, _ := filepath.Glob(pattern); len(matches) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func checkPcapInstalled() error {
+	if !CheckPcapInstalled() {
+		return fmt.Errorf("未找到libpcap, 请先安装libpcap或在配置中使用 capture_backend = \"afpacket\"")
+	}
+	return nil
+}
@@ -0,0 +1,16 @@
+package capture
+
+import "os"
+
+// CheckPcapInstalled 检查是否安装了Npcap
+func CheckPcapInstalled() bool {
+	_, err := os.Stat("C:\\Windows\\System32\\Npcap")
+	return err == nil
+}
+
+func checkPcapInstalled() error {
+	if !CheckPcapInstalled() {
+		return ErrNpcapNotInstalled
+	}
+	return nil
+}
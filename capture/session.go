@@ -0,0 +1,159 @@
+package capture
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"tiktok_tool/config"
+)
+
+const (
+	sessionStallTimeout = 10 * time.Second // 连接打开但超过该时间没有上传数据视为中断
+	sessionEndTimeout   = 60 * time.Second // 中断后超过该时间没有恢复视为推流结束
+
+	SessionFileName = "push_sessions.jsonl" // 推流会话记录文件名
+)
+
+// PushEventType 推流连接生命周期事件类型
+type PushEventType int
+
+const (
+	PushStarted     PushEventType = iota // 开始推流(或中断后恢复)
+	PushInterrupted                      // 推流中断
+	PushEnded                            // 推流结束
+)
+
+func (t PushEventType) String() string {
+	switch t {
+	case PushStarted:
+		return "推流开始"
+	case PushInterrupted:
+		return "推流中断"
+	case PushEnded:
+		return "推流结束"
+	}
+	return "未知事件"
+}
+
+// PushEvent 推流连接生命周期事件
+type PushEvent struct {
+	Type     PushEventType
+	Reason   string
+	Time     time.Time
+	Server   string
+	Duration time.Duration // 仅 PushEnded 有效, 本次推流会话的时长
+
+	record *SessionRecord // 仅 PushEnded 有效, 需要写入记录文件的会话记录
+}
+
+// SessionRecord 一次推流会话的记录
+type SessionRecord struct {
+	Server   string    `json:"server"`
+	Start    time.Time `json:"start"`
+	End      time.Time `json:"end"`
+	Duration string    `json:"duration"`
+	Seconds  int64     `json:"seconds"`
+	Reason   string    `json:"reason"`
+}
+
+// pushSession 推流会话状态
+type pushSession struct {
+	server string
+
+	active        bool
+	start         time.Time
+	interruptedAt time.Time
+	reason        string
+}
+
+// started 有上传数据时调用
+func (s *pushSession) started(ts time.Time) *PushEvent {
+	if !s.active {
+		s.active = true
+		s.start = ts
+		s.interruptedAt = time.Time{}
+		return &PushEvent{Type: PushStarted, Reason: "开始推流", Time: ts, Server: s.server}
+	}
+	if !s.interruptedAt.IsZero() {
+		s.interruptedAt = time.Time{}
+		return &PushEvent{Type: PushStarted, Reason: "推流已恢复", Time: ts, Server: s.server}
+	}
+	return nil
+}
+
+// interrupted 连接关闭或长时间无数据时调用
+func (s *pushSession) interrupted(ts time.Time, reason string) *PushEvent {
+	if !s.active || !s.interruptedAt.IsZero() {
+		return nil
+	}
+	s.interruptedAt = ts
+	s.reason = reason
+	return &PushEvent{Type: PushInterrupted, Reason: reason, Time: ts, Server: s.server}
+}
+
+// checkEnd 中断后长时间未恢复则结束会话
+func (s *pushSession) checkEnd(now time.Time) *PushEvent {
+	if !s.active || s.interruptedAt.IsZero() || now.Sub(s.interruptedAt) < sessionEndTimeout {
+		return nil
+	}
+	return s.end(s.interruptedAt, s.reason)
+}
+
+// end 结束会话, 会话记录随事件返回, 由 emit 在释放锁后写入
+func (s *pushSession) end(ts time.Time, reason string) *PushEvent {
+	if !s.active {
+		return nil
+	}
+	s.active = false
+
+	event := &PushEvent{
+		Type:     PushEnded,
+		Reason:   reason,
+		Time:     ts,
+		Server:   s.server,
+		Duration: ts.Sub(s.start).Truncate(time.Second),
+	}
+
+	event.record = &SessionRecord{
+		Server:   s.server,
+		Start:    s.start,
+		End:      ts,
+		Duration: event.Duration.String(),
+		Seconds:  int64(event.Duration.Seconds()),
+		Reason:   reason,
+	}
+	return event
+}
+
+// appendSessionRecord 将推流会话追加到记录文件
+func appendSessionRecord(record SessionRecord) error {
+	if err := os.MkdirAll(config.CfgFilePath, 0755); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filepath.Join(config.CfgFilePath, SessionFileName), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.Write(append(data, '\n'))
+	return err
+}
+
+// FormatPushEvent 格式化推流事件, 用于通知和日志
+func FormatPushEvent(event PushEvent) string {
+	text := fmt.Sprintf("[%s] %s: %s", event.Time.Format("15:04:05"), event.Type, event.Reason)
+	if event.Type == PushEnded {
+		text += fmt.Sprintf(" (本次推流时长 %s)", event.Duration)
+	}
+	return text
+}
@@ -0,0 +1,158 @@
+package capture
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket/layers"
+
+	"tiktok_tool/config"
+)
+
+// sessionStep 推流会话测试中的一步, at 为相对开始的秒数
+type sessionStep struct {
+	at     int
+	action string // data 上传数据, close 服务器关闭连接, tick 统计周期检查
+	want   []PushEventType
+}
+
+func TestPushSessionTimers(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []sessionStep
+		want  []time.Duration // 写入记录文件的会话时长
+	}{
+		{
+			name: "持续推流",
+			steps: []sessionStep{
+				{0, "data", []PushEventType{PushStarted}},
+				{5, "data", nil},
+				{9, "tick", nil},
+				{14, "tick", nil},
+			},
+		},
+		{
+			// 超过10秒没有上传数据视为中断, 中断60秒后视为结束, 结束时间为中断时间
+			name: "无数据中断后结束",
+			steps: []sessionStep{
+				{0, "data", []PushEventType{PushStarted}},
+				{9, "tick", nil},
+				{10, "tick", []PushEventType{PushInterrupted}},
+				{69, "tick", nil},
+				{70, "tick", []PushEventType{PushEnded}},
+				{80, "tick", nil},
+			},
+			want: []time.Duration{10 * time.Second},
+		},
+		{
+			name: "中断后恢复",
+			steps: []sessionStep{
+				{0, "data", []PushEventType{PushStarted}},
+				{11, "tick", []PushEventType{PushInterrupted}},
+				{30, "data", []PushEventType{PushStarted}},
+				{39, "tick", nil},
+				{40, "tick", []PushEventType{PushInterrupted}},
+				{99, "tick", nil},
+				{100, "tick", []PushEventType{PushEnded}},
+			},
+			want: []time.Duration{40 * time.Second},
+		},
+		{
+			name: "服务器关闭连接",
+			steps: []sessionStep{
+				{0, "data", []PushEventType{PushStarted}},
+				{20, "data", nil},
+				{25, "close", []PushEventType{PushInterrupted}},
+				{40, "tick", nil},
+				{85, "tick", []PushEventType{PushEnded}},
+			},
+			want: []time.Duration{25 * time.Second},
+		},
+		{
+			// 结束后再有数据是新的推流会话
+			name: "结束后重新推流",
+			steps: []sessionStep{
+				{0, "data", []PushEventType{PushStarted}},
+				{10, "tick", []PushEventType{PushInterrupted}},
+				{70, "tick", []PushEventType{PushEnded}},
+				{100, "data", []PushEventType{PushStarted}},
+				{110, "tick", []PushEventType{PushInterrupted}},
+				{170, "tick", []PushEventType{PushEnded}},
+			},
+			want: []time.Duration{10 * time.Second, 10 * time.Second},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := config.CfgFilePath
+			config.CfgFilePath = t.TempDir()
+			defer func() { config.CfgFilePath = dir }()
+
+			start := time.Unix(1700000000, 0)
+			m := newTestMonitor()
+			m.session = pushSession{server: "10.0.0.1:1935"}
+			seq := uint32(0)
+			for _, step := range tt.steps {
+				ts := start.Add(time.Duration(step.at) * time.Second)
+				var events []*PushEvent
+				switch step.action {
+				case "data":
+					events = append(events, m.handleOutgoing(&layers.TCP{SrcPort: testLocalPort, DstPort: testServerPort, Seq: seq, ACK: true}, 1000, ts))
+					seq += 1000
+				case "close":
+					events = append(events, m.handleIncoming(&layers.TCP{SrcPort: testServerPort, DstPort: testLocalPort, FIN: true, ACK: true}, ts))
+				case "tick":
+					events = m.checkSession(ts)
+				}
+				m.emit(events...)
+
+				var got []PushEventType
+				for _, event := range events {
+					if event != nil {
+						got = append(got, event.Type)
+					}
+				}
+				if !slices.Equal(got, step.want) {
+					t.Errorf("第%d秒的事件错误: %v, 期望 %v", step.at, got, step.want)
+				}
+			}
+
+			records := readSessionRecords(t)
+			if len(records) != len(tt.want) {
+				t.Fatalf("会话记录错误: %+v", records)
+			}
+			for i, record := range records {
+				if record.Seconds != int64(tt.want[i].Seconds()) || record.Server != "10.0.0.1:1935" {
+					t.Errorf("会话记录错误: %+v", record)
+				}
+			}
+		})
+	}
+}
+
+// readSessionRecords 读取记录文件中的推流会话
+func readSessionRecords(t *testing.T) []SessionRecord {
+	data, err := os.ReadFile(filepath.Join(config.CfgFilePath, SessionFileName))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []SessionRecord
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var record SessionRecord
+		if err = json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, record)
+	}
+	return records
+}
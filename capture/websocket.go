@@ -0,0 +1,427 @@
+package capture
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	wsOpContinuation = 0x0
+	wsOpText         = 0x1
+	wsOpBinary       = 0x2
+	wsOpClose        = 0x8
+
+	wsMaxBuffer  = 1 << 20 // 单个方向未解析数据的最大缓存
+	wsMaxMessage = 4 << 20 // 单条消息(分片合并/解压后)的最大长度
+	wsDictSize   = 32 << 10
+	wsMaxHeader  = 16 << 10 // 握手响应头的最大长度
+
+	// 超过该时间没有数据的连接视为已断开(未抓到FIN/RST), 按该间隔清理
+	wsIdleTimeout = 2 * time.Minute
+)
+
+// deflate 块结尾, permessage-deflate 发送时会去掉这4个字节
+var wsDeflateTail = []byte{0x00, 0x00, 0xff, 0xff}
+
+// wsFlowKey 单方向的TCP流标识
+type wsFlowKey struct {
+	net, transport gopacket.Flow
+}
+
+// wsConn 一条WebSocket连接(包含两个方向)
+type wsConn struct {
+	upgraded bool // 是否已收到 101 Switching Protocols
+	deflate  bool // 是否协商了 permessage-deflate
+
+	clientNoContext bool // client_no_context_takeover
+	serverNoContext bool // server_no_context_takeover
+
+	client *wsStream // 客户端 -> 服务端
+	server *wsStream // 服务端 -> 客户端
+
+	lastSeen time.Time // 最后一次收到数据的时间
+}
+
+// wsStream WebSocket连接中一个方向的数据流
+type wsStream struct {
+	conn     *wsConn
+	isClient bool
+
+	nextSeq uint32
+	hasSeq  bool
+	buf     []byte
+
+	// 分片消息
+	msgOpcode     byte
+	msgCompressed bool
+	msg           []byte
+	inMessage     bool
+
+	// 上下文接管时使用的解压字典(最近32KB的明文)
+	dict []byte
+}
+
+// wsTracker 跟踪抓包中出现的WebSocket连接, 并将其中的文本/二进制消息还原出来
+type wsTracker struct {
+	mu        sync.Mutex
+	streams   map[wsFlowKey]*wsStream
+	lastSweep time.Time
+}
+
+func newWSTracker() *wsTracker {
+	return &wsTracker{streams: make(map[wsFlowKey]*wsStream)}
+}
+
+// Feed 处理一个TCP数据包, 返回该包中解析出的完整WebSocket消息(已去掩码和解压)
+func (t *wsTracker) Feed(packet gopacket.Packet) [][]byte {
+	netLayer := packet.NetworkLayer()
+	tcpLayer := packet.Layer(layers.LayerTypeTCP)
+	if netLayer == nil || tcpLayer == nil {
+		return nil
+	}
+	tcp, ok := tcpLayer.(*layers.TCP)
+	if !ok {
+		return nil
+	}
+
+	ts := packet.Metadata().Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+	key := wsFlowKey{net: netLayer.NetworkFlow(), transport: tcp.TransportFlow()}
+	return t.feed(key, tcp.Seq, tcp.Payload, tcp.FIN, tcp.RST, ts)
+}
+
+// feed 处理一个方向的数据, 处理完携带的数据后
+// fin 为true时只删除该方向(另一方向仍可能发送数据), rst 为true时删除整条连接
+func (t *wsTracker) feed(key wsFlowKey, seq uint32, payload []byte, fin, rst bool, ts time.Time) [][]byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sweep(ts)
+	messages := t.feedStream(key, seq, payload, ts)
+	switch {
+	case rst:
+		t.remove(key)
+	case fin:
+		t.removeStream(key)
+	}
+	return messages
+}
+
+// sweep 清理长时间没有数据的连接
+func (t *wsTracker) sweep(now time.Time) {
+	if now.Sub(t.lastSweep) < wsIdleTimeout {
+		return
+	}
+	t.lastSweep = now
+	for key, stream := range t.streams {
+		if now.Sub(stream.conn.lastSeen) > wsIdleTimeout {
+			delete(t.streams, key)
+		}
+	}
+}
+
+func (t *wsTracker) feedStream(key wsFlowKey, seq uint32, payload []byte, ts time.Time) [][]byte {
+	if len(payload) == 0 {
+		return nil
+	}
+
+	stream := t.streams[key]
+	if stream == nil {
+		// 新连接只能从客户端的 HTTP Upgrade 请求开始跟踪
+		if !isWSUpgradeRequest(payload) {
+			return nil
+		}
+		conn := &wsConn{lastSeen: ts}
+		conn.client = &wsStream{conn: conn, isClient: true}
+		conn.server = &wsStream{conn: conn}
+		t.streams[key] = conn.client
+		t.streams[wsFlowKey{net: key.net.Reverse(), transport: key.transport.Reverse()}] = conn.server
+		conn.client.nextSeq, conn.client.hasSeq = seq+uint32(len(payload)), true
+		return nil
+	}
+
+	// 简单的顺序检查: 丢弃重传, 遇到乱序/丢包则清空缓存重新同步
+	if stream.hasSeq {
+		diff := int32(seq - stream.nextSeq)
+		if diff < 0 {
+			if int(-diff) >= len(payload) {
+				return nil
+			}
+			payload = payload[-diff:]
+			seq = stream.nextSeq
+		} else if diff > 0 {
+			stream.reset()
+		}
+	}
+	stream.nextSeq, stream.hasSeq = seq+uint32(len(payload)), true
+
+	conn := stream.conn
+	conn.lastSeen = ts
+	stream.buf = append(stream.buf, payload...)
+	if !conn.upgraded {
+		if stream.isClient {
+			stream.buf = nil
+			return nil
+		}
+		// 握手响应可能被拆分到多个TCP包中, 缓存到响应头结束
+		headerEnd := bytes.Index(stream.buf, []byte("\r\n\r\n"))
+		if headerEnd < 0 {
+			if len(stream.buf) > wsMaxHeader || !isHTTPResponsePrefix(stream.buf) {
+				t.remove(key)
+			}
+			return nil
+		}
+		if !parseWSUpgradeResponse(conn, stream.buf[:headerEnd]) {
+			t.remove(key)
+			return nil
+		}
+		stream.buf = stream.buf[headerEnd+4:]
+	}
+
+	if len(stream.buf) > wsMaxBuffer {
+		stream.reset()
+		return nil
+	}
+
+	// 收到关闭帧后该方向不会再发送消息, 另一方向还需要回复关闭帧
+	messages, closed := stream.parse()
+	if closed {
+		t.removeStream(key)
+	}
+	return messages
+}
+
+// remove 删除整条连接的两个方向
+func (t *wsTracker) remove(key wsFlowKey) {
+	delete(t.streams, key)
+	delete(t.streams, wsFlowKey{net: key.net.Reverse(), transport: key.transport.Reverse()})
+}
+
+// removeStream 删除连接中已关闭的一个方向, 两个方向都关闭后整条连接不再被引用
+func (t *wsTracker) removeStream(key wsFlowKey) {
+	delete(t.streams, key)
+}
+
+// isWSUpgradeRequest 判断负载是否为 WebSocket 握手请求
+func isWSUpgradeRequest(payload []byte) bool {
+	if !bytes.HasPrefix(payload, []byte("GET ")) {
+		return false
+	}
+	headerEnd := bytes.Index(payload, []byte("\r\n\r\n"))
+	if headerEnd < 0 {
+		headerEnd = len(payload)
+	}
+	header := strings.ToLower(string(payload[:headerEnd]))
+	return strings.Contains(header, "\r\nupgrade: websocket")
+}
+
+// isHTTPResponsePrefix 判断缓存的数据是否可能是HTTP响应的开头
+func isHTTPResponsePrefix(data []byte) bool {
+	prefix := []byte("HTTP/")
+	if len(data) < len(prefix) {
+		return bytes.HasPrefix(prefix, data)
+	}
+	return bytes.HasPrefix(data, prefix)
+}
+
+// parseWSUpgradeResponse 解析服务端握手响应, 记录协商的扩展
+func parseWSUpgradeResponse(conn *wsConn, header []byte) bool {
+	lines := strings.Split(string(header), "\r\n")
+	if len(lines) == 0 || !strings.HasPrefix(lines[0], "HTTP/1.1 101") {
+		return false
+	}
+
+	for _, line := range lines[1:] {
+		name, value, found := strings.Cut(line, ":")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "Sec-WebSocket-Extensions") {
+			continue
+		}
+		for _, ext := range strings.Split(value, ",") {
+			params := strings.Split(ext, ";")
+			if strings.TrimSpace(strings.ToLower(params[0])) != "permessage-deflate" {
+				continue
+			}
+			conn.deflate = true
+			for _, param := range params[1:] {
+				switch strings.TrimSpace(strings.ToLower(param)) {
+				case "client_no_context_takeover":
+					conn.clientNoContext = true
+				case "server_no_context_takeover":
+					conn.serverNoContext = true
+				}
+			}
+		}
+	}
+
+	conn.upgraded = true
+	return true
+}
+
+func (s *wsStream) reset() {
+	s.buf = nil
+	s.msg = nil
+	s.inMessage = false
+	// 丢失数据后压缩上下文已不可用
+	s.dict = nil
+}
+
+// parse 从缓存中解析尽可能多的完整帧, 返回完整消息以及连接是否已关闭
+func (s *wsStream) parse() ([][]byte, bool) {
+	var messages [][]byte
+	for {
+		frame, n, ok := parseWSFrame(s.buf)
+		if !ok {
+			break
+		}
+		s.buf = s.buf[n:]
+
+		switch frame.opcode {
+		case wsOpClose:
+			return messages, true
+		case wsOpText, wsOpBinary:
+			s.msgOpcode = frame.opcode
+			s.msgCompressed = frame.rsv1
+			s.msg = append(s.msg[:0], frame.payload...)
+			s.inMessage = true
+		case wsOpContinuation:
+			if !s.inMessage {
+				continue
+			}
+			s.msg = append(s.msg, frame.payload...)
+		default:
+			// ping/pong 等控制帧不含业务数据
+			continue
+		}
+
+		// 只丢弃过长的消息, 继续解析缓存中后面的帧, 后续分片因不在消息中被跳过
+		if len(s.msg) > wsMaxMessage {
+			s.msg = nil
+			s.inMessage = false
+			if s.msgCompressed {
+				// 压缩上下文缺少该消息的明文, 已不可用
+				s.dict = nil
+			}
+			continue
+		}
+		if !frame.fin {
+			continue
+		}
+
+		s.inMessage = false
+		msg := s.msg
+		s.msg = nil
+		if s.msgCompressed && s.conn.deflate {
+			inflated, err := s.inflate(msg)
+			if err != nil {
+				s.dict = nil
+				continue
+			}
+			msg = inflated
+		}
+		messages = append(messages, msg)
+	}
+
+	if len(s.buf) == 0 {
+		s.buf = nil
+	}
+	return messages, false
+}
+
+// inflate 解压 permessage-deflate 消息
+func (s *wsStream) inflate(data []byte) ([]byte, error) {
+	noContext := s.conn.serverNoContext
+	if s.isClient {
+		noContext = s.conn.clientNoContext
+	}
+
+	src := io.MultiReader(bytes.NewReader(data), bytes.NewReader(wsDeflateTail))
+	reader := flate.NewReaderDict(src, s.dict)
+	defer reader.Close()
+
+	out, err := io.ReadAll(io.LimitReader(reader, wsMaxMessage))
+	// 消息以同步刷新块结尾, 流本身不会结束
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+
+	if !noContext {
+		s.dict = append(s.dict, out...)
+		if len(s.dict) > wsDictSize {
+			s.dict = s.dict[len(s.dict)-wsDictSize:]
+		}
+	}
+	return out, nil
+}
+
+// wsFrame 一个已解析的WebSocket帧
+type wsFrame struct {
+	fin     bool
+	rsv1    bool
+	opcode  byte
+	payload []byte
+}
+
+// parseWSFrame 从数据开头解析一个帧, 返回帧、消耗的字节数以及数据是否足够
+func parseWSFrame(data []byte) (wsFrame, int, bool) {
+	if len(data) < 2 {
+		return wsFrame{}, 0, false
+	}
+
+	frame := wsFrame{
+		fin:    data[0]&0x80 != 0,
+		rsv1:   data[0]&0x40 != 0,
+		opcode: data[0] & 0x0f,
+	}
+	masked := data[1]&0x80 != 0
+	length := uint64(data[1] & 0x7f)
+	offset := 2
+
+	switch length {
+	case 126:
+		if len(data) < offset+2 {
+			return wsFrame{}, 0, false
+		}
+		length = uint64(binary.BigEndian.Uint16(data[offset:]))
+		offset += 2
+	case 127:
+		if len(data) < offset+8 {
+			return wsFrame{}, 0, false
+		}
+		length = binary.BigEndian.Uint64(data[offset:])
+		offset += 8
+	}
+
+	var maskKey [4]byte
+	if masked {
+		if len(data) < offset+4 {
+			return wsFrame{}, 0, false
+		}
+		copy(maskKey[:], data[offset:offset+4])
+		offset += 4
+	}
+
+	if length > wsMaxBuffer || uint64(len(data)-offset) < length {
+		return wsFrame{}, 0, false
+	}
+	end := offset + int(length)
+
+	frame.payload = make([]byte, length)
+	copy(frame.payload, data[offset:end])
+	if masked {
+		for i := range frame.payload {
+			frame.payload[i] ^= maskKey[i%4]
+		}
+	}
+
+	return frame, end, true
+}
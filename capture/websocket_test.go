@@ -0,0 +1,270 @@
+package capture
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/gopacket"
+	"github.com/google/gopacket/layers"
+)
+
+const (
+	testUpgradeRequest = "GET /ws HTTP/1.1\r\nHost: example.com\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Extensions: permessage-deflate; client_max_window_bits\r\n\r\n"
+	testUpgradeResponse = "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Extensions: permessage-deflate\r\n\r\n"
+)
+
+var testTime = time.Unix(1700000000, 0)
+
+func testFlowKeys() (wsFlowKey, wsFlowKey) {
+	netFlow := gopacket.NewFlow(layers.EndpointIPv4, net.IPv4(192, 168, 1, 2).To4(), net.IPv4(10, 0, 0, 1).To4())
+	tcpFlow := gopacket.NewFlow(layers.EndpointTCPPort, []byte{0xc3, 0x50}, []byte{0x01, 0xbb})
+	client := wsFlowKey{net: netFlow, transport: tcpFlow}
+	server := wsFlowKey{net: netFlow.Reverse(), transport: tcpFlow.Reverse()}
+	return client, server
+}
+
+// buildFrame 构造一个WebSocket帧
+func buildFrame(fin, rsv1 bool, opcode byte, payload []byte, mask []byte) []byte {
+	var frame []byte
+	first := opcode
+	if fin {
+		first |= 0x80
+	}
+	if rsv1 {
+		first |= 0x40
+	}
+	frame = append(frame, first)
+
+	maskBit := byte(0)
+	if mask != nil {
+		maskBit = 0x80
+	}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, maskBit|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	data := append([]byte{}, payload...)
+	if mask != nil {
+		frame = append(frame, mask...)
+		for i := range data {
+			data[i] ^= mask[i%4]
+		}
+	}
+	return append(frame, data...)
+}
+
+// compressMessages 使用同一个压缩上下文依次压缩多条消息
+func compressMessages(t *testing.T, messages ...string) [][]byte {
+	var buf bytes.Buffer
+	writer, err := flate.NewWriter(&buf, flate.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out [][]byte
+	for _, message := range messages {
+		buf.Reset()
+		_, _ = writer.Write([]byte(message))
+		if err = writer.Flush(); err != nil {
+			t.Fatal(err)
+		}
+		data := append([]byte{}, buf.Bytes()...)
+		out = append(out, bytes.TrimSuffix(data, wsDeflateTail))
+	}
+	return out
+}
+
+func TestWSTrackerDeflateWithContextTakeover(t *testing.T) {
+	client, server := testFlowKeys()
+	tracker := newWSTracker()
+
+	seq := uint32(1000)
+	tracker.feed(client, seq, []byte(testUpgradeRequest), false, false, testTime)
+	seq += uint32(len(testUpgradeRequest))
+
+	first := `{"push_url":"rtmp://push-rtmp-l1.douyincdn.com/thirdgame","stream":"stream-123?expire=1700000000&sign=abc"}`
+	second := `{"push_url":"rtmp://push-rtmp-l1.douyincdn.com/thirdgame","stream":"stream-456?expire=1700000000&sign=def"}`
+	compressed := compressMessages(t, first, second)
+
+	// 服务端响应和第一帧在同一个包中
+	serverSeq := uint32(5000)
+	payload := append([]byte(testUpgradeResponse), buildFrame(true, true, wsOpText, compressed[0], nil)...)
+	messages := tracker.feed(server, serverSeq, payload, false, false, testTime)
+	if len(messages) != 1 || string(messages[0]) != first {
+		t.Fatalf("第一条消息解析错误: %q", messages)
+	}
+	serverSeq += uint32(len(payload))
+
+	// 第二条消息依赖第一条消息的压缩上下文, 并被拆分到两个TCP包中
+	frame := buildFrame(true, true, wsOpText, compressed[1], nil)
+	if messages = tracker.feed(server, serverSeq, frame[:3], false, false, testTime); len(messages) != 0 {
+		t.Fatalf("不完整的帧不应返回消息: %q", messages)
+	}
+	messages = tracker.feed(server, serverSeq+3, frame[3:], false, false, testTime)
+	if len(messages) != 1 || string(messages[0]) != second {
+		t.Fatalf("第二条消息解析错误: %q", messages)
+	}
+}
+
+func TestWSTrackerMaskedFragments(t *testing.T) {
+	client, server := testFlowKeys()
+	tracker := newWSTracker()
+
+	seq := uint32(1)
+	tracker.feed(client, seq, []byte(testUpgradeRequest), false, false, testTime)
+	seq += uint32(len(testUpgradeRequest))
+	tracker.feed(server, 1, []byte(testUpgradeResponse), false, false, testTime)
+
+	mask := []byte{0x12, 0x34, 0x56, 0x78}
+	var payload []byte
+	payload = append(payload, buildFrame(false, false, wsOpBinary, []byte("stream-789?expire="), mask)...)
+	payload = append(payload, buildFrame(true, false, 0x9, []byte("ping"), mask)...)
+	payload = append(payload, buildFrame(true, false, wsOpContinuation, []byte("1700000000&sign=fff"), mask)...)
+
+	messages := tracker.feed(client, seq, payload, false, false, testTime)
+	if len(messages) != 1 || string(messages[0]) != "stream-789?expire=1700000000&sign=fff" {
+		t.Fatalf("分片消息解析错误: %q", messages)
+	}
+
+	// 重传的数据应被忽略
+	if messages = tracker.feed(client, seq, payload, false, false, testTime); len(messages) != 0 {
+		t.Fatalf("重传数据不应重复返回消息: %q", messages)
+	}
+}
+
+func TestWSTrackerIgnoresPlainHTTP(t *testing.T) {
+	client, _ := testFlowKeys()
+	tracker := newWSTracker()
+
+	request := "GET /index.html HTTP/1.1\r\nHost: example.com\r\n\r\n"
+	tracker.feed(client, 1, []byte(request), false, false, testTime)
+	if len(tracker.streams) != 0 {
+		t.Fatalf("普通HTTP请求不应被跟踪")
+	}
+}
+
+func TestWSTrackerSplitUpgradeResponse(t *testing.T) {
+	client, server := testFlowKeys()
+	tracker := newWSTracker()
+
+	seq := uint32(1)
+	tracker.feed(client, seq, []byte(testUpgradeRequest), false, false, testTime)
+	seq += uint32(len(testUpgradeRequest))
+
+	// 握手响应被拆分到多个TCP包中, 第一帧跟在响应头结尾之后
+	payload := append([]byte(testUpgradeResponse), buildFrame(true, false, wsOpText, []byte("stream-123?expire=1700000000&sign=abc"), nil)...)
+	serverSeq := uint32(1)
+	for _, n := range []int{3, 20, len(testUpgradeResponse) - 25} {
+		if messages := tracker.feed(server, serverSeq, payload[:n], false, false, testTime); len(messages) != 0 {
+			t.Fatalf("不完整的响应不应返回消息: %q", messages)
+		}
+		if len(tracker.streams) != 2 {
+			t.Fatalf("不完整的响应不应删除连接")
+		}
+		serverSeq += uint32(n)
+		payload = payload[n:]
+	}
+	messages := tracker.feed(server, serverSeq, payload, false, false, testTime)
+	if len(messages) != 1 || string(messages[0]) != "stream-123?expire=1700000000&sign=abc" {
+		t.Fatalf("消息解析错误: %q", messages)
+	}
+
+	// 服务端响应不是 101 时删除连接
+	tracker = newWSTracker()
+	tracker.feed(client, 1, []byte(testUpgradeRequest), false, false, testTime)
+	tracker.feed(server, 1, []byte("HTTP/1.1 40"), false, false, testTime)
+	tracker.feed(server, 12, []byte("3 Forbidden\r\n\r\n"), false, false, testTime)
+	if len(tracker.streams) != 0 {
+		t.Fatalf("握手失败的连接应被删除")
+	}
+}
+
+func TestWSTrackerRemovesClosedFlows(t *testing.T) {
+	client, server := testFlowKeys()
+	tracker := newWSTracker()
+
+	tracker.feed(client, 1, []byte(testUpgradeRequest), false, false, testTime)
+	tracker.feed(server, 1, []byte(testUpgradeResponse), false, false, testTime)
+
+	// FIN 包中携带的数据仍然会被解析, FIN 只关闭该方向
+	frame := buildFrame(true, false, wsOpText, []byte("last"), nil)
+	messages := tracker.feed(server, 1+uint32(len(testUpgradeResponse)), frame, true, false, testTime)
+	if len(messages) != 1 || string(messages[0]) != "last" {
+		t.Fatalf("FIN 包中的消息解析错误: %q", messages)
+	}
+	if len(tracker.streams) != 1 || tracker.streams[client] == nil {
+		t.Fatalf("FIN 后只应删除服务端方向: %v", tracker.streams)
+	}
+
+	// 另一方向仍然可以发送消息, 两个方向都关闭后删除整条连接
+	frame = buildFrame(true, false, wsOpText, []byte("bye"), []byte{1, 2, 3, 4})
+	messages = tracker.feed(client, 1+uint32(len(testUpgradeRequest)), frame, true, false, testTime)
+	if len(messages) != 1 || string(messages[0]) != "bye" {
+		t.Fatalf("半关闭连接的消息解析错误: %q", messages)
+	}
+	if len(tracker.streams) != 0 {
+		t.Fatalf("两个方向都关闭后连接应被删除")
+	}
+
+	// RST 删除整条连接
+	tracker.feed(client, 1, []byte(testUpgradeRequest), false, false, testTime)
+	tracker.feed(server, 1, nil, false, true, testTime)
+	if len(tracker.streams) != 0 {
+		t.Fatalf("RST 后连接应被删除")
+	}
+
+	// 没有抓到 FIN/RST 的连接超时后被清理
+	tracker.feed(client, 1, []byte(testUpgradeRequest), false, false, testTime)
+	tracker.feed(client, 100, nil, false, false, testTime.Add(wsIdleTimeout/2))
+	if len(tracker.streams) != 2 {
+		t.Fatalf("未超时的连接不应被清理")
+	}
+	tracker.feed(client, 100, nil, false, false, testTime.Add(2*wsIdleTimeout))
+	if len(tracker.streams) != 0 {
+		t.Fatalf("超时的连接应被清理")
+	}
+}
+
+func TestWSTrackerDropsOversizedMessage(t *testing.T) {
+	client, server := testFlowKeys()
+	tracker := newWSTracker()
+
+	tracker.feed(client, 1, []byte(testUpgradeRequest), false, false, testTime)
+	tracker.feed(server, 1, []byte(testUpgradeResponse), false, false, testTime)
+	seq := 1 + uint32(len(testUpgradeResponse))
+
+	// 分片合并后超过长度上限的消息被丢弃, 同一批数据中后面的帧仍然被解析
+	fragment := bytes.Repeat([]byte("a"), wsMaxBuffer-100)
+	for i := 0; i < wsMaxMessage/len(fragment); i++ {
+		opcode := byte(wsOpContinuation)
+		if i == 0 {
+			opcode = wsOpText
+		}
+		frame := buildFrame(false, false, opcode, fragment, nil)
+		if messages := tracker.feed(server, seq, frame, false, false, testTime); len(messages) != 0 {
+			t.Fatalf("不完整的消息不应返回: %d", len(messages))
+		}
+		seq += uint32(len(frame))
+	}
+
+	payload := buildFrame(false, false, wsOpContinuation, fragment, nil)
+	payload = append(payload, buildFrame(true, false, wsOpContinuation, []byte("tail"), nil)...)
+	payload = append(payload, buildFrame(true, false, wsOpText, []byte("ok"), nil)...)
+	messages := tracker.feed(server, seq, payload, false, false, testTime)
+	if len(messages) != 1 || string(messages[0]) != "ok" {
+		t.Fatalf("过长消息之后的消息解析错误: %q", messages)
+	}
+}
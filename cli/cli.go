@@ -0,0 +1,305 @@
+// Package cli 命令行模式, 不启动界面直接执行抓包、导入OBS配置、一键开播、环境检查和配置读写
+// 用于没有 OpenGL 的环境和脚本调用, 结果可以用 --json 输出
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"tiktok_tool/config"
+	"tiktok_tool/live"
+	"tiktok_tool/lkit"
+	"tiktok_tool/llog"
+)
+
+// 进程退出码
+const (
+	ExitOK      = 0 // 执行成功
+	ExitFailed  = 1 // 执行失败
+	ExitUsage   = 2 // 参数错误
+	ExitTimeout = 3 // 等待推流信息超时
+)
+
+// 命令行模式的子命令
+const (
+	CommandCapture   = "capture"
+	CommandImportOBS = "import-obs"
+	CommandOneClick  = "one-click"
+	CommandDoctor    = "doctor"
+	CommandConfig    = "config"
+	CommandHelp      = "help"
+)
+
+// 所有子命令通用的参数
+const (
+	JSONFlag    = "json"
+	ProfileFlag = "profile"
+)
+
+// handler 执行子命令, 返回 --json 时输出的结果和默认输出的文本
+type handler func(ctx context.Context, o *options, args []string) (result any, text string, err error)
+
+// command 子命令
+type command struct {
+	name  string
+	usage string
+	run   handler
+}
+
+var commands []command
+
+var logger = llog.Named("cli")
+
+func init() {
+	commands = []command{
+		{CommandCapture, "capture [--timeout=60s] [--import]\n    抓取推流服务器地址和推流码, --import 抓取后导入OBS", runCapture},
+		{CommandImportOBS, "import-obs --server=地址 --key=推流码\n    导入推流配置到OBS, OBS运行时需要配置WebSocket", runImportOBS},
+		{CommandOneClick, "one-click\n    执行一键开播流程, 进度输出到标准错误", runOneClick},
+		{CommandDoctor, "doctor\n    检查抓包环境、网卡、相关程序和配置问题", runDoctor},
+		{CommandConfig, "config get [键名]\n  config set <键名> <值>\n    读取或修改配置项, 键名与 --print-config 一致, 修改只写入配置文件", runConfig},
+	}
+}
+
+// ExitError 带退出码的错误
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// usageError 参数错误
+func usageError(format string, args ...any) error {
+	return &ExitError{Code: ExitUsage, Err: fmt.Errorf(format, args...)}
+}
+
+// exitCode 错误对应的退出码
+func exitCode(err error) int {
+	var exitErr *ExitError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &exitErr):
+		return exitErr.Code
+	case errors.Is(err, live.ErrCaptureTimeout):
+		return ExitTimeout
+	}
+	return ExitFailed
+}
+
+// envelope --json 时输出的结果
+type envelope struct {
+	OK      bool   `json:"ok"`
+	Command string `json:"command"`
+	Result  any    `json:"result,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// options 通用参数
+type options struct {
+	json    bool
+	profile string
+	stderr  io.Writer // 进度等提示的输出
+	loadErr error     // 加载配置失败的错误, 此时使用默认配置
+}
+
+// flagSet 创建子命令的参数集合, 并注册通用参数
+func (o *options) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.BoolVar(&o.json, JSONFlag, o.json, "以JSON格式输出结果")
+	fs.StringVar(&o.profile, ProfileFlag, o.profile, "使用指定的配置方案, 不修改配置文件")
+	return fs
+}
+
+// parse 解析参数, 参数可以出现在位置参数前后, 返回位置参数
+// 指定了配置方案时只在本次运行中切换
+func (o *options) parse(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	args = stripConfigArgs(args)
+	for {
+		if err := fs.Parse(args); err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil, usageError("用法: %s", usageOf(fs.Name()))
+			}
+			return nil, usageError("%v\n用法: %s", err, usageOf(fs.Name()))
+		}
+		if args = fs.Args(); len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+
+	if o.profile != "" && o.profile != config.GetConfig().Profile().Name {
+		cfg := config.GetConfig().Clone()
+		if cfg.FindProfile(o.profile) == nil {
+			return nil, usageError("配置方案不存在: %s", o.profile)
+		}
+		cfg.BaseSettings.ActiveProfile = o.profile
+		config.SetConfig(cfg)
+	}
+	return positional, nil
+}
+
+// stripConfigArgs 去掉加载配置时已处理的配置覆盖参数, 例如 --config、--monitor.enable
+func stripConfigArgs(args []string) []string {
+	stripped := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, _, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !strings.HasPrefix(arg, "--") || (name != config.ConfigFlag && !strings.Contains(name, ".")) {
+			stripped = append(stripped, arg)
+			continue
+		}
+		// --key value 形式的值是下一个参数, 布尔配置项可省略值
+		if !hasValue && i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") && takesValue(name) {
+			i++
+		}
+	}
+	return stripped
+}
+
+// takesValue 配置覆盖参数是否需要值, 与加载配置时解析命令行参数的规则一致
+func takesValue(name string) bool {
+	if name == config.ConfigFlag {
+		return true
+	}
+	field, ok := config.Lookup(name)
+	return ok && field.Value != "true" && field.Value != "false"
+}
+
+// IsCommand 启动参数是否为命令行模式, 子命令需为第一个参数
+func IsCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	if args[0] == CommandHelp {
+		return true
+	}
+	for _, c := range commands {
+		if c.name == args[0] {
+			return true
+		}
+	}
+	return false
+}
+
+// usageOf 子命令的用法
+func usageOf(name string) string {
+	for _, c := range commands {
+		if c.name == name {
+			return c.usage
+		}
+	}
+	return name
+}
+
+// Usage 命令行模式的用法说明
+func Usage() string {
+	var b strings.Builder
+	b.WriteString("用法: tiktok_tool <命令> [参数]\n\n命令:\n")
+	for _, c := range commands {
+		b.WriteString("  " + c.usage + "\n")
+	}
+	b.WriteString("\n通用参数:\n")
+	b.WriteString("  --json          以JSON格式输出结果\n")
+	b.WriteString("  --profile=方案  使用指定的配置方案, 不修改配置文件\n")
+	b.WriteString("  --config=路径、--<配置段>.<键名>=值  与界面模式相同的配置覆盖参数\n")
+	b.WriteString("\n退出码: 0 成功, 1 失败, 2 参数错误, 3 等待推流信息超时\n")
+	return b.String()
+}
+
+// Run 执行命令行模式的子命令, 返回进程退出码
+// 结果输出到标准输出, 进度和日志之外的提示输出到标准错误
+// loadErr 为加载配置失败的错误, doctor 使用默认配置继续检查并报告该错误, 其他命令直接失败
+func Run(args []string, loadErr error) int {
+	return run(args, loadErr, os.Stdout, os.Stderr)
+}
+
+func run(args []string, loadErr error, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == CommandHelp {
+		_, _ = fmt.Fprint(stdout, Usage())
+		return ExitOK
+	}
+	var cmd *command
+	for i := range commands {
+		if commands[i].name == args[0] {
+			cmd = &commands[i]
+		}
+	}
+	if cmd == nil {
+		_, _ = fmt.Fprintf(stderr, "未知的命令: %s\n\n%s", args[0], Usage())
+		return ExitUsage
+	}
+
+	// 日志只写入文件, 标准输出只输出结果
+	if logConfig := config.GetConfig().LogConfig; logConfig != nil {
+		setting := *logConfig
+		setting.Console = false
+		if err := llog.Init(&setting); err != nil {
+			_, _ = fmt.Fprintf(stderr, "初始化日志系统失败: %v\n", err)
+		}
+		defer llog.Cleanup()
+	}
+	// 退出前停止抓包等后台任务
+	defer func() {
+		if err := lkit.Shutdown(lkit.ShutdownTimeout); err != nil {
+			logger.Warn("停止后台任务失败", llog.Err(err))
+		}
+	}()
+
+	// Ctrl+C 时取消正在执行的命令
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	o := &options{stderr: stderr, loadErr: loadErr}
+	var result any
+	var text string
+	var err error
+	if loadErr != nil && cmd.name != CommandDoctor {
+		err = fmt.Errorf("加载配置失败: %v", loadErr)
+	} else {
+		result, text, err = cmd.run(ctx, o, args[1:])
+	}
+	if err != nil {
+		logger.Warn("命令执行失败", llog.String("command", cmd.name), llog.Err(err))
+	}
+	// 参数错误时 --json 可能还未解析
+	if value, ok := config.LookupFlag(args, JSONFlag); err != nil && ok && (value == "" || value == "true") {
+		o.json = true
+	}
+
+	if o.json {
+		out := envelope{OK: err == nil, Command: cmd.name, Result: result}
+		if err != nil {
+			out.Error = err.Error()
+		}
+		encoder := json.NewEncoder(stdout)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		_ = encoder.Encode(out)
+	} else {
+		if text != "" {
+			_, _ = fmt.Fprintln(stdout, text)
+		}
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+		}
+	}
+	return exitCode(err)
+}
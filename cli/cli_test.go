@@ -0,0 +1,76 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"tiktok_tool/live"
+)
+
+func TestIsCommand(t *testing.T) {
+	for args, want := range map[string]bool{
+		"capture --json":   true,
+		"config get":       true,
+		"help":             true,
+		"--start-capture":  false,
+		"--one-click":      false,
+		"--profile=B show": false,
+		"":                 false,
+	} {
+		if got := IsCommand(strings.Fields(args)); got != want {
+			t.Errorf("IsCommand(%q) = %v, 期望 %v", args, got, want)
+		}
+	}
+}
+
+func TestParseArgs(t *testing.T) {
+	o := &options{}
+	fs := o.flagSet(CommandConfig)
+	// 配置覆盖参数不影响子命令参数, 参数可以出现在位置参数之后
+	args := []string{"--config", "a.toml", "get", "--monitor.enable", "monitor.max_rtt_ms", "--base.capture_backend", "replay", "--json"}
+	positional, err := o.parse(fs, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(positional, []string{"get", "monitor.max_rtt_ms"}) || !o.json {
+		t.Errorf("解析参数错误: %v json=%v", positional, o.json)
+	}
+
+	if _, err = (&options{}).parse((&options{}).flagSet(CommandCapture), []string{"--unknown"}); exitCode(err) != ExitUsage {
+		t.Errorf("未知参数应返回参数错误: %v", err)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	for err, want := range map[error]int{
+		nil:                    ExitOK,
+		usageError("参数错误"):     ExitUsage,
+		live.ErrCaptureTimeout: ExitTimeout,
+		fmt.Errorf("导入失败"):     ExitFailed,
+	} {
+		if got := exitCode(err); got != want {
+			t.Errorf("exitCode(%v) = %d, 期望 %d", err, got, want)
+		}
+	}
+}
+
+func TestRunWithLoadError(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := run([]string{CommandConfig, "get", "--json"}, errors.New("toml: line 3: expected '='"), &stdout, &stderr)
+	if code != ExitFailed {
+		t.Errorf("退出码错误: %d", code)
+	}
+
+	var out envelope
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		t.Fatalf("输出不是JSON: %v %s", err, stdout.String())
+	}
+	if out.OK || out.Command != CommandConfig || !strings.Contains(out.Error, "加载配置失败") {
+		t.Errorf("输出错误: %+v", out)
+	}
+}
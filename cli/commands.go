@@ -0,0 +1,274 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"tiktok_tool/config"
+	"tiktok_tool/diag"
+	"tiktok_tool/live"
+	"tiktok_tool/llog"
+)
+
+// DefaultCaptureTimeout capture 命令默认等待推流信息的时间
+const DefaultCaptureTimeout = 60 * time.Second
+
+// captureResult capture 命令的结果
+type captureResult struct {
+	live.StreamInfo
+	ImportedBy string `json:"imported_by,omitempty"` // 导入OBS的方式, 未导入时为空
+}
+
+// runCapture 抓取推流信息, 可选导入OBS
+func runCapture(ctx context.Context, o *options, args []string) (any, string, error) {
+	fs := o.flagSet(CommandCapture)
+	timeout := fs.Duration("timeout", DefaultCaptureTimeout, "等待推流信息的最长时间")
+	importOBS := fs.Bool("import", false, "抓取后导入OBS")
+	if positional, err := o.parse(fs, args); err != nil {
+		return nil, "", err
+	} else if len(positional) > 0 {
+		return nil, "", usageError("多余的参数: %s", strings.Join(positional, " "))
+	}
+	if *timeout <= 0 {
+		return nil, "", usageError("--timeout 需大于0")
+	}
+
+	session, err := live.StartCapture(live.CaptureHooks{})
+	if err != nil {
+		return nil, "", err
+	}
+	_, _ = fmt.Fprintf(o.stderr, "正在抓包(方案: %s), 请在直播伴侣中开始直播...\n", config.GetConfig().Profile().Name)
+	info, err := session.Wait(ctx, *timeout)
+	if err != nil {
+		return nil, "", err
+	}
+
+	result := captureResult{StreamInfo: info}
+	text := formatStreamInfo(info)
+	if *importOBS {
+		if result.ImportedBy, err = live.ImportOBSConfig(info.Server, info.StreamKey); err != nil {
+			return result, text, fmt.Errorf("导入OBS配置失败：%v", err)
+		}
+		text += "\n已导入OBS配置(" + result.ImportedBy + ")"
+	}
+	return result, text, nil
+}
+
+// formatStreamInfo 推流信息的文本输出
+func formatStreamInfo(info live.StreamInfo) string {
+	text := fmt.Sprintf("服务器地址: %s\n推流码: %s", info.Server, info.StreamKey)
+	if info.StreamIP != "" {
+		text += "\n推流IP: " + info.StreamIP
+	}
+	return text
+}
+
+// runImportOBS 导入推流配置到OBS
+func runImportOBS(_ context.Context, o *options, args []string) (any, string, error) {
+	fs := o.flagSet(CommandImportOBS)
+	server := fs.String("server", "", "推流服务器地址")
+	key := fs.String("key", "", "推流码")
+	if positional, err := o.parse(fs, args); err != nil {
+		return nil, "", err
+	} else if len(positional) > 0 {
+		return nil, "", usageError("多余的参数: %s", strings.Join(positional, " "))
+	}
+	if strings.TrimSpace(*server) == "" || strings.TrimSpace(*key) == "" {
+		return nil, "", usageError("需要指定 --server 和 --key\n用法: %s", usageOf(CommandImportOBS))
+	}
+
+	method, err := live.ImportOBSConfig(*server, *key)
+	if err != nil {
+		return nil, "", err
+	}
+	return map[string]string{"imported_by": method}, "推流配置已成功导入到OBS(" + method + ")", nil
+}
+
+// runOneClick 执行一键开播流程
+func runOneClick(ctx context.Context, o *options, args []string) (any, string, error) {
+	fs := o.flagSet(CommandOneClick)
+	if positional, err := o.parse(fs, args); err != nil {
+		return nil, "", err
+	} else if len(positional) > 0 {
+		return nil, "", usageError("多余的参数: %s", strings.Join(positional, " "))
+	}
+	if err := live.ValidateOneClick(); err != nil {
+		return nil, "", err
+	}
+
+	// 本次一键开播期间的日志都会带上流程ID
+	unbind := llog.Bind(llog.RunIDKey, llog.NewID())
+	defer unbind()
+
+	info, err := live.RunOneClick(ctx, live.OneClickHooks{
+		OnProgress: func(text string, value float64) {
+			_, _ = fmt.Fprintf(o.stderr, "[%3.0f%%] %s\n", value*100, text)
+		},
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return info, formatStreamInfo(info) + "\n一键开播流程已完成！", nil
+}
+
+// problem 配置问题的输出
+type problem struct {
+	Field   string `json:"field"`
+	Level   string `json:"level"`
+	Message string `json:"message"`
+}
+
+// doctorResult doctor 命令的结果
+type doctorResult struct {
+	System   *diag.SystemInfo `json:"system"`
+	Problems []problem        `json:"problems"`
+}
+
+// runDoctor 检查运行环境和配置, 抓包后端不可用或配置有错误时返回错误
+func runDoctor(_ context.Context, o *options, args []string) (any, string, error) {
+	fs := o.flagSet(CommandDoctor)
+	if positional, err := o.parse(fs, args); err != nil {
+		return nil, "", err
+	} else if len(positional) > 0 {
+		return nil, "", usageError("多余的参数: %s", strings.Join(positional, " "))
+	}
+
+	system := diag.CollectSystem()
+	var names []string
+	if system.DeviceError == "" {
+		names = make([]string, 0, len(system.Devices))
+		for _, device := range system.Devices {
+			names = append(names, device.Description)
+		}
+	}
+	problems := config.GetConfig().Validate(names)
+	if o.loadErr != nil {
+		// 配置文件无法加载时其余检查使用默认配置
+		problems = append([]config.Problem{{Field: "config", Level: config.ProblemError, Message: fmt.Sprintf("加载配置失败: %v", o.loadErr)}}, problems...)
+	}
+
+	result := doctorResult{System: system, Problems: make([]problem, 0, len(problems))}
+	for _, p := range problems {
+		result.Problems = append(result.Problems, problem{Field: p.Field, Level: p.Level.String(), Message: p.Message})
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "版本: %s (%s/%s, 管理员权限: %v)\n", system.AppVersion, system.OS, system.Arch, system.Admin)
+	fmt.Fprintf(&b, "数据目录: %s (便携模式: %v)\n", system.DataDir, system.Portable)
+	fmt.Fprintf(&b, "配置文件: %s (方案: %s)\n", system.ConfigFile, system.Profile)
+	if system.BackendError != "" {
+		fmt.Fprintf(&b, "抓包后端: %s 不可用: %s\n", system.Backend, system.BackendError)
+	} else {
+		fmt.Fprintf(&b, "抓包后端: %s\n", system.Backend)
+	}
+	if system.DeviceError != "" {
+		fmt.Fprintf(&b, "网卡: 获取失败: %s\n", system.DeviceError)
+	} else {
+		fmt.Fprintf(&b, "网卡: %d 个\n", len(system.Devices))
+		for _, device := range system.Devices {
+			fmt.Fprintf(&b, "  %s\n", device)
+		}
+	}
+	for _, process := range system.Processes {
+		switch {
+		case process.Error != "":
+			fmt.Fprintf(&b, "%s: 检查失败: %s\n", process.Name, process.Error)
+		case process.Running:
+			fmt.Fprintf(&b, "%s: 运行中 %v\n", process.Name, process.PIDs)
+		default:
+			fmt.Fprintf(&b, "%s: 未运行\n", process.Name)
+		}
+	}
+	if system.Plugin.Path == "" {
+		b.WriteString("自动化插件: 未配置\n")
+	} else {
+		fmt.Fprintf(&b, "自动化插件: %s (存在: %v)\n", system.Plugin.Path, system.Plugin.Exists)
+	}
+	if system.RuleError != "" {
+		fmt.Fprintf(&b, "规则包: 加载失败: %s\n", system.RuleError)
+	} else if system.RuleVersion == 0 {
+		b.WriteString("规则包: 内置规则\n")
+	} else {
+		fmt.Fprintf(&b, "规则包: 版本 %d\n", system.RuleVersion)
+	}
+	if len(problems) == 0 {
+		b.WriteString("没有发现配置问题")
+	} else {
+		b.WriteString("配置问题:")
+		for _, p := range problems {
+			b.WriteString("\n  " + p.String())
+		}
+	}
+
+	var err error
+	if o.loadErr != nil {
+		err = fmt.Errorf("加载配置失败: %v", o.loadErr)
+	} else if system.BackendError != "" {
+		err = fmt.Errorf("抓包后端不可用: %s", system.BackendError)
+	} else if config.HasError(problems) {
+		err = errors.New("配置存在错误, 请修改后重试")
+	}
+	return result, b.String(), err
+}
+
+// fieldResult 配置项的输出
+type fieldResult struct {
+	Key    string `json:"key"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+func newFieldResult(field config.Field) fieldResult {
+	return fieldResult{Key: field.Key, Value: field.Value, Source: field.Source.String()}
+}
+
+// runConfig 读取或修改配置项, 密码等敏感配置项的值会被隐藏
+func runConfig(_ context.Context, o *options, args []string) (any, string, error) {
+	fs := o.flagSet(CommandConfig)
+	positional, err := o.parse(fs, args)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(positional) == 0 {
+		return nil, "", usageError("需要指定 get 或 set\n用法: %s", usageOf(CommandConfig))
+	}
+
+	switch action := positional[0]; {
+	case action == "get" && len(positional) == 1:
+		fields := config.Effective()
+		results := make([]fieldResult, 0, len(fields))
+		lines := make([]string, 0, len(fields))
+		for _, field := range fields {
+			results = append(results, newFieldResult(field))
+			lines = append(lines, fmt.Sprintf("%s = %s  (%s)", field.Key, field.Value, field.Source))
+		}
+		return results, strings.Join(lines, "\n"), nil
+	case action == "get" && len(positional) == 2:
+		field, ok := config.Lookup(positional[1])
+		if !ok {
+			return nil, "", fmt.Errorf("配置项不存在: %s", positional[1])
+		}
+		return newFieldResult(field), field.Value, nil
+	case action == "set" && len(positional) == 3:
+		// 配置方案切换只在本次运行中生效, 修改配置项时无法对应到配置文件
+		if o.profile != "" {
+			return nil, "", usageError("config set 不支持 --%s, 修改的是配置文件中当前方案的配置项", ProfileFlag)
+		}
+		key := positional[1]
+		old, err := config.SetValue(key, positional[2])
+		if err != nil {
+			return nil, "", err
+		}
+		field, _ := config.Lookup(key)
+		result := map[string]string{"key": field.Key, "old": old, "value": field.Value}
+		text := fmt.Sprintf("%s: %s -> %s", field.Key, old, field.Value)
+		if field.Source == config.SourceEnv || field.Source == config.SourceFlag {
+			text += fmt.Sprintf("\n注意: 当前生效的值来自%s, 配置文件中的值不会生效", field.Source)
+		}
+		return result, text, nil
+	}
+	return nil, "", usageError("参数错误\n用法: %s", usageOf(CommandConfig))
+}
@@ -0,0 +1,181 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// BackupCount 保留的历史配置数量
+const BackupCount = 5
+
+// 启动时配置文件损坏并已恢复为备份的问题
+var recoverProblems []Problem
+
+// Backup 历史配置
+type Backup struct {
+	Path    string
+	Index   int       // 1 为最近一次保存前的配置
+	ModTime time.Time // 该配置保存的时间
+}
+
+// settingsPath 保存配置文件的路径, 配置目录无法创建时保存到数据根目录
+// 使用 --config 指定配置文件时保存到指定的文件
+func settingsPath() string {
+	if customPath {
+		_ = os.MkdirAll(filepath.Dir(configPath), 0755)
+		return configPath
+	}
+	configDir := CfgFilePath
+	if err := os.MkdirAll(configDir, 0755); err != nil {
+		configDir = filepath.Dir(CfgFilePath)
+	}
+	return filepath.Join(configDir, CfgFileName)
+}
+
+// backupPath 第 index 个历史配置的路径
+func backupPath(path string, index int) string {
+	return fmt.Sprintf("%s.%d.bak", path, index)
+}
+
+// WriteFileAtomic 先写入同目录的临时文件再重命名, 避免写入中断导致文件损坏
+func WriteFileAtomic(path string, content []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	if _, err = tmp.Write(content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmpPath, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// checkFile 检查配置文件能否正常加载
+func checkFile(path string) error {
+	noEnv := func(string) (string, bool) { return "", false }
+	_, _, err := loadLayers(path, noEnv, nil)
+	return err
+}
+
+// rotateBackups 将当前配置文件加入历史配置, 超出数量的最旧配置被删除
+// 无法加载的配置文件不会加入历史配置, 避免损坏的文件覆盖正常的备份
+func rotateBackups(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	if checkFile(path) != nil {
+		return nil
+	}
+
+	for i := BackupCount - 1; i >= 1; i-- {
+		if _, err = os.Stat(backupPath(path, i)); err == nil {
+			if err = os.Rename(backupPath(path, i), backupPath(path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	if err = WriteFileAtomic(backupPath(path, 1), content); err != nil {
+		return err
+	}
+
+	// 保留原配置文件的修改时间, 历史配置列表按此显示保存时间
+	if info, err := os.Stat(path); err == nil {
+		_ = os.Chtimes(backupPath(path, 1), info.ModTime(), info.ModTime())
+	}
+	return nil
+}
+
+// FilePath 保存配置文件的路径
+func FilePath() string {
+	return settingsPath()
+}
+
+// ListBackups 列出历史配置, 最近的在前
+func ListBackups() []Backup {
+	path := settingsPath()
+	backups := make([]Backup, 0, BackupCount)
+	for i := 1; i <= BackupCount; i++ {
+		info, err := os.Stat(backupPath(path, i))
+		if err != nil {
+			continue
+		}
+		backups = append(backups, Backup{Path: backupPath(path, i), Index: i, ModTime: info.ModTime()})
+	}
+	return backups
+}
+
+// RestoreBackup 恢复历史配置并重新加载, 恢复前的配置会加入历史配置
+func RestoreBackup(backup Backup) error {
+	if err := checkFile(backup.Path); err != nil {
+		return fmt.Errorf("历史配置无法加载: %v", err)
+	}
+	content, err := os.ReadFile(backup.Path)
+	if err != nil {
+		return err
+	}
+
+	path := settingsPath()
+	if err = rotateBackups(path); err != nil {
+		return fmt.Errorf("备份当前配置失败: %v", err)
+	}
+	markSelfWrite(content)
+	if err = WriteFileAtomic(path, content); err != nil {
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+	return ReloadConfig()
+}
+
+// recoverFromBackup 配置文件损坏时使用最近一个可以加载的历史配置替换, 损坏的文件另存为 .corrupt
+func recoverFromBackup(loadErr error) error {
+	path := configPath
+	if _, err := os.Stat(path); err != nil || checkFile(path) == nil {
+		// 不是配置文件本身的问题(如环境变量无效), 不使用历史配置
+		return loadErr
+	}
+
+	for _, backup := range ListBackups() {
+		if checkFile(backup.Path) != nil {
+			continue
+		}
+		content, err := os.ReadFile(backup.Path)
+		if err != nil {
+			continue
+		}
+
+		corruptPath := path + ".corrupt"
+		if err = os.Rename(path, corruptPath); err != nil {
+			return fmt.Errorf("%v, 且无法保留损坏的配置文件: %v", loadErr, err)
+		}
+		if err = WriteFileAtomic(path, content); err != nil {
+			return fmt.Errorf("%v, 且恢复历史配置失败: %v", loadErr, err)
+		}
+
+		recoverProblems = []Problem{{
+			Field: "config",
+			Message: fmt.Sprintf("配置文件加载失败(%v), 已恢复为 %s 保存的配置, 损坏的文件已另存为 %s",
+				loadErr, backup.ModTime.Format(time.DateTime), corruptPath),
+			Level: ProblemWarning,
+		}}
+		return nil
+	}
+	return loadErr
+}
@@ -0,0 +1,398 @@
+package config
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// BundleExt 配置包扩展名, 配置包为zip格式
+	BundleExt = ".ttbundle"
+
+	bundleManifestName = "manifest.json"
+	bundleRulesDir     = "rules/"
+
+	// 配置包中OBS程序目录的占位符, 导入时按方案中的OBS启动路径展开
+	obsDirPlaceholder = "${OBS_DIR}"
+)
+
+// RulesPath 规则包目录, 导出配置包时一并导出
+var RulesPath string
+
+// BundleManifest 配置包说明
+type BundleManifest struct {
+	Version        int       `json:"version"`         // 配置文件结构版本
+	ExportedAt     time.Time `json:"exported_at"`     // 导出时间
+	SecretsOmitted bool      `json:"secrets_omitted"` // 是否去除了敏感配置项
+}
+
+// Bundle 从配置包中读取的内容
+type Bundle struct {
+	Manifest BundleManifest
+	Config   *Config           // 路径占位符已展开的配置
+	Rules    map[string][]byte // 规则包文件, 键为文件名
+	Problems []Problem         // 敏感配置项解密失败等问题
+}
+
+// Change 配置项的变化
+type Change struct {
+	Key string
+	Old string
+	New string
+}
+
+// pathRoot 路径占位符及其在本机对应的目录
+type pathRoot struct {
+	name string
+	dir  string
+}
+
+// pathRoots 本机可用的路径占位符, 较长的目录优先匹配
+func pathRoots() []pathRoot {
+	roots := []pathRoot{
+		{name: "${APPDATA}", dir: os.Getenv("APPDATA")},
+		{name: "${LOCALAPPDATA}", dir: os.Getenv("LOCALAPPDATA")},
+		{name: "${PROGRAMFILES}", dir: os.Getenv("ProgramFiles")},
+		{name: "${PROGRAMFILES_X86}", dir: os.Getenv("ProgramFiles(x86)")},
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		roots = append(roots, pathRoot{name: "${HOME}", dir: home})
+	}
+
+	result := make([]pathRoot, 0, len(roots))
+	for _, root := range roots {
+		if root.dir != "" {
+			result = append(result, root)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return len(result[i].dir) > len(result[j].dir)
+	})
+	return result
+}
+
+// cutPathPrefix 判断 p 是否位于 dir 目录下, 返回相对路径
+func cutPathPrefix(p, dir string) (string, bool) {
+	p, dir = filepath.Clean(p), filepath.Clean(dir)
+	if len(p) <= len(dir) || !strings.EqualFold(p[:len(dir)], dir) || !os.IsPathSeparator(p[len(dir)]) {
+		return "", false
+	}
+	return filepath.ToSlash(p[len(dir)+1:]), true
+}
+
+// relativizePath 将路径改写为以占位符开头的形式, 不在已知目录下的路径保持不变
+func relativizePath(p, obsDir string, roots []pathRoot) string {
+	if p == "" {
+		return p
+	}
+	if obsDir != "" {
+		if rel, ok := cutPathPrefix(p, obsDir); ok {
+			return obsDirPlaceholder + "/" + rel
+		}
+	}
+	for _, root := range roots {
+		if rel, ok := cutPathPrefix(p, root.dir); ok {
+			return root.name + "/" + rel
+		}
+	}
+	return p
+}
+
+// expandPath 展开路径中的占位符, 本机不存在对应目录的占位符保持不变
+func expandPath(p, obsDir string, roots []pathRoot) string {
+	if obsDir != "" && strings.HasPrefix(p, obsDirPlaceholder+"/") {
+		return filepath.Join(obsDir, filepath.FromSlash(strings.TrimPrefix(p, obsDirPlaceholder+"/")))
+	}
+	for _, root := range roots {
+		if strings.HasPrefix(p, root.name+"/") {
+			return filepath.Join(root.dir, filepath.FromSlash(strings.TrimPrefix(p, root.name+"/")))
+		}
+	}
+	return p
+}
+
+// HasPathPlaceholder 判断路径中是否还有未展开的占位符
+func HasPathPlaceholder(p string) bool {
+	return strings.HasPrefix(p, "${")
+}
+
+// eachPath 遍历配置中的全部路径配置项
+// OBS启动路径只使用系统目录占位符, 其他路径可使用OBS程序目录占位符
+func eachPath(cfg *Config, fn func(p *string, obsDir string)) {
+	if cfg.BaseSettings != nil {
+		fn(&cfg.BaseSettings.ReplayFile, "")
+	}
+	for _, profile := range cfg.Profiles {
+		settings := profile.PathSettings
+		if settings == nil {
+			continue
+		}
+		// 导出时使用改写前的启动路径, 导入时使用展开后的启动路径
+		launchPath := settings.OBSLaunchPath
+		fn(&settings.OBSLaunchPath, "")
+		if !HasPathPlaceholder(settings.OBSLaunchPath) {
+			launchPath = settings.OBSLaunchPath
+		}
+
+		obsDir := ""
+		if launchPath != "" && !HasPathPlaceholder(launchPath) {
+			obsDir = filepath.Dir(launchPath)
+		}
+		fn(&settings.OBSConfigPath, obsDir)
+		fn(&settings.LiveCompanionPath, obsDir)
+		fn(&settings.PluginScriptPath, obsDir)
+	}
+}
+
+// expandRootPaths 只展开系统目录占位符, OBS程序目录占位符保持不变
+// 导入的OBS启动路径在本机可能不存在, 重新检测OBS启动路径后再由 ExpandPaths 展开
+func expandRootPaths(cfg *Config) {
+	roots := pathRoots()
+	eachPath(cfg, func(p *string, _ string) {
+		*p = expandPath(*p, "", roots)
+	})
+}
+
+// ExpandPaths 展开配置中路径的占位符, OBS程序目录占位符按当前的OBS启动路径展开
+func ExpandPaths(cfg *Config) {
+	roots := pathRoots()
+	eachPath(cfg, func(p *string, obsDir string) {
+		*p = expandPath(*p, obsDir, roots)
+	})
+}
+
+// ExportBundle 导出配置包, 包含配置文件(含全部配置方案)和规则包
+// includeSecrets 为true时敏感配置项使用主密码加密后导出, 需要设置主密码环境变量
+func ExportBundle(w io.Writer, settings *Config, includeSecrets bool) error {
+	exported := settings.Clone()
+	exported.Version = CurrentVersion
+
+	if includeSecrets {
+		if os.Getenv(MasterPasswordEnv) == "" {
+			return fmt.Errorf("导出密码需要先设置环境变量 %s 作为主密码", MasterPasswordEnv)
+		}
+		if err := encryptSecrets(exported); err != nil {
+			return err
+		}
+	} else {
+		stripSecrets(exported)
+	}
+
+	roots := pathRoots()
+	eachPath(exported, func(p *string, obsDir string) {
+		*p = relativizePath(*p, obsDir, roots)
+	})
+
+	zw := zip.NewWriter(w)
+
+	manifest, err := json.MarshalIndent(BundleManifest{
+		Version:        CurrentVersion,
+		ExportedAt:     time.Now(),
+		SecretsOmitted: !includeSecrets,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = writeZipFile(zw, bundleManifestName, manifest); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err = toml.NewEncoder(&buf).Encode(exported); err != nil {
+		return err
+	}
+	if err = writeZipFile(zw, CfgFileName, buf.Bytes()); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(RulesPath)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("读取规则包目录失败: %v", err)
+	}
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(RulesPath, entry.Name()))
+		if err != nil {
+			return fmt.Errorf("读取规则包失败: %v", err)
+		}
+		if err = writeZipFile(zw, bundleRulesDir+entry.Name(), content); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+// writeZipFile 向配置包写入文件
+func writeZipFile(zw *zip.Writer, name string, content []byte) error {
+	fw, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(content)
+	return err
+}
+
+// ReadBundle 读取配置包, 旧版本导出的配置会先迁移, 系统目录占位符会按本机目录展开
+// OBS程序目录占位符(${OBS_DIR})保持不变, 重新检测OBS启动路径后调用 ExpandPaths 展开, 保存时未展开的会按导入的启动路径展开
+func ReadBundle(r io.ReaderAt, size int64) (*Bundle, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("配置包格式错误: %v", err)
+	}
+
+	bundle := &Bundle{Rules: make(map[string][]byte)}
+	var cfgContent []byte
+	for _, file := range zr.File {
+		content, err := readZipFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取配置包文件 %s 失败: %v", file.Name, err)
+		}
+		switch {
+		case file.Name == bundleManifestName:
+			if err = json.Unmarshal(content, &bundle.Manifest); err != nil {
+				return nil, fmt.Errorf("配置包说明格式错误: %v", err)
+			}
+		case file.Name == CfgFileName:
+			cfgContent = content
+		case strings.HasPrefix(file.Name, bundleRulesDir) && !file.FileInfo().IsDir():
+			// 只取文件名, 避免路径穿越
+			bundle.Rules[path.Base(file.Name)] = content
+		}
+	}
+	if cfgContent == nil {
+		return nil, fmt.Errorf("配置包中没有配置文件")
+	}
+
+	// 复用配置文件的迁移和加载流程, 导入的配置不受本机环境变量和命令行参数影响
+	dir, err := os.MkdirTemp("", "tiktok_tool_bundle")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	tmpPath := filepath.Join(dir, CfgFileName)
+	if err = os.WriteFile(tmpPath, cfgContent, 0600); err != nil {
+		return nil, err
+	}
+	if err = migrateFile(tmpPath); err != nil {
+		return nil, err
+	}
+	noEnv := func(string) (string, bool) { return "", false }
+	if bundle.Config, _, err = loadLayers(tmpPath, noEnv, nil); err != nil {
+		return nil, err
+	}
+
+	bundle.Problems = secretProblems(bundle.Config, decryptSecrets(bundle.Config))
+	expandRootPaths(bundle.Config)
+	return bundle, nil
+}
+
+// readZipFile 读取配置包中的文件
+func readZipFile(file *zip.File) ([]byte, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// ApplyBundle 保存导入的配置和规则包并重新加载配置
+// 配置包未包含敏感配置项时保留本机同名方案的敏感配置项
+func ApplyBundle(bundle *Bundle) error {
+	settings := bundle.Config.Clone()
+	ExpandPaths(settings)
+	if bundle.Manifest.SecretsOmitted {
+		keepSecrets(settings, GetConfig())
+	}
+
+	if len(bundle.Rules) > 0 {
+		if err := os.MkdirAll(RulesPath, 0755); err != nil {
+			return fmt.Errorf("创建规则包目录失败: %v", err)
+		}
+		for name, content := range bundle.Rules {
+			if err := os.WriteFile(filepath.Join(RulesPath, name), content, 0644); err != nil {
+				return fmt.Errorf("写入规则包失败: %v", err)
+			}
+		}
+	}
+
+	// 配置包只包含配置文件层, 不需要还原覆盖项
+	if err := saveFile(settings, settings.Clone()); err != nil {
+		return err
+	}
+	return ReloadConfig()
+}
+
+// keepSecrets 将 current 中的敏感配置项填入 settings 中为空的同名配置项
+// 两份配置的当前方案可能不同, 按方案名称对应
+func keepSecrets(settings, current *Config) {
+	existing := make(map[string]string)
+	eachSecret(current, func(key string, value reflect.Value) {
+		existing[secretID(current, key)] = value.String()
+	})
+	eachSecret(settings, func(key string, value reflect.Value) {
+		if value.String() == "" {
+			value.SetString(existing[secretID(settings, key)])
+		}
+	})
+}
+
+// flattenConfig 展开配置中的全部配置项, 包括所有配置方案
+func flattenConfig(cfg *Config) map[string]string {
+	cfg = cfg.Clone()
+	fields := make(map[string]string)
+	collect := func(key string, value reflect.Value) error {
+		fields[key] = formatValue(key, value)
+		return nil
+	}
+
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		_ = walkSection(tomlName(root.Type().Field(i)), root.Field(i), collect)
+	}
+	for _, profile := range cfg.Profiles {
+		prefix := "profiles." + profile.Name
+		_ = walkSection(prefix, reflect.ValueOf(profile), collect)
+	}
+	return fields
+}
+
+// Diff 比较两份配置, 返回有变化的配置项, 方案中的配置项以 profiles.<方案名>. 为前缀
+func Diff(old, new *Config) []Change {
+	before, after := flattenConfig(old), flattenConfig(new)
+
+	keys := make([]string, 0, len(after))
+	for key := range after {
+		keys = append(keys, key)
+	}
+	for key := range before {
+		if _, ok := after[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := make([]Change, 0)
+	for _, key := range keys {
+		if before[key] != after[key] {
+			changes = append(changes, Change{Key: key, Old: before[key], New: after[key]})
+		}
+	}
+	return changes
+}
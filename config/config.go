@@ -1,30 +1,33 @@
 package config
 
 import (
-	"errors"
+	"bytes"
+	"fmt"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sync"
 
+	"tiktok_tool/appdir"
 	"tiktok_tool/llog"
 
 	"github.com/BurntSushi/toml"
 )
 
-const (
-	CfgFilePath = "config"               // 配置文件目录
-	CfgFileName = "tiktok_tool_cfg.toml" // 配置文件名
-)
-
-var (
-	AlreadyTop = errors.New("AlreadyTop")
-)
+const CfgFileName = "tiktok_tool_cfg.toml" // 配置文件名
 
 var (
 	Debug   string
 	IsDebug bool
 
+	CfgFilePath string // 配置文件目录, 位于数据根目录下
+
+	// 配置文件监听协程重新加载时会替换配置, 读写 currentConfig、configSources 和 keptSecrets 需要加锁
+	configMutex   sync.RWMutex
 	currentConfig *Config
+	configSources map[string]Source // 各配置项的来源, 未记录的为默认值
 	configPath    string
+	customPath    bool // 是否使用 --config 指定了配置文件
 )
 
 func init() {
@@ -32,25 +35,33 @@ func init() {
 		IsDebug = true
 	}
 
-	configPath = filepath.Join(CfgFilePath, CfgFileName)
+	setConfigDir(appdir.Join("config"))
+}
+
+// setConfigDir 设置配置文件目录, 同时更新位于配置目录下的文件路径
+func setConfigDir(dir string) {
+	CfgFilePath = dir
+	configPath = filepath.Join(dir, CfgFileName)
+	secretKeyPath = filepath.Join(dir, "secret.key")
+	RulesPath = filepath.Join(dir, "rules")
 }
 
 type Config struct {
-	BaseSettings   *BaseSettings    `toml:"base"`   // 基础设置
-	PathSettings   *PathSettings    `toml:"path"`   // 路径设置
-	ScriptSettings *ScriptSettings  `toml:"script"` // 脚本设置
-	LogConfig      *llog.LogSetting `toml:"log"`    // 日志配置
+	Version         int              `toml:"version"`  // 配置文件结构版本
+	BaseSettings    *BaseSettings    `toml:"base"`     // 基础设置
+	MonitorSettings *MonitorSettings `toml:"monitor"`  // 推流监控设置
+	RuleSettings    *RuleSettings    `toml:"rules"`    // 规则包更新设置
+	LogConfig       *llog.LogSetting `toml:"log"`      // 日志配置
+	Profiles        []*Profile       `toml:"profiles"` // 配置方案, 每个直播账号/直播间一个
 }
 
 type BaseSettings struct {
 	NetworkInterfaces []string `toml:"network_interfaces"`   // 网卡名称列表
-	ServerRegex       string   `toml:"server_regex"`         // 服务器地址正则表达式
-	StreamKeyRegex    string   `toml:"stream_key_regex"`     // 推流码正则表达式
+	CaptureBackend    string   `toml:"capture_backend"`      // 抓包后端(pcap/afpacket/replay), 为空时使用平台默认
+	ReplayFile        string   `toml:"replay_file"`          // replay 后端回放的抓包文件路径
+	ActiveProfile     string   `toml:"active_profile"`       // 当前使用的配置方案名称
 	MinimizeOnClose   bool     `toml:"minimize_on_close"`    // 关闭窗口时最小化到系统托盘而不退出
 	OpenLiveWhenStart bool     `toml:"open_live_when_start"` // 启动时自动打开直播伴侣
-	OBSWsIp           string   `toml:"obs_ws_ip"`            // OBS WebSocket IP地址
-	OBSWsPort         int32    `toml:"obs_ws_port"`          // OBS WebSocket端口
-	OBSWsPassword     string   `toml:"obs_ws_password"`      // OBS WebSocket密码
 }
 
 type PathSettings struct {
@@ -66,57 +77,261 @@ type ScriptSettings struct {
 	PluginTimeout        int32 `toml:"plugin_timeout"`          // 插件超时时间（秒）
 }
 
+type MonitorSettings struct {
+	Enable            bool  `toml:"enable"`              // 获取到推流地址后监控推流连接健康状态
+	TrackSession      bool  `toml:"track_session"`       // 跟踪推流开始/中断/结束并记录会话时长
+	MinBitrateKbps    int32 `toml:"min_bitrate_kbps"`    // 上传码率低于该值时告警
+	MaxRetransPercent int32 `toml:"max_retrans_percent"` // 重传率高于该值时告警
+	MaxRTTMs          int32 `toml:"max_rtt_ms"`          // RTT高于该值时告警
+}
+
+type RuleSettings struct {
+	UpdateURL string `toml:"update_url"` // 规则包更新地址
+	AutoCheck bool   `toml:"auto_check"` // 启动时检查规则包更新
+}
+
 // DefaultConfig 默认配置
 var DefaultConfig = Config{
+	Version: CurrentVersion,
 	BaseSettings: &BaseSettings{
 		NetworkInterfaces: make([]string, 0),
-		ServerRegex:       `(rtmp://push-rtmp[^ ]*?\.douyincdn\.com[^\x00\r\n ]*)`,
-		StreamKeyRegex:    `(stream-[^\s]*?expire=\d{10}&sign=[^\s]+[^\x00\r\n ]*)`,
+		ActiveProfile:     DefaultProfileName,
 		MinimizeOnClose:   false,
 		OpenLiveWhenStart: true,
 	},
-	PathSettings: &PathSettings{},
-	ScriptSettings: &ScriptSettings{
-		PluginCheckInterval:  1,
-		PluginWaitAfterFound: 5,
-		PluginTimeout:        20,
+	MonitorSettings: &MonitorSettings{
+		Enable:            false,
+		TrackSession:      false,
+		MinBitrateKbps:    500,
+		MaxRetransPercent: 5,
+		MaxRTTMs:          300,
+	},
+	// 暂未发布签名的规则包, 默认不设置更新地址也不自动检查
+	RuleSettings: &RuleSettings{
+		UpdateURL: "",
+		AutoCheck: false,
 	},
 	LogConfig: llog.DefaultConfig,
+	Profiles:  []*Profile{&DefaultProfile},
 }
 
-// LoadConfig 加载配置文件
+// LoadConfig 加载配置
+// 优先级从低到高依次为: 内置默认值、配置文件、环境变量、命令行参数
+// 配置文件损坏时使用最近一个可以加载的历史配置
 func LoadConfig() error {
-	if _, err := os.Stat(configPath); err != nil {
-		configPath = filepath.Join(CfgFileName)
-		if _, err = os.Stat(configPath); err != nil {
-			return nil
+	applyConfigFlag()
+
+	cfg, sources, err := loadConfig()
+	if err != nil {
+		if err = recoverFromBackup(err); err != nil {
+			return err
+		}
+		if cfg, sources, err = loadConfig(); err != nil {
+			return err
 		}
 	}
-	currentConfig = &Config{}
-	_, err := toml.DecodeFile(configPath, currentConfig)
-	return err
+	configMutex.Lock()
+	currentConfig, configSources = cfg, sources
+	configMutex.Unlock()
+	return nil
 }
 
-// SaveSettings 保存配置文件
-func SaveSettings(settings *Config) error {
-	configDir := CfgFilePath
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		configDir = "."
+// LoadConfigReadOnly 加载配置但不修改配置文件, 用于未获取单实例锁时读取配置(如 --print-config 和命令行模式)
+// 此时配置文件可能属于已运行的实例: 需要迁移时只迁移临时副本, 损坏时不恢复历史配置
+func LoadConfigReadOnly() error {
+	applyConfigFlag()
+
+	cfg, sources, err := loadConfigFrom(true)
+	if err != nil {
+		return err
+	}
+	configMutex.Lock()
+	currentConfig, configSources = cfg, sources
+	configMutex.Unlock()
+	return nil
+}
+
+// applyConfigFlag 使用 --config 指定的配置文件
+func applyConfigFlag() {
+	if path, ok := LookupFlag(os.Args[1:], ConfigFlag); ok && path != "" {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		configPath, customPath = path, true
+	}
+}
+
+// loadConfig 查找配置文件, 必要时迁移后按层合并
+func loadConfig() (*Config, map[string]Source, error) {
+	return loadConfigFrom(false)
+}
+
+// loadConfigFrom 查找配置文件并按层合并, readOnly 为true时在临时副本上迁移
+func loadConfigFrom(readOnly bool) (*Config, map[string]Source, error) {
+	path := configPath
+	if _, err := os.Stat(path); err != nil && customPath {
+		// 指定的配置文件不存在时使用默认值, 保存时创建
+		path = ""
+	} else if err != nil {
+		path = filepath.Join(filepath.Dir(CfgFilePath), CfgFileName)
+		if _, err = os.Stat(path); err != nil {
+			path = ""
+		} else {
+			configPath = path
+		}
 	}
 
-	savePath := filepath.Join(configDir, CfgFileName)
-	file, err := os.Create(savePath)
+	if path != "" && readOnly {
+		copyPath, cleanup, err := migratedCopy(path)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer cleanup()
+		path = copyPath
+	} else if path != "" {
+		if err := migrateFile(path); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	cfg, sources, err := loadLayers(path, os.LookupEnv, os.Args[1:])
 	if err != nil {
+		return nil, nil, err
+	}
+	kept := decryptSecrets(cfg)
+	configMutex.Lock()
+	keptSecrets = kept
+	configMutex.Unlock()
+	llog.SetSecrets("config", secretValues(cfg)...)
+	return cfg, sources, nil
+}
+
+// SaveSettings 保存配置文件, 保存前的配置会加入历史配置
+// settings 通常由生效配置修改而来, 未修改的环境变量和命令行参数覆盖项保存为配置文件中的值
+func SaveSettings(settings *Config) error {
+	encrypted := settings.Clone()
+	if err := restoreOverrides(encrypted); err != nil {
+		return err
+	}
+	return saveFile(settings, encrypted)
+}
+
+// saveFile 保存只包含配置文件层的配置, 例如 SetValue 和导入的配置包
+func saveFile(settings, encrypted *Config) error {
+	settings.Version = CurrentVersion
+	encrypted.Version = CurrentVersion
+
+	// 敏感配置项加密后保存, 旧版本保存的明文也会在此时加密
+	if err := encryptSecrets(encrypted); err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(encrypted); err != nil {
 		return err
 	}
-	defer file.Close()
 
-	return toml.NewEncoder(file).Encode(settings)
+	savePath := settingsPath()
+	if err := rotateBackups(savePath); err != nil {
+		return fmt.Errorf("备份配置文件失败: %v", err)
+	}
+	markSelfWrite(buf.Bytes())
+	return WriteFileAtomic(savePath, buf.Bytes())
+}
+
+// loadFileLayer 只加载配置文件, 不合并环境变量和命令行参数, 敏感配置项未解密
+// 旧版本的配置文件在临时副本上迁移后加载, 保存时写入迁移后的结构, 避免丢失移动过的配置项
+func loadFileLayer() (*Config, error) {
+	path := configPath
+	if _, err := os.Stat(path); err != nil {
+		path = ""
+	} else {
+		copyPath, cleanup, err := migratedCopy(path)
+		if err != nil {
+			return nil, err
+		}
+		defer cleanup()
+		path = copyPath
+	}
+	cfg, _, err := loadLayers(path, func(string) (string, bool) { return "", false }, nil)
+	return cfg, err
+}
+
+// restoreOverrides 将 settings 中未修改的环境变量和命令行参数覆盖项还原为配置文件中的值
+// 覆盖项只作用于当前方案, 按方案名称对应, settings 切换了方案时同样适用
+func restoreOverrides(settings *Config) error {
+	var overridden []string
+	for key, source := range getSources() {
+		if source == SourceEnv || source == SourceFlag {
+			overridden = append(overridden, key)
+		}
+	}
+	if len(overridden) == 0 {
+		return nil
+	}
+
+	file, err := loadFileLayer()
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+	current := GetConfig()
+	profile := current.Profile().Name
+	settingsValues := layerValues(settings, profile)
+	currentValues := layerValues(current, profile)
+	fileValues := layerValues(file, profile)
+	for _, key := range overridden {
+		// 在界面中修改过的覆盖项与生效值不同, 正常保存
+		value, effective, fileValue := settingsValues[key], currentValues[key], fileValues[key]
+		if value.IsValid() && effective.IsValid() && fileValue.IsValid() && reflect.DeepEqual(value.Interface(), effective.Interface()) {
+			value.Set(fileValue)
+		}
+	}
+	return nil
+}
+
+// layerValues 配置中的全部配置项, 指定方案中的配置项以 profile. 为前缀, 与配置项来源的键名一致
+func layerValues(cfg *Config, profile string) map[string]reflect.Value {
+	values := make(map[string]reflect.Value)
+	collect := func(key string, value reflect.Value) error {
+		values[key] = value
+		return nil
+	}
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		_ = walkSection(tomlName(root.Type().Field(i)), root.Field(i), collect)
+	}
+	if p := cfg.FindProfile(profile); p != nil {
+		_ = walkSection(ProfileSection, reflect.ValueOf(p), collect)
+	}
+	return values
 }
 
 func GetConfig() *Config {
+	configMutex.RLock()
+	cfg := currentConfig
+	configMutex.RUnlock()
+	if cfg != nil {
+		return cfg
+	}
+
+	configMutex.Lock()
+	defer configMutex.Unlock()
 	if currentConfig == nil {
-		currentConfig = &DefaultConfig
+		currentConfig = DefaultConfig.Clone()
 	}
 	return currentConfig
 }
+
+// getSources 各配置项的来源
+func getSources() map[string]Source {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return configSources
+}
+
+// getKeptSecrets 加载配置时无法解密的密文
+func getKeptSecrets() map[string]keptSecret {
+	configMutex.RLock()
+	defer configMutex.RUnlock()
+	return keptSecrets
+}
@@ -0,0 +1,571 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestLoadLayers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), CfgFileName)
+	content := "version = 2\n" +
+		"[base]\nactive_profile = \"主号\"\n" +
+		"[[profiles]]\nname = \"小号\"\n" +
+		"[[profiles]]\nname = \"主号\"\nobs_ws_ip = \"127.0.0.1\"\nobs_ws_port = 4455\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{
+		"TIKTOK_TOOL_PROFILE_OBS_WS_PORT":     "4456",
+		"TIKTOK_TOOL_BASE_NETWORK_INTERFACES": "以太网, WLAN",
+		"TIKTOK_TOOL_BASE_STREAM_KEY_REGEX":   "(stream-.+)",
+	}
+	lookup := func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+	args := []string{"--profile.obs_ws_port=4457", "--monitor.enable", "--unknown", "value",
+		"--path.obs_launch_path", "obs64.exe", "--config", "other.toml"}
+
+	cfg, sources, err := loadLayers(path, lookup, args)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	profile := cfg.Profile()
+	if profile.Name != "主号" || len(cfg.Profiles) != 2 {
+		t.Fatalf("当前配置方案错误: %s", profile.Name)
+	}
+
+	// 配置文件中缺失的配置段使用默认值填充
+	if profile.ScriptSettings == nil || profile.ScriptSettings.PluginTimeout != DefaultProfile.ScriptSettings.PluginTimeout {
+		t.Errorf("缺失的配置段未使用默认值: %+v", profile.ScriptSettings)
+	}
+	if cfg.LogConfig == nil || cfg.LogConfig.Level != DefaultConfig.LogConfig.Level {
+		t.Errorf("缺失的日志配置未使用默认值: %+v", cfg.LogConfig)
+	}
+
+	checks := []struct {
+		key    string
+		source Source
+	}{
+		{"base.active_profile", SourceFile},
+		{"profile.obs_ws_ip", SourceFile},
+		{"base.network_interfaces", SourceEnv},
+		{"profile.obs_ws_port", SourceFlag},
+		{"monitor.enable", SourceFlag},
+		{"profile.script.plugin_timeout", SourceDefault},
+		{"profile.stream_key_regex", SourceEnv},
+		{"profile.path.obs_launch_path", SourceFlag},
+	}
+	for _, check := range checks {
+		if sources[check.key] != check.source {
+			t.Errorf("%s 来源错误: %s, 期望 %s", check.key, sources[check.key], check.source)
+		}
+	}
+
+	if profile.OBSWsIp != "127.0.0.1" || profile.OBSWsPort != 4457 || !cfg.MonitorSettings.Enable {
+		t.Errorf("配置合并结果错误: %+v %+v", profile, cfg.MonitorSettings)
+	}
+	if len(cfg.BaseSettings.NetworkInterfaces) != 2 || len(DefaultConfig.BaseSettings.NetworkInterfaces) != 0 {
+		t.Errorf("网卡列表错误或修改了默认配置: %v", cfg.BaseSettings.NetworkInterfaces)
+	}
+}
+
+func TestLookupFlag(t *testing.T) {
+	args := []string{"--config", "a.toml", "--print-config", "--base.capture_backend=replay"}
+	if value, ok := LookupFlag(args, ConfigFlag); !ok || value != "a.toml" {
+		t.Errorf("--config 解析错误: %s %v", value, ok)
+	}
+	if _, ok := LookupFlag(args, PrintConfigFlag); !ok {
+		t.Error("--print-config 未识别")
+	}
+	if _, ok := LookupFlag(args, "replay"); ok {
+		t.Error("参数值不应被识别为参数")
+	}
+}
+
+func TestLoadLayersInvalidEnv(t *testing.T) {
+	lookup := func(key string) (string, bool) {
+		return "abc", key == "TIKTOK_TOOL_PROFILE_SCRIPT_PLUGIN_TIMEOUT"
+	}
+	if _, _, err := loadLayers("", lookup, nil); err == nil {
+		t.Error("无效的环境变量未返回错误")
+	}
+}
+
+func TestMigrateFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), CfgFileName)
+	content := "[base]\n" +
+		"server_regex = '(rtmp://push-rtmp-[a-zA-Z0-9\\-]+\\.douyincdn\\.com/thirdgame)'\n" +
+		"stream_key_regex = '(stream-custom-\\d+)'\n" +
+		"obs_ws_port = 4455\n" +
+		"[path]\nobs_launch_path = 'C:/obs/obs64.exe'\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := migrateFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := os.ReadFile(path + ".v0.bak")
+	if err != nil || string(backup) != content {
+		t.Fatalf("未备份原配置文件: %v", err)
+	}
+
+	cfg, _, err := loadLayers(path, func(string) (string, bool) { return "", false }, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != CurrentVersion {
+		t.Errorf("版本未升级: %d", cfg.Version)
+	}
+	profile := cfg.Profile()
+	if len(cfg.Profiles) != 1 || profile.Name != DefaultProfileName {
+		t.Fatalf("未迁移到默认配置方案: %v", cfg.ProfileNames())
+	}
+	if profile.ServerRegex != DefaultProfile.ServerRegex {
+		t.Errorf("过时的默认正则未替换: %s", profile.ServerRegex)
+	}
+	if profile.StreamKeyRegex != `(stream-custom-\d+)` {
+		t.Errorf("自定义正则被修改: %s", profile.StreamKeyRegex)
+	}
+	if profile.OBSWsPort != 4455 || profile.PathSettings.OBSLaunchPath != "C:/obs/obs64.exe" {
+		t.Errorf("配置未移动到配置方案: %+v %+v", profile, profile.PathSettings)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	cfg := DefaultConfig.Clone()
+	if list := cfg.Validate(nil); len(list) != 0 {
+		t.Fatalf("默认配置不应有问题: %v", list)
+	}
+
+	cfg.BaseSettings.NetworkInterfaces = []string{"WLAN"}
+	profile := cfg.Profile()
+	profile.ServerRegex = "(rtmp://["
+	profile.StreamKeyRegex = "stream-.*"
+	profile.OBSWsPort = 70000
+	profile.PathSettings.OBSLaunchPath = filepath.Join(t.TempDir(), "obs64.exe")
+
+	expected := map[string]ProblemLevel{
+		"base.network_interfaces":      ProblemWarning,
+		"profile.server_regex":         ProblemError,
+		"profile.stream_key_regex":     ProblemWarning,
+		"profile.obs_ws_port":          ProblemError,
+		"profile.obs_ws_ip":            ProblemWarning,
+		"profile.path.obs_launch_path": ProblemWarning,
+	}
+	list := cfg.Validate([]string{"以太网"})
+	for _, problem := range list {
+		level, ok := expected[problem.Field]
+		if !ok || level != problem.Level {
+			t.Errorf("意外的问题: %s", problem)
+		}
+		delete(expected, problem.Field)
+	}
+	if len(expected) != 0 {
+		t.Errorf("未检查出的问题: %v", expected)
+	}
+	if !HasError(list) {
+		t.Error("应存在错误级别的问题")
+	}
+
+	// 端口为0表示未设置, 设置了IP时提示
+	cfg = DefaultConfig.Clone()
+	cfg.Profile().OBSWsIp = "127.0.0.1"
+	if list = cfg.Validate(nil); len(list) != 1 || list[0].Field != "profile.obs_ws_port" || list[0].Level != ProblemWarning {
+		t.Errorf("未检查出端口为0: %v", list)
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	secretKeyPath = filepath.Join(t.TempDir(), "secret.key")
+	machineKey = nil
+
+	cfg := DefaultConfig.Clone()
+	cfg.Profile().OBSWsPassword = "obs-password"
+	if err := encryptSecrets(cfg); err != nil {
+		t.Fatal(err)
+	}
+	ciphertext := cfg.Profile().OBSWsPassword
+	if !strings.HasPrefix(ciphertext, secretMachinePrefix) {
+		t.Fatalf("密码未加密: %s", ciphertext)
+	}
+	if list := decryptSecrets(cfg); len(list) != 0 || cfg.Profile().OBSWsPassword != "obs-password" {
+		t.Fatalf("解密失败: %v", list)
+	}
+
+	// 旧版本保存的明文原样读取
+	if plaintext, err := DecryptSecret("plain"); err != nil || plaintext != "plain" {
+		t.Errorf("明文读取失败: %s %v", plaintext, err)
+	}
+
+	// 使用主密码加密的配置项需要相同的主密码解密
+	t.Setenv(MasterPasswordEnv, "master")
+	encrypted, err := EncryptSecret("obs-password")
+	if err != nil || !strings.HasPrefix(encrypted, secretPasswordPrefix) {
+		t.Fatalf("主密码加密失败: %s %v", encrypted, err)
+	}
+	t.Setenv(MasterPasswordEnv, "wrong")
+	if _, err = DecryptSecret(encrypted); !errors.Is(err, ErrMasterPassword) {
+		t.Errorf("主密码错误时应解密失败: %v", err)
+	}
+
+	// 导出时不包含敏感配置项
+	var buf bytes.Buffer
+	if err = ExportSettings(&buf, cfg); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "obs-password") || strings.Contains(buf.String(), "enc:") {
+		t.Errorf("导出的配置包含密码: %s", buf.String())
+	}
+}
+
+func TestBundle(t *testing.T) {
+	appData, obsDir := t.TempDir(), t.TempDir()
+	t.Setenv("APPDATA", appData)
+	t.Setenv(MasterPasswordEnv, "")
+	RulesPath = filepath.Join(t.TempDir(), "rules")
+	if err := os.MkdirAll(RulesPath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(RulesPath, "douyin.toml"), []byte("rule"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultConfig.Clone()
+	profile := cfg.Profile()
+	profile.OBSWsPassword = "obs-password"
+	profile.PathSettings.OBSLaunchPath = filepath.Join(obsDir, "obs64.exe")
+	profile.PathSettings.OBSConfigPath = filepath.Join(appData, "obs-studio", "service.json")
+	profile.PathSettings.PluginScriptPath = filepath.Join(obsDir, "scripts", "auto.exe")
+
+	var buf bytes.Buffer
+	if err := ExportBundle(&buf, cfg, false); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "obs-password") {
+		t.Error("配置包中包含密码")
+	}
+
+	// 在另一台电脑上导入, APPDATA 和 OBS 安装目录不同
+	newAppData, newOBSDir := t.TempDir(), t.TempDir()
+	t.Setenv("APPDATA", newAppData)
+	bundle, err := ReadBundle(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(bundle.Rules["douyin.toml"]) != "rule" {
+		t.Errorf("规则包未导出: %v", bundle.Rules)
+	}
+
+	paths := bundle.Config.Profile().PathSettings
+	if paths.OBSConfigPath != filepath.Join(newAppData, "obs-studio", "service.json") {
+		t.Errorf("APPDATA 路径未展开: %s", paths.OBSConfigPath)
+	}
+	if paths.PluginScriptPath != obsDirPlaceholder+"/scripts/auto.exe" {
+		t.Errorf("OBS目录路径应在重新检测OBS启动路径后展开: %s", paths.PluginScriptPath)
+	}
+
+	// 重新检测到OBS启动路径后展开OBS目录
+	paths.OBSLaunchPath = filepath.Join(newOBSDir, "obs64.exe")
+	ExpandPaths(bundle.Config)
+	if paths.PluginScriptPath != filepath.Join(newOBSDir, "scripts", "auto.exe") {
+		t.Errorf("OBS目录路径未展开: %s", paths.PluginScriptPath)
+	}
+
+	changes := Diff(cfg, bundle.Config)
+	keys := make([]string, 0, len(changes))
+	for _, change := range changes {
+		keys = append(keys, change.Key)
+	}
+	if !slices.Contains(keys, "profiles."+DefaultProfileName+".path.obs_config_path") ||
+		!slices.Contains(keys, "profiles."+DefaultProfileName+".obs_ws_password") {
+		t.Errorf("配置差异不正确: %v", keys)
+	}
+}
+
+func TestBackups(t *testing.T) {
+	dir := CfgFilePath
+	setConfigDir(filepath.Join(t.TempDir(), "config"))
+	t.Cleanup(func() {
+		setConfigDir(dir)
+		currentConfig, recoverProblems = nil, nil
+	})
+
+	for i := 1; i <= BackupCount+2; i++ {
+		cfg := DefaultConfig.Clone()
+		cfg.MonitorSettings.MinBitrateKbps = int32(i)
+		if err := SaveSettings(cfg); err != nil {
+			t.Fatal(err)
+		}
+	}
+	backups := ListBackups()
+	if len(backups) != BackupCount {
+		t.Fatalf("历史配置数量错误: %d", len(backups))
+	}
+
+	// 配置文件损坏时启动使用最近一个历史配置
+	if err := os.WriteFile(configPath, []byte("[monitor\nbroken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if GetConfig().MonitorSettings.MinBitrateKbps != BackupCount+1 || len(recoverProblems) != 1 {
+		t.Errorf("未恢复最近的历史配置: %d %v", GetConfig().MonitorSettings.MinBitrateKbps, recoverProblems)
+	}
+	if _, err := os.Stat(configPath + ".corrupt"); err != nil {
+		t.Errorf("损坏的配置文件未保留: %v", err)
+	}
+
+	// 恢复更早的历史配置
+	if err := RestoreBackup(ListBackups()[BackupCount-1]); err != nil {
+		t.Fatal(err)
+	}
+	if GetConfig().MonitorSettings.MinBitrateKbps != 2 {
+		t.Errorf("恢复历史配置失败: %d", GetConfig().MonitorSettings.MinBitrateKbps)
+	}
+}
+
+func TestSetValue(t *testing.T) {
+	dir := CfgFilePath
+	setConfigDir(filepath.Join(t.TempDir(), "config"))
+	t.Cleanup(func() {
+		setConfigDir(dir)
+		currentConfig = nil
+	})
+
+	old, err := SetValue("monitor.min_bitrate_kbps", "800")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old != fmt.Sprint(DefaultConfig.MonitorSettings.MinBitrateKbps) || GetConfig().MonitorSettings.MinBitrateKbps != 800 {
+		t.Errorf("修改配置项失败: %s %d", old, GetConfig().MonitorSettings.MinBitrateKbps)
+	}
+
+	// 旧键名对应当前方案中的配置项
+	if _, err = SetValue("base.obs_ws_ip", "127.0.0.1"); err != nil {
+		t.Fatal(err)
+	}
+	if field, ok := Lookup("profile.obs_ws_ip"); !ok || field.Value != "127.0.0.1" {
+		t.Errorf("旧键名修改失败: %+v", field)
+	}
+
+	for key, value := range map[string]string{
+		"monitor.unknown":          "1",
+		"monitor.min_bitrate_kbps": "abc",
+		"monitor.max_rtt_ms":       "-1",
+	} {
+		if _, err := SetValue(key, value); err == nil {
+			t.Errorf("%s=%s 应当失败", key, value)
+		}
+	}
+	if GetConfig().MonitorSettings.MaxRTTMs < 0 {
+		t.Errorf("检查未通过的值不应保存")
+	}
+}
+
+func TestKeepUndecryptableSecrets(t *testing.T) {
+	dir, keyPath := CfgFilePath, secretKeyPath
+	setConfigDir(filepath.Join(t.TempDir(), "config"))
+	t.Cleanup(func() {
+		setConfigDir(dir)
+		secretKeyPath, machineKey = keyPath, nil
+		currentConfig, keptSecrets = nil, nil
+	})
+
+	// 使用其他电脑的密钥加密, 本机没有对应的密钥文件
+	secretKeyPath, machineKey = filepath.Join(t.TempDir(), "other.key"), nil
+	ciphertext, err := EncryptSecret("obs-password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	secretKeyPath, machineKey = filepath.Join(CfgFilePath, "secret.key"), nil
+
+	content := fmt.Sprintf("version = %d\n[base]\nactive_profile = \"A\"\n\n[[profiles]]\nname = \"A\"\nobs_ws_password = %q\n\n[[profiles]]\nname = \"B\"\n",
+		CurrentVersion, ciphertext)
+	if err = os.MkdirAll(CfgFilePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err = LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if GetConfig().Profile().OBSWsPassword != "" {
+		t.Fatalf("无法解密的配置项应为空")
+	}
+
+	// 修改后的配置同样提示无法解密, 保存和切换方案后密文不变
+	cfg := GetConfig().Clone()
+	cfg.MonitorSettings.MinBitrateKbps = 800
+	if !slices.ContainsFunc(cfg.Validate(nil), func(p Problem) bool { return p.Field == "profile.obs_ws_password" }) {
+		t.Errorf("修改后的配置未提示无法解密")
+	}
+	if err = SaveSettings(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if err = ReloadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if err = SwitchProfile("B"); err != nil {
+		t.Fatal(err)
+	}
+	saved, err := os.ReadFile(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(saved), ciphertext) {
+		t.Errorf("保存后密文丢失: %s", saved)
+	}
+}
+
+func TestKeepSecrets(t *testing.T) {
+	newConfig := func(active string, passwords map[string]string) *Config {
+		cfg := DefaultConfig.Clone()
+		cfg.Profiles = nil
+		for _, name := range []string{"A", "B"} {
+			profile := DefaultProfile.Clone()
+			profile.Name, profile.OBSWsPassword = name, passwords[name]
+			cfg.Profiles = append(cfg.Profiles, profile)
+		}
+		cfg.BaseSettings.ActiveProfile = active
+		return cfg
+	}
+
+	// 配置包的当前方案为 A, 本机的当前方案为 B
+	current := newConfig("B", map[string]string{"A": "password-a", "B": "password-b"})
+	settings := newConfig("A", nil)
+	keepSecrets(settings, current)
+	if a, b := settings.FindProfile("A").OBSWsPassword, settings.FindProfile("B").OBSWsPassword; a != "password-a" || b != "password-b" {
+		t.Errorf("敏感配置项未按方案对应: A=%s B=%s", a, b)
+	}
+}
+
+func TestConcurrentReload(t *testing.T) {
+	dir := CfgFilePath
+	setConfigDir(filepath.Join(t.TempDir(), "config"))
+	t.Cleanup(func() {
+		setConfigDir(dir)
+		currentConfig = nil
+	})
+
+	// 配置文件监听协程重新加载配置时, 其他协程同时读取配置(使用 -race 检查)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			_ = ReloadConfig()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		_ = GetConfig().Profile().Name
+		_ = Effective()
+	}
+	<-done
+}
+
+func TestSaveKeepsOverridesOut(t *testing.T) {
+	dir := CfgFilePath
+	setConfigDir(filepath.Join(t.TempDir(), "config"))
+	t.Cleanup(func() {
+		setConfigDir(dir)
+		currentConfig, configSources = nil, nil
+	})
+
+	content := fmt.Sprintf("version = %d\n[base]\nactive_profile = \"A\"\n[monitor]\nmax_rtt_ms = 100\n\n"+
+		"[[profiles]]\nname = \"A\"\nobs_ws_ip = \"127.0.0.1\"\n\n[[profiles]]\nname = \"B\"\nobs_ws_ip = \"192.168.1.2\"\n", CurrentVersion)
+	if err := os.MkdirAll(CfgFilePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv(envName("monitor.max_rtt_ms"), "999")
+	t.Setenv(envName("profile.obs_ws_ip"), "10.0.0.1")
+	if err := LoadConfig(); err != nil {
+		t.Fatal(err)
+	}
+	if GetConfig().MonitorSettings.MaxRTTMs != 999 || GetConfig().Profile().OBSWsIp != "10.0.0.1" {
+		t.Fatalf("环境变量未生效")
+	}
+
+	// 修改其他配置项并切换方案后保存, 环境变量覆盖的值不写入配置文件
+	cfg := GetConfig().Clone()
+	cfg.MonitorSettings.MinBitrateKbps = 800
+	cfg.BaseSettings.ActiveProfile = "B"
+	if err := SaveSettings(cfg); err != nil {
+		t.Fatal(err)
+	}
+	file, err := loadFileLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file.MonitorSettings.MaxRTTMs != 100 || file.MonitorSettings.MinBitrateKbps != 800 {
+		t.Errorf("覆盖的值被写入配置文件: %+v", file.MonitorSettings)
+	}
+	if a, b := file.FindProfile("A").OBSWsIp, file.FindProfile("B").OBSWsIp; a != "127.0.0.1" || b != "192.168.1.2" {
+		t.Errorf("方案中覆盖的值被写入配置文件: A=%s B=%s", a, b)
+	}
+
+	// 修改过的覆盖项正常保存
+	cfg = GetConfig().Clone()
+	cfg.MonitorSettings.MaxRTTMs = 500
+	if err = SaveSettings(cfg); err != nil {
+		t.Fatal(err)
+	}
+	if file, err = loadFileLayer(); err != nil || file.MonitorSettings.MaxRTTMs != 500 {
+		t.Errorf("修改的配置项未保存: %v", err)
+	}
+}
+
+func TestLoadConfigReadOnly(t *testing.T) {
+	dir := CfgFilePath
+	setConfigDir(filepath.Join(t.TempDir(), "config"))
+	t.Cleanup(func() {
+		setConfigDir(dir)
+		currentConfig = nil
+	})
+	if err := os.MkdirAll(CfgFilePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	// 旧版本的配置文件只在副本上迁移
+	content := "[base]\nobs_ws_port = 4455\n"
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfigReadOnly(); err != nil {
+		t.Fatal(err)
+	}
+	if GetConfig().Profile().OBSWsPort != 4455 {
+		t.Errorf("配置未迁移: %d", GetConfig().Profile().OBSWsPort)
+	}
+	if saved, _ := os.ReadFile(configPath); string(saved) != content {
+		t.Errorf("配置文件被修改: %s", saved)
+	}
+	if _, err := os.Stat(configPath + ".v0.bak"); !os.IsNotExist(err) {
+		t.Errorf("不应备份配置文件: %v", err)
+	}
+
+	// 损坏的配置文件返回错误, 不恢复历史配置
+	if err := os.WriteFile(configPath, []byte("[monitor\nbroken"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := LoadConfigReadOnly(); err == nil {
+		t.Error("损坏的配置文件应返回错误")
+	}
+	if _, err := os.Stat(configPath + ".corrupt"); !os.IsNotExist(err) {
+		t.Errorf("不应处理损坏的配置文件: %v", err)
+	}
+}
@@ -0,0 +1,416 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// EnvPrefix 环境变量前缀, 例如 TIKTOK_TOOL_PROFILE_OBS_WS_PORT 对应 profile.obs_ws_port
+const EnvPrefix = "TIKTOK_TOOL_"
+
+const (
+	ConfigFlag      = "config"       // --config <path> 指定配置文件
+	PrintConfigFlag = "print-config" // --print-config 输出合并后的生效配置
+)
+
+// Source 配置项的来源
+type Source int
+
+const (
+	SourceDefault Source = iota // 内置默认值
+	SourceFile                  // 配置文件
+	SourceEnv                   // 环境变量
+	SourceFlag                  // 命令行参数
+)
+
+func (s Source) String() string {
+	switch s {
+	case SourceDefault:
+		return "默认值"
+	case SourceFile:
+		return "配置文件"
+	case SourceEnv:
+		return "环境变量"
+	case SourceFlag:
+		return "命令行参数"
+	}
+	return "未知"
+}
+
+// Field 生效的配置项
+type Field struct {
+	Key    string // section.key 形式的键名
+	Value  string
+	Source Source
+}
+
+// Clone 深拷贝配置, 缺失的配置段会被创建为零值
+func (c *Config) Clone() *Config {
+	dst := &Config{}
+	src := reflect.ValueOf(c).Elem()
+	out := reflect.ValueOf(dst).Elem()
+	for i := 0; i < src.NumField(); i++ {
+		field := src.Field(i)
+		if field.Kind() == reflect.Ptr && !field.IsNil() {
+			copied := reflect.New(field.Type().Elem())
+			copied.Elem().Set(field.Elem())
+			out.Field(i).Set(copied)
+			continue
+		}
+		out.Field(i).Set(field)
+	}
+
+	dst.Profiles = make([]*Profile, 0, len(c.Profiles))
+	for _, profile := range c.Profiles {
+		dst.Profiles = append(dst.Profiles, profile.Clone())
+	}
+
+	// 切片单独复制, 避免修改影响原配置
+	_ = eachField(dst, func(_ string, value reflect.Value) error {
+		if value.Kind() == reflect.Slice && !value.IsNil() {
+			copied := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+			reflect.Copy(copied, value)
+			value.Set(copied)
+		}
+		return nil
+	})
+	return dst
+}
+
+// eachField 遍历所有配置项, key 为 section.key 形式的键名
+// 当前配置方案中的配置项以 profile. 为前缀, 例如 profile.path.obs_launch_path
+func eachField(cfg *Config, fn func(key string, value reflect.Value) error) error {
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		if err := walkSection(tomlName(root.Type().Field(i)), root.Field(i), fn); err != nil {
+			return err
+		}
+	}
+	return walkSection(ProfileSection, reflect.ValueOf(cfg.Profile()), fn)
+}
+
+// walkSection 遍历配置段中的配置项, 嵌套的配置段递归遍历, 非配置段的值(如版本号、方案列表)会被跳过
+func walkSection(prefix string, section reflect.Value, fn func(key string, value reflect.Value) error) error {
+	if !isSection(section) {
+		return nil
+	}
+	if section.Kind() == reflect.Ptr {
+		if section.IsNil() {
+			section.Set(reflect.New(section.Type().Elem()))
+		}
+		section = section.Elem()
+	}
+
+	for i := 0; i < section.NumField(); i++ {
+		field := section.Field(i)
+		key := prefix + "." + tomlName(section.Type().Field(i))
+		if isSection(field) {
+			if err := walkSection(key, field, fn); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := fn(key, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tomlName 获取字段在配置文件中的名称
+func tomlName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("toml"), ",")
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// setValue 将字符串解析后写入配置项
+func setValue(value reflect.Value, raw string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(raw), 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(n)
+	case reflect.Slice:
+		if value.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("不支持的类型: %s", value.Type())
+		}
+		items := make([]string, 0)
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("不支持的类型: %s", value.Type())
+	}
+	return nil
+}
+
+// formatValue 格式化配置项的值用于展示
+func formatValue(key string, value reflect.Value) string {
+	if strings.Contains(key, "password") && value.String() != "" {
+		return "******"
+	}
+	if value.Kind() == reflect.Slice {
+		items := make([]string, 0, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			items = append(items, fmt.Sprint(value.Index(i).Interface()))
+		}
+		return strings.Join(items, ", ")
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+// envName 配置项对应的环境变量名
+func envName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// legacyKey 版本2之前的键名, 迁移到配置方案的配置项仍可使用旧键名, 例如 base.obs_ws_ip 对应 profile.obs_ws_ip
+func legacyKey(key string) string {
+	name, ok := strings.CutPrefix(key, ProfileSection+".")
+	if !ok {
+		return ""
+	}
+	if slices.Contains(profileBaseKeys, name) {
+		return "base." + name
+	}
+	if strings.HasPrefix(name, "path.") || strings.HasPrefix(name, "script.") {
+		return name
+	}
+	return ""
+}
+
+// LookupFlag 查找不属于配置项的命令行参数, 支持 --name=value 和 --name value 两种形式
+func LookupFlag(args []string, name string) (string, bool) {
+	for i, arg := range args {
+		key, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !strings.HasPrefix(arg, "--") || key != name {
+			continue
+		}
+		if !hasValue && i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") {
+			value = args[i+1]
+		}
+		return value, true
+	}
+	return "", false
+}
+
+// PrintConfigRequested 是否指定了 --print-config
+func PrintConfigRequested() bool {
+	_, ok := LookupFlag(os.Args[1:], PrintConfigFlag)
+	return ok
+}
+
+// PrintConfig 以配置文件格式输出合并后的生效配置, 敏感配置项会被隐藏
+func PrintConfig(w io.Writer) error {
+	cfg := GetConfig().Clone()
+	eachSecret(cfg, func(_ string, value reflect.Value) {
+		if value.String() != "" {
+			value.SetString("******")
+		}
+	})
+
+	if _, err := fmt.Fprintf(w, "# 配置文件: %s\n# 已合并环境变量(%s*)和命令行参数\n\n", configPath, EnvPrefix); err != nil {
+		return err
+	}
+	return toml.NewEncoder(w).Encode(cfg)
+}
+
+// parseFlags 解析 --section.key=value 形式的命令行参数, 不认识的参数会被忽略
+// 布尔类型的配置项可省略值, 例如 --monitor.enable
+func parseFlags(args []string, isBool func(key string) (known, boolean bool)) map[string]string {
+	flags := make(map[string]string)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if !strings.HasPrefix(arg, "--") {
+			continue
+		}
+		key, value, hasValue := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		known, boolean := isBool(key)
+		if !known {
+			continue
+		}
+		if !hasValue {
+			if boolean {
+				value = "true"
+			} else if i+1 < len(args) {
+				i++
+				value = args[i]
+			}
+		}
+		flags[key] = value
+	}
+	return flags
+}
+
+// loadLayers 依次合并内置默认值、配置文件、环境变量和命令行参数, 并记录每个配置项的来源
+// path 为空时跳过配置文件
+func loadLayers(path string, lookupEnv func(string) (string, bool), args []string) (*Config, map[string]Source, error) {
+	cfg := DefaultConfig.Clone()
+	sources := make(map[string]Source)
+
+	var meta toml.MetaData
+	profileKeys := make(map[string]map[string]bool)
+	if path != "" {
+		var err error
+		if meta, err = toml.DecodeFile(path, cfg); err != nil {
+			return nil, nil, fmt.Errorf("解析配置文件失败: %v", err)
+		}
+		if profileKeys, err = decodeProfiles(path, cfg); err != nil {
+			return nil, nil, fmt.Errorf("解析配置方案失败: %v", err)
+		}
+	}
+
+	kinds := make(map[string]bool)
+	aliases := make(map[string]string)
+	err := eachField(cfg, func(key string, value reflect.Value) error {
+		kinds[key] = value.Kind() == reflect.Bool
+		legacy := legacyKey(key)
+		if legacy != "" {
+			aliases[legacy] = key
+		}
+		if strings.HasPrefix(key, ProfileSection+".") {
+			if profileKeys[cfg.Profile().Name][key] {
+				sources[key] = SourceFile
+			}
+		} else if meta.IsDefined(strings.Split(key, ".")...) {
+			sources[key] = SourceFile
+		}
+
+		// 新键名的环境变量优先于旧键名
+		for _, name := range []string{key, legacy} {
+			if name == "" {
+				continue
+			}
+			if raw, ok := lookupEnv(envName(name)); ok {
+				if err := setValue(value, raw); err != nil {
+					return fmt.Errorf("环境变量 %s 无效: %v", envName(name), err)
+				}
+				sources[key] = SourceEnv
+				break
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	flags := parseFlags(args, func(key string) (bool, bool) {
+		if alias, ok := aliases[key]; ok {
+			key = alias
+		}
+		boolean, ok := kinds[key]
+		return ok, boolean
+	})
+	err = eachField(cfg, func(key string, value reflect.Value) error {
+		raw, ok := flags[key]
+		if !ok {
+			if legacy := legacyKey(key); legacy != "" {
+				raw, ok = flags[legacy]
+			}
+		}
+		if !ok {
+			return nil
+		}
+		if err := setValue(value, raw); err != nil {
+			return fmt.Errorf("命令行参数 --%s 无效: %v", key, err)
+		}
+		sources[key] = SourceFlag
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, sources, nil
+}
+
+// Effective 返回当前生效的全部配置项及其来源
+func Effective() []Field {
+	fields := make([]Field, 0)
+	sources := getSources()
+	_ = eachField(GetConfig(), func(key string, value reflect.Value) error {
+		fields = append(fields, Field{Key: key, Value: formatValue(key, value), Source: sources[key]})
+		return nil
+	})
+	return fields
+}
+
+// Lookup 查找当前生效的配置项, 支持版本2之前的键名
+func Lookup(key string) (Field, bool) {
+	for _, field := range Effective() {
+		if field.Key == key || legacyKey(field.Key) == key {
+			return field, true
+		}
+	}
+	return Field{}, false
+}
+
+// SetValue 修改配置文件中的配置项, 检查通过后保存并重新加载配置, 返回修改前的值
+// 只修改配置文件中的值, 环境变量和命令行参数的覆盖不会写入配置文件
+func SetValue(key, raw string) (string, error) {
+	cfg, err := loadFileLayer()
+	if err != nil {
+		return "", err
+	}
+	// 无法解密的配置项保存时写回原密文
+	decryptSecrets(cfg)
+
+	var old string
+	found := false
+	err = eachField(cfg, func(name string, value reflect.Value) error {
+		if name != key && legacyKey(name) != key {
+			return nil
+		}
+		found = true
+		old = formatValue(name, value)
+		if err := setValue(value, raw); err != nil {
+			return fmt.Errorf("配置项 %s 的值无效: %v", key, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if !found {
+		return "", fmt.Errorf("配置项不存在: %s", key)
+	}
+
+	// 无法解密的配置项会写回原密文, 不影响修改其他配置项
+	kept := make(map[string]bool)
+	for _, problem := range secretProblems(cfg, getKeptSecrets()) {
+		kept[problem.Field] = true
+	}
+	for _, problem := range cfg.Validate(nil) {
+		if problem.Level == ProblemError && !kept[problem.Field] {
+			return "", fmt.Errorf("%s", problem.String())
+		}
+	}
+	if err := saveFile(cfg, cfg.Clone()); err != nil {
+		return "", err
+	}
+	return old, ReloadConfig()
+}
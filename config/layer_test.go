@@ -0,0 +1,47 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetValueMigratesOldFile(t *testing.T) {
+	dir := CfgFilePath
+	setConfigDir(filepath.Join(t.TempDir(), "config"))
+	t.Cleanup(func() {
+		setConfigDir(dir)
+		currentConfig = nil
+	})
+
+	// 版本1的配置文件, 连接、路径和脚本设置还不在配置方案中
+	content := "version = 1\n" +
+		"[base]\nobs_ws_ip = '10.1.1.1'\nobs_ws_port = 4455\n" +
+		"[path]\nobs_launch_path = 'C:/obs/obs64.exe'\n" +
+		"[script]\nplugin_timeout = 99\n"
+	if err := os.MkdirAll(CfgFilePath, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(configPath, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := SetValue("log.level", "info"); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := loadFileLayer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	profile := cfg.Profile()
+	if cfg.Version != CurrentVersion || cfg.LogConfig.Level != "info" {
+		t.Errorf("配置项未修改: version=%d level=%s", cfg.Version, cfg.LogConfig.Level)
+	}
+	if profile.OBSWsIp != "10.1.1.1" || profile.OBSWsPort != 4455 {
+		t.Errorf("OBS连接设置丢失: %+v", profile)
+	}
+	if profile.PathSettings.OBSLaunchPath != "C:/obs/obs64.exe" || profile.ScriptSettings.PluginTimeout != 99 {
+		t.Errorf("路径或脚本设置丢失: %+v %+v", profile.PathSettings, profile.ScriptSettings)
+	}
+}
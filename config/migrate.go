@@ -0,0 +1,170 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// CurrentVersion 当前配置文件结构版本, 修改配置结构时递增并在 migrations 中添加迁移函数
+const CurrentVersion = 2
+
+// migration 将配置从 to-1 版本升级到 to 版本
+// 迁移直接操作配置文件解析出的原始数据, 便于处理字段改名或移动
+type migration struct {
+	to    int
+	name  string
+	apply func(data map[string]any) error
+}
+
+var migrations = []migration{
+	{to: 1, name: "替换过时的默认正则表达式", apply: migrateObsoleteRegex},
+	{to: 2, name: "迁移到配置方案", apply: migrateProfiles},
+}
+
+// 版本2开始移动到配置方案中的基础设置项
+var profileBaseKeys = []string{"server_regex", "stream_key_regex", "obs_ws_ip", "obs_ws_port", "obs_ws_password"}
+
+// 历史版本中使用过的默认正则, 用户未修改过的会被替换为当前默认值
+var obsoleteRegexes = map[string][]string{
+	"server_regex": {
+		`(rtmp://push-rtmp-[a-zA-Z0-9\-]+\.douyincdn\.com/thirdgame)`,
+	},
+	"stream_key_regex": {
+		`(stream-\d+\?expire=\d+&sign=[a-f0-9]+(?:&volcSecret=[a-f0-9]+&volcTime=\d+)?)`,
+	},
+}
+
+// migrateObsoleteRegex 替换过时的默认正则, 保留用户自定义的正则
+func migrateObsoleteRegex(data map[string]any) error {
+	base, ok := data["base"].(map[string]any)
+	if !ok {
+		return nil
+	}
+
+	current := map[string]string{
+		"server_regex":     DefaultProfile.ServerRegex,
+		"stream_key_regex": DefaultProfile.StreamKeyRegex,
+	}
+	for key, obsolete := range obsoleteRegexes {
+		value, ok := base[key].(string)
+		if !ok {
+			continue
+		}
+		for _, old := range obsolete {
+			if strings.TrimSpace(value) == old {
+				base[key] = current[key]
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// migrateProfiles 将原有的路径、脚本、OBS连接及正则设置移动到默认配置方案
+func migrateProfiles(data map[string]any) error {
+	if _, ok := data["profiles"]; ok {
+		return nil
+	}
+
+	profile := map[string]any{"name": DefaultProfileName}
+	if base, ok := data["base"].(map[string]any); ok {
+		for _, key := range profileBaseKeys {
+			if value, ok := base[key]; ok {
+				profile[key] = value
+				delete(base, key)
+			}
+		}
+		base["active_profile"] = DefaultProfileName
+	}
+	for _, section := range []string{"path", "script"} {
+		if value, ok := data[section]; ok {
+			profile[section] = value
+			delete(data, section)
+		}
+	}
+
+	data["profiles"] = []map[string]any{profile}
+	return nil
+}
+
+// fileVersion 获取配置文件的版本, 没有 version 字段的为0
+func fileVersion(data map[string]any) int {
+	version, _ := data["version"].(int64)
+	return int(version)
+}
+
+// migrateData 依次执行迁移, 返回迁移前的版本
+func migrateData(data map[string]any) (int, error) {
+	from := fileVersion(data)
+	for _, m := range migrations {
+		if m.to <= from {
+			continue
+		}
+		if err := m.apply(data); err != nil {
+			return from, fmt.Errorf("配置迁移失败(%s): %v", m.name, err)
+		}
+	}
+	data["version"] = CurrentVersion
+	return from, nil
+}
+
+// migratedCopy 复制配置文件到临时目录并迁移副本, 不修改原文件
+func migratedCopy(path string) (string, func(), error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	dir, err := os.MkdirTemp("", "tiktok_tool_cfg")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { _ = os.RemoveAll(dir) }
+
+	copyPath := filepath.Join(dir, CfgFileName)
+	if err = os.WriteFile(copyPath, content, 0600); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	if err = migrateFile(copyPath); err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return copyPath, cleanup, nil
+}
+
+// migrateFile 配置文件版本低于当前版本时备份原文件并升级
+func migrateFile(path string) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]any)
+	if _, err = toml.Decode(string(content), &data); err != nil {
+		return fmt.Errorf("解析配置文件失败: %v", err)
+	}
+	if fileVersion(data) >= CurrentVersion {
+		return nil
+	}
+
+	from, err := migrateData(data)
+	if err != nil {
+		return err
+	}
+
+	backupPath := fmt.Sprintf("%s.v%d.bak", path, from)
+	if err = os.WriteFile(backupPath, content, 0644); err != nil {
+		return fmt.Errorf("备份配置文件失败: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err = toml.NewEncoder(&buf).Encode(data); err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, buf.Bytes())
+}
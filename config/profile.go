@@ -0,0 +1,168 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	DefaultProfileName = "默认"      // 默认配置方案名称
+	ProfileSection     = "profile" // 当前配置方案在生效配置中的键名前缀
+)
+
+// Profile 配置方案
+// 每个直播账号/直播间可使用独立的路径、OBS连接、正则以及一键开播设置, 日志等共享设置保持全局
+type Profile struct {
+	Name           string          `toml:"name"`                          // 方案名称
+	ServerRegex    string          `toml:"server_regex"`                  // 服务器地址正则表达式
+	StreamKeyRegex string          `toml:"stream_key_regex"`              // 推流码正则表达式
+	OBSWsIp        string          `toml:"obs_ws_ip"`                     // OBS WebSocket IP地址
+	OBSWsPort      int32           `toml:"obs_ws_port"`                   // OBS WebSocket端口
+	OBSWsPassword  string          `toml:"obs_ws_password" secret:"true"` // OBS WebSocket密码, 保存时加密
+	PathSettings   *PathSettings   `toml:"path"`                          // 路径设置
+	ScriptSettings *ScriptSettings `toml:"script"`                        // 一键开播脚本设置
+}
+
+// DefaultProfile 默认配置方案
+var DefaultProfile = Profile{
+	Name:           DefaultProfileName,
+	ServerRegex:    `(rtmp://push-rtmp[^ ]*?\.douyincdn\.com[^\x00\r\n ]*)`,
+	StreamKeyRegex: `(stream-[^\s]*?expire=\d{10}&sign=[^\s]+[^\x00\r\n ]*)`,
+	PathSettings:   &PathSettings{},
+	ScriptSettings: &ScriptSettings{
+		PluginCheckInterval:  1,
+		PluginWaitAfterFound: 5,
+		PluginTimeout:        20,
+	},
+}
+
+// Clone 深拷贝配置方案
+func (p *Profile) Clone() *Profile {
+	dst := *p
+	if p.PathSettings != nil {
+		path := *p.PathSettings
+		dst.PathSettings = &path
+	}
+	if p.ScriptSettings != nil {
+		script := *p.ScriptSettings
+		dst.ScriptSettings = &script
+	}
+	return &dst
+}
+
+// Profile 返回当前使用的配置方案, 找不到 ActiveProfile 对应的方案时使用第一个
+func (c *Config) Profile() *Profile {
+	if c.BaseSettings != nil {
+		if profile := c.FindProfile(c.BaseSettings.ActiveProfile); profile != nil {
+			return profile
+		}
+	}
+	if len(c.Profiles) == 0 {
+		c.Profiles = append(c.Profiles, DefaultProfile.Clone())
+	}
+	return c.Profiles[0]
+}
+
+// FindProfile 按名称查找配置方案
+func (c *Config) FindProfile(name string) *Profile {
+	for _, profile := range c.Profiles {
+		if profile.Name == name {
+			return profile
+		}
+	}
+	return nil
+}
+
+// profilePrefix 配置方案中配置项键名的前缀, 当前方案为 profile, 其他方案为 profiles.<方案名>
+func (c *Config) profilePrefix(profile *Profile) string {
+	if profile == c.Profile() {
+		return ProfileSection
+	}
+	return "profiles." + profile.Name
+}
+
+// ProfileNames 返回全部配置方案的名称
+func (c *Config) ProfileNames() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for _, profile := range c.Profiles {
+		names = append(names, profile.Name)
+	}
+	return names
+}
+
+// SwitchProfile 切换配置方案, 保存后重新加载配置
+func SwitchProfile(name string) error {
+	cfg := GetConfig().Clone()
+	if cfg.FindProfile(name) == nil {
+		return fmt.Errorf("配置方案不存在: %s", name)
+	}
+	cfg.BaseSettings.ActiveProfile = name
+
+	if err := SaveSettings(cfg); err != nil {
+		return err
+	}
+	return ReloadConfig()
+}
+
+// decodeProfiles 以默认方案为基础解析配置文件中的配置方案, 缺失的配置项使用默认值
+// 返回每个方案在配置文件中定义过的键名, 用于记录配置项来源
+func decodeProfiles(path string, cfg *Config) (map[string]map[string]bool, error) {
+	var file struct {
+		Profiles []toml.Primitive `toml:"profiles"`
+	}
+	meta, err := toml.DecodeFile(path, &file)
+	if err != nil {
+		return nil, err
+	}
+
+	defined := make(map[string]map[string]bool)
+	if len(file.Profiles) == 0 {
+		return defined, nil
+	}
+
+	profiles := make([]*Profile, 0, len(file.Profiles))
+	for _, primitive := range file.Profiles {
+		profile := DefaultProfile.Clone()
+		profile.Name = ""
+		if err = meta.PrimitiveDecode(primitive, profile); err != nil {
+			return nil, err
+		}
+		if profile.Name == "" {
+			profile.Name = fmt.Sprintf("方案%d", len(profiles)+1)
+		}
+
+		raw := make(map[string]any)
+		if err = meta.PrimitiveDecode(primitive, &raw); err != nil {
+			return nil, err
+		}
+		keys := make(map[string]bool)
+		flattenKeys(ProfileSection, raw, keys)
+		defined[profile.Name] = keys
+
+		profiles = append(profiles, profile)
+	}
+	cfg.Profiles = profiles
+	return defined, nil
+}
+
+// flattenKeys 将嵌套的配置数据展开为 section.key 形式的键名
+func flattenKeys(prefix string, data map[string]any, keys map[string]bool) {
+	for key, value := range data {
+		if sub, ok := value.(map[string]any); ok {
+			flattenKeys(prefix+"."+key, sub, keys)
+			continue
+		}
+		keys[prefix+"."+key] = true
+	}
+}
+
+// isSection 判断字段是否为嵌套的配置段
+func isSection(value reflect.Value) bool {
+	kind := value.Kind()
+	if kind == reflect.Ptr {
+		kind = value.Type().Elem().Kind()
+	}
+	return kind == reflect.Struct
+}
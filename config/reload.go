@@ -0,0 +1,150 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"tiktok_tool/llog"
+)
+
+// 配置文件变化后等待一段时间再重新加载, 避免编辑器分多次写入时重复加载
+const reloadDebounce = 300 * time.Millisecond
+
+var reloadLog = llog.Named("config")
+
+var (
+	subscribeMutex sync.Mutex
+	subscribers    []func(old, new *Config)
+
+	// 最近一次由程序自身写入的配置内容, 监听到相同内容时不重复加载
+	selfWriteMutex sync.Mutex
+	selfWrite      []byte
+)
+
+// Subscribe 订阅配置变化, 配置重新加载后在调用 ReloadConfig 的协程中回调
+func Subscribe(fn func(old, new *Config)) {
+	subscribeMutex.Lock()
+	defer subscribeMutex.Unlock()
+	subscribers = append(subscribers, fn)
+}
+
+// SetConfig 替换当前配置并通知订阅者
+func SetConfig(cfg *Config) {
+	configMutex.Lock()
+	old := currentConfig
+	currentConfig = cfg
+	configMutex.Unlock()
+	if old == nil {
+		old = DefaultConfig.Clone()
+	}
+
+	subscribeMutex.Lock()
+	fns := append([]func(old, new *Config){}, subscribers...)
+	subscribeMutex.Unlock()
+
+	for _, fn := range fns {
+		fn(old, cfg)
+	}
+}
+
+// ReloadConfig 重新加载配置并通知订阅者, 加载失败时保留当前配置
+func ReloadConfig() error {
+	cfg, sources, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	configMutex.Lock()
+	configSources = sources
+	configMutex.Unlock()
+	SetConfig(cfg)
+	return nil
+}
+
+// markSelfWrite 记录程序自身写入的配置内容
+func markSelfWrite(content []byte) {
+	selfWriteMutex.Lock()
+	defer selfWriteMutex.Unlock()
+	selfWrite = append([]byte{}, content...)
+}
+
+// isSelfWrite 判断配置文件内容是否为程序自身写入
+func isSelfWrite(path string) bool {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+
+	selfWriteMutex.Lock()
+	defer selfWriteMutex.Unlock()
+	return selfWrite != nil && bytes.Equal(content, selfWrite)
+}
+
+// Watch 监听配置文件的外部修改并自动重新加载, 返回停止监听的函数
+func Watch() (func(), error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// 配置文件可能位于 config 目录或数据根目录, 监听目录以兼容编辑器先删除再创建的保存方式
+	dirs := []string{CfgFilePath, filepath.Dir(CfgFilePath)}
+	if customPath {
+		dirs = []string{filepath.Dir(configPath)}
+	}
+	fileName := filepath.Base(settingsPath())
+	for _, dir := range dirs {
+		if err = watcher.Add(dir); err != nil {
+			_ = watcher.Close()
+			return nil, err
+		}
+	}
+
+	go func() {
+		var timer *time.Timer
+		var changed string
+		reload := make(chan struct{}, 1)
+		for {
+			select {
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Base(event.Name) != fileName || event.Op == fsnotify.Chmod {
+					continue
+				}
+				changed = event.Name
+				if timer != nil {
+					timer.Stop()
+				}
+				timer = time.AfterFunc(reloadDebounce, func() {
+					select {
+					case reload <- struct{}{}:
+					default:
+					}
+				})
+			case <-reload:
+				if isSelfWrite(changed) {
+					continue
+				}
+				if err := ReloadConfig(); err != nil {
+					reloadLog.Warn("配置文件已修改, 但重新加载失败", llog.Err(err))
+					continue
+				}
+				reloadLog.Info("检测到配置文件修改, 已重新加载", llog.String("path", changed))
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				reloadLog.Warn("监听配置文件失败", llog.Err(err))
+			}
+		}
+	}()
+
+	return func() { _ = watcher.Close() }, nil
+}
@@ -0,0 +1,322 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	// 使用本机密钥文件加密的密文前缀
+	secretMachinePrefix = "enc:v1:"
+	// 使用主密码加密的密文前缀, 密文中包含随机盐
+	secretPasswordPrefix = "enc:v1p:"
+
+	// MasterPasswordEnv 设置该环境变量后使用主密码加密敏感配置项, 配置文件可在其他电脑上使用同一密码解密
+	MasterPasswordEnv = "TIKTOK_TOOL_MASTER_PASSWORD"
+
+	secretKeySize    = 32
+	secretSaltSize   = 16
+	secretIterations = 200000
+)
+
+var (
+	// 本机密钥文件, 首次加密时随机生成
+	secretKeyPath string
+
+	secretMutex sync.Mutex
+	machineKey  []byte
+
+	// 加载配置时无法解密的密文, 键为 secretID
+	keptSecrets map[string]keptSecret
+)
+
+// keptSecret 无法解密的敏感配置项
+// 密钥文件丢失、配置文件来自其他电脑或未设置主密码时无法解密, 保存时写回原密文, 避免密文被覆盖
+type keptSecret struct {
+	ciphertext string
+	err        error
+}
+
+// ErrMasterPassword 未设置主密码或主密码错误
+var ErrMasterPassword = errors.New("未设置主密码或主密码错误")
+
+// isSecret 判断字段是否为敏感配置项, 敏感配置项使用 secret:"true" 标记
+func isSecret(field reflect.StructField) bool {
+	return field.Tag.Get("secret") == "true" && field.Type.Kind() == reflect.String
+}
+
+// eachSecret 遍历配置中的全部敏感配置项, 包括所有配置方案
+func eachSecret(cfg *Config, fn func(key string, value reflect.Value)) {
+	root := reflect.ValueOf(cfg).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		if root.Type().Field(i).Name != "Profiles" {
+			walkSecrets(tomlName(root.Type().Field(i)), root.Field(i), fn)
+		}
+	}
+	for _, profile := range cfg.Profiles {
+		walkSecrets(cfg.profilePrefix(profile), reflect.ValueOf(profile), fn)
+	}
+}
+
+// walkSecrets 递归遍历配置段中的敏感配置项, key 为 section.key 形式的键名
+func walkSecrets(prefix string, value reflect.Value, fn func(key string, value reflect.Value)) {
+	switch value.Kind() {
+	case reflect.Ptr:
+		if !value.IsNil() {
+			walkSecrets(prefix, value.Elem(), fn)
+		}
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			key := prefix + "." + tomlName(field)
+			if isSecret(field) {
+				fn(key, value.Field(i))
+				continue
+			}
+			walkSecrets(key, value.Field(i), fn)
+		}
+	}
+}
+
+// getMachineKey 读取本机密钥, 不存在时生成
+func getMachineKey(create bool) ([]byte, error) {
+	secretMutex.Lock()
+	defer secretMutex.Unlock()
+
+	if machineKey != nil {
+		return machineKey, nil
+	}
+
+	key, err := os.ReadFile(secretKeyPath)
+	if err == nil && len(key) == secretKeySize {
+		machineKey = key
+		return key, nil
+	}
+	if !create {
+		return nil, fmt.Errorf("读取密钥文件失败: %s", secretKeyPath)
+	}
+
+	key = make([]byte, secretKeySize)
+	if _, err = rand.Read(key); err != nil {
+		return nil, err
+	}
+	if err = os.MkdirAll(filepath.Dir(secretKeyPath), 0755); err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(secretKeyPath, key, 0600); err != nil {
+		return nil, fmt.Errorf("写入密钥文件失败: %v", err)
+	}
+	machineKey = key
+	return key, nil
+}
+
+// passwordKey 由主密码和盐派生密钥
+func passwordKey(password string, salt []byte) ([]byte, error) {
+	return pbkdf2.Key(sha256.New, password, salt, secretIterations, secretKeySize)
+}
+
+// seal 使用 AES-GCM 加密
+func seal(key, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// open 使用 AES-GCM 解密
+func open(key, data []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("密文长度错误")
+	}
+	return gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+}
+
+// EncryptSecret 加密敏感配置项, 设置了主密码时使用主密码, 否则使用本机密钥
+func EncryptSecret(plaintext string) (string, error) {
+	if plaintext == "" || IsEncrypted(plaintext) {
+		return plaintext, nil
+	}
+
+	if password := os.Getenv(MasterPasswordEnv); password != "" {
+		salt := make([]byte, secretSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			return "", err
+		}
+		key, err := passwordKey(password, salt)
+		if err != nil {
+			return "", err
+		}
+		data, err := seal(key, []byte(plaintext))
+		if err != nil {
+			return "", err
+		}
+		return secretPasswordPrefix + base64.StdEncoding.EncodeToString(append(salt, data...)), nil
+	}
+
+	key, err := getMachineKey(true)
+	if err != nil {
+		return "", err
+	}
+	data, err := seal(key, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return secretMachinePrefix + base64.StdEncoding.EncodeToString(data), nil
+}
+
+// DecryptSecret 解密敏感配置项, 未加密的明文原样返回
+func DecryptSecret(value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, secretPasswordPrefix):
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretPasswordPrefix))
+		if err != nil || len(data) < secretSaltSize {
+			return "", fmt.Errorf("密文格式错误")
+		}
+		password := os.Getenv(MasterPasswordEnv)
+		if password == "" {
+			return "", ErrMasterPassword
+		}
+		key, err := passwordKey(password, data[:secretSaltSize])
+		if err != nil {
+			return "", err
+		}
+		plaintext, err := open(key, data[secretSaltSize:])
+		if err != nil {
+			return "", ErrMasterPassword
+		}
+		return string(plaintext), nil
+
+	case strings.HasPrefix(value, secretMachinePrefix):
+		data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, secretMachinePrefix))
+		if err != nil {
+			return "", fmt.Errorf("密文格式错误")
+		}
+		key, err := getMachineKey(false)
+		if err != nil {
+			return "", err
+		}
+		plaintext, err := open(key, data)
+		if err != nil {
+			return "", fmt.Errorf("密钥不匹配, 配置文件可能来自其他电脑")
+		}
+		return string(plaintext), nil
+	}
+	return value, nil
+}
+
+// IsEncrypted 判断配置项是否已加密
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, secretMachinePrefix) || strings.HasPrefix(value, secretPasswordPrefix)
+}
+
+// secretID 敏感配置项与当前方案无关的键名, 当前方案的 profile.<键名> 转换为 profiles.<方案名>.<键名>
+func secretID(cfg *Config, key string) string {
+	if name, ok := strings.CutPrefix(key, ProfileSection+"."); ok {
+		return "profiles." + cfg.Profile().Name + "." + name
+	}
+	return key
+}
+
+// decryptSecrets 解密配置中的敏感配置项, 返回无法解密的密文
+// 无法解密的配置项在内存中为空
+func decryptSecrets(cfg *Config) map[string]keptSecret {
+	kept := make(map[string]keptSecret)
+	eachSecret(cfg, func(key string, value reflect.Value) {
+		plaintext, err := DecryptSecret(value.String())
+		if err != nil {
+			kept[secretID(cfg, key)] = keptSecret{ciphertext: value.String(), err: err}
+			plaintext = ""
+		}
+		value.SetString(plaintext)
+	})
+	return kept
+}
+
+// secretProblems 无法解密且未重新填写的敏感配置项
+func secretProblems(cfg *Config, kept map[string]keptSecret) []Problem {
+	var list problems
+	eachSecret(cfg, func(key string, value reflect.Value) {
+		if secret, ok := kept[secretID(cfg, key)]; ok && value.String() == "" {
+			list.add(ProblemError, key, "无法解密, 保存时保留原密文, 请重新填写: %v", secret.err)
+		}
+	})
+	return list
+}
+
+// encryptSecrets 加密配置中的敏感配置项, 无法解密且未重新填写的配置项写回原密文
+func encryptSecrets(cfg *Config) error {
+	var firstErr error
+	kept := getKeptSecrets()
+	eachSecret(cfg, func(key string, value reflect.Value) {
+		if secret, ok := kept[secretID(cfg, key)]; ok && value.String() == "" {
+			value.SetString(secret.ciphertext)
+			return
+		}
+		ciphertext, err := EncryptSecret(value.String())
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("加密 %s 失败: %v", key, err)
+			}
+			return
+		}
+		value.SetString(ciphertext)
+	})
+	return firstErr
+}
+
+// secretValues 配置中已解密的敏感配置项, 用于日志脱敏
+func secretValues(cfg *Config) []string {
+	var values []string
+	eachSecret(cfg, func(_ string, value reflect.Value) {
+		if value.String() != "" {
+			values = append(values, value.String())
+		}
+	})
+	return values
+}
+
+// stripSecrets 清空配置中的敏感配置项
+func stripSecrets(cfg *Config) {
+	eachSecret(cfg, func(_ string, value reflect.Value) {
+		value.SetString("")
+	})
+}
+
+// ExportSettings 导出配置, 敏感配置项(如OBS WebSocket密码)不会被导出
+func ExportSettings(w io.Writer, settings *Config) error {
+	exported := settings.Clone()
+	exported.Version = CurrentVersion
+	stripSecrets(exported)
+	return toml.NewEncoder(w).Encode(exported)
+}
@@ -0,0 +1,200 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ProblemLevel 配置问题的严重程度
+type ProblemLevel int
+
+const (
+	ProblemWarning ProblemLevel = iota // 警告, 不影响保存, 相关功能可能无法使用
+	ProblemError                       // 错误, 必须修改后才能保存
+)
+
+func (l ProblemLevel) String() string {
+	if l == ProblemError {
+		return "错误"
+	}
+	return "警告"
+}
+
+// Problem 配置项的问题
+type Problem struct {
+	Field   string // 配置项键名, 与 Effective 返回的键名一致, 其他方案为 profiles.<方案名>.<键名>
+	Message string
+	Level   ProblemLevel
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("[%s] %s: %s", p.Level, p.Field, p.Message)
+}
+
+// problems 收集配置问题
+type problems []Problem
+
+func (p *problems) add(level ProblemLevel, field, format string, args ...any) {
+	*p = append(*p, Problem{Field: field, Message: fmt.Sprintf(format, args...), Level: level})
+}
+
+// HasError 是否存在必须修改的错误
+func HasError(list []Problem) bool {
+	return slices.ContainsFunc(list, func(p Problem) bool {
+		return p.Level == ProblemError
+	})
+}
+
+// Validate 检查配置中的问题
+// devices 为当前可用的网卡名称, 为nil时跳过网卡检查
+func (c *Config) Validate(devices []string) []Problem {
+	var list problems
+
+	if base := c.BaseSettings; base != nil {
+		if devices != nil {
+			for _, name := range base.NetworkInterfaces {
+				if !slices.Contains(devices, name) {
+					list.add(ProblemWarning, "base.network_interfaces", "网卡 %s 不存在, 请重新选择", name)
+				}
+			}
+		}
+		if base.CaptureBackend == "replay" {
+			if base.ReplayFile == "" {
+				list.add(ProblemError, "base.replay_file", "使用 replay 抓包后端时必须设置回放文件")
+			} else {
+				validateFile(&list, "base.replay_file", base.ReplayFile)
+			}
+		}
+		if base.ActiveProfile != "" && c.FindProfile(base.ActiveProfile) == nil {
+			list.add(ProblemWarning, "base.active_profile", "方案 %s 不存在, 将使用方案 %s", base.ActiveProfile, c.Profile().Name)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, profile := range c.Profiles {
+		prefix := c.profilePrefix(profile)
+		if names[profile.Name] {
+			list.add(ProblemError, prefix+".name", "方案名称重复")
+		}
+		names[profile.Name] = true
+		validateProfile(&list, prefix, profile)
+	}
+
+	if monitor := c.MonitorSettings; monitor != nil {
+		if monitor.MinBitrateKbps < 0 {
+			list.add(ProblemError, "monitor.min_bitrate_kbps", "码率告警阈值不能为负数")
+		}
+		if monitor.MaxRetransPercent < 0 || monitor.MaxRetransPercent > 100 {
+			list.add(ProblemError, "monitor.max_retrans_percent", "重传率告警阈值需在0到100之间")
+		}
+		if monitor.MaxRTTMs < 0 {
+			list.add(ProblemError, "monitor.max_rtt_ms", "RTT告警阈值不能为负数")
+		}
+	}
+
+	if rules := c.RuleSettings; rules != nil && rules.UpdateURL != "" {
+		if u, err := url.Parse(rules.UpdateURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			list.add(ProblemError, "rules.update_url", "规则包更新地址需为 http 或 https 链接")
+		}
+	}
+
+	if log := c.LogConfig; log != nil {
+		if !log.Console && !log.File {
+			list.add(ProblemWarning, "log.file", "日志既不输出到控制台也不输出到文件")
+		}
+		if log.Level != "" && !slices.Contains([]string{"debug", "info", "warn", "error", "fatal"}, strings.ToLower(log.Level)) {
+			list.add(ProblemWarning, "log.level", "未知的日志等级 %s, 将使用 info", log.Level)
+		}
+		if log.RemoteURL != "" {
+			if u, err := url.Parse(log.RemoteURL); err != nil || !slices.Contains([]string{"http", "https", "udp", "syslog"}, u.Scheme) || u.Host == "" {
+				list.add(ProblemError, "log.remote_url", "远程日志地址需为 http(s):// 或 udp:// 链接")
+			}
+		}
+		if log.RemoteLevel != "" && !slices.Contains([]string{"debug", "info", "warn", "error", "fatal"}, strings.ToLower(log.RemoteLevel)) {
+			list.add(ProblemWarning, "log.remote_level", "未知的远程日志等级 %s, 将使用 info", log.RemoteLevel)
+		}
+		if log.Unredacted {
+			list.add(ProblemWarning, "log.unredacted", "已关闭日志脱敏, 日志中包含推流码等敏感信息, 请勿将日志发送给他人")
+		}
+	}
+
+	// 加载时恢复了历史配置
+	if c == GetConfig() {
+		list = append(list, recoverProblems...)
+	}
+	// 无法解密的配置项, 修改后的配置同样需要提示
+	list = append(list, secretProblems(c, getKeptSecrets())...)
+
+	return list
+}
+
+// validateProfile 检查配置方案
+func validateProfile(list *problems, prefix string, profile *Profile) {
+	validateRegex(list, prefix+".server_regex", profile.ServerRegex, "服务器地址")
+	validateRegex(list, prefix+".stream_key_regex", profile.StreamKeyRegex, "推流码")
+
+	// 端口为0表示未设置
+	if profile.OBSWsPort < 0 || profile.OBSWsPort > 65535 {
+		list.add(ProblemError, prefix+".obs_ws_port", "端口需在1到65535之间, 不使用WebSocket时留空")
+	}
+	if profile.OBSWsPort == 0 && profile.OBSWsIp != "" {
+		list.add(ProblemWarning, prefix+".obs_ws_port", "设置了OBS WebSocket IP但未设置端口, 将无法连接WebSocket")
+	}
+	if profile.OBSWsPort != 0 && profile.OBSWsIp == "" {
+		list.add(ProblemWarning, prefix+".obs_ws_ip", "设置了OBS WebSocket端口但未设置IP, 将不会使用WebSocket导入")
+	}
+
+	if path := profile.PathSettings; path != nil {
+		validateFile(list, prefix+".path.obs_launch_path", path.OBSLaunchPath)
+		validateFile(list, prefix+".path.obs_config_path", path.OBSConfigPath)
+		validateFile(list, prefix+".path.live_companion_path", path.LiveCompanionPath)
+		validateFile(list, prefix+".path.plugin_script_path", path.PluginScriptPath)
+	}
+
+	if script := profile.ScriptSettings; script != nil {
+		if script.PluginCheckInterval < 1 {
+			list.add(ProblemError, prefix+".script.plugin_check_interval", "插件检查间隔不可小于1秒")
+		}
+		if script.PluginWaitAfterFound < 0 {
+			list.add(ProblemError, prefix+".script.plugin_wait_after_found", "等待时间不能为负数")
+		}
+		if script.PluginTimeout < 5 || script.PluginTimeout > 300 {
+			list.add(ProblemError, prefix+".script.plugin_timeout", "超时时间需在5到300秒之间")
+		}
+	}
+}
+
+// validateRegex 检查正则表达式能否编译以及是否包含捕获组
+func validateRegex(list *problems, field, expr, name string) {
+	if strings.TrimSpace(expr) == "" {
+		list.add(ProblemError, field, "%s正则不能为空", name)
+		return
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		list.add(ProblemError, field, "%s正则无效: %v", name, err)
+		return
+	}
+	if re.NumSubexp() == 0 {
+		list.add(ProblemWarning, field, "%s正则缺少捕获组, 请使用()包含需要提取的内容", name)
+	}
+}
+
+// validateFile 检查已设置的文件路径是否存在
+func validateFile(list *problems, field, path string) {
+	if path == "" {
+		return
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		list.add(ProblemWarning, field, "文件不存在或无法访问: %s", path)
+		return
+	}
+	if info.IsDir() {
+		list.add(ProblemWarning, field, "路径是目录而不是文件: %s", path)
+	}
+}
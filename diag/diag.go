@@ -0,0 +1,418 @@
+package diag
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"tiktok_tool/appdir"
+	"tiktok_tool/capture"
+	"tiktok_tool/config"
+	"tiktok_tool/lkit"
+	"tiktok_tool/llog"
+	"tiktok_tool/rules"
+)
+
+const (
+	// FilePrefix 诊断包文件名前缀
+	FilePrefix = "tiktok_tool_diag"
+
+	// 诊断包中包含的日志文件总大小上限, 超过时跳过较旧的日志文件
+	maxLogSize = 20 << 20
+	// 诊断包中包含的最近推流会话数量
+	maxSessions = 50
+)
+
+// Options 导出诊断包的选项
+type Options struct {
+	Unredacted bool // 不脱敏, 日志中的推流码等敏感信息会原样导出
+}
+
+// Manifest 诊断包说明, 保存为 manifest.json
+type Manifest struct {
+	CreatedAt  time.Time `json:"created_at"`
+	AppVersion string    `json:"app_version"`
+	Redacted   bool      `json:"redacted"`
+	Files      []string  `json:"files"`
+	Skipped    []string  `json:"skipped,omitempty"` // 未包含的文件及原因
+	Errors     []string  `json:"errors,omitempty"`  // 收集信息时遇到的错误, 不影响导出
+}
+
+// ProcessInfo 相关程序的运行状态
+type ProcessInfo struct {
+	Name    string  `json:"name"`
+	Running bool    `json:"running"`
+	PIDs    []int32 `json:"pids,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// PluginInfo 自动化插件信息, 通过文件哈希区分插件版本
+type PluginInfo struct {
+	Path    string    `json:"path"`
+	Exists  bool      `json:"exists"`
+	Size    int64     `json:"size,omitempty"`
+	ModTime time.Time `json:"mod_time,omitempty"`
+	SHA256  string    `json:"sha256,omitempty"`
+}
+
+// SystemInfo 运行环境信息, 保存为 system.json
+type SystemInfo struct {
+	OS            string             `json:"os"`
+	Arch          string             `json:"arch"`
+	GoVersion     string             `json:"go_version"`
+	AppVersion    string             `json:"app_version"`
+	Admin         bool               `json:"admin"`
+	DataDir       string             `json:"data_dir"`
+	Portable      bool               `json:"portable"`
+	ConfigFile    string             `json:"config_file"`
+	Profile       string             `json:"profile"`
+	Backend       string             `json:"backend"`
+	BackendError  string             `json:"backend_error,omitempty"`
+	Devices       []capture.Device   `json:"devices"`
+	DeviceError   string             `json:"device_error,omitempty"`
+	Processes     []ProcessInfo      `json:"processes"`
+	Plugin        PluginInfo         `json:"plugin"`
+	RuleVersion   int                `json:"rule_version"` // 0 为内置规则
+	RuleError     string             `json:"rule_error,omitempty"`
+	RemoteLog     *llog.RemoteStatus `json:"remote_log,omitempty"`
+	LogUnredacted bool               `json:"log_unredacted"`
+}
+
+// FileName 诊断包默认文件名, 包含导出时间
+func FileName(now time.Time) string {
+	return fmt.Sprintf("%s_%s.zip", FilePrefix, now.Format("20060102_150405"))
+}
+
+// CollectSystem 收集运行环境信息
+func CollectSystem() *SystemInfo {
+	cfg := config.GetConfig()
+	info := &SystemInfo{
+		OS:            runtime.GOOS,
+		Arch:          runtime.GOARCH,
+		GoVersion:     runtime.Version(),
+		AppVersion:    lkit.AppVersion(),
+		Admin:         lkit.IsAdmin,
+		DataDir:       appdir.Root(),
+		Portable:      appdir.IsPortable(),
+		ConfigFile:    config.FilePath(),
+		Profile:       cfg.Profile().Name,
+		LogUnredacted: llog.IsUnredacted(),
+	}
+
+	if backend, err := capture.CurrentBackend(); err != nil {
+		info.BackendError = err.Error()
+	} else {
+		info.Backend = backend.Name()
+		if err = backend.Available(); err != nil {
+			info.BackendError = err.Error()
+		}
+	}
+	if devices, err := capture.ListDevices(); err != nil {
+		info.DeviceError = err.Error()
+	} else {
+		info.Devices = devices
+	}
+
+	info.Processes = []ProcessInfo{
+		processInfo("OBS", "obs64.exe", "obs32.exe"),
+		processInfo("直播伴侣", "直播伴侣.exe"),
+	}
+	info.Plugin = pluginInfo(cfg.Profile().PathSettings.PluginScriptPath)
+
+	if pack, err := rules.Current(); err != nil {
+		info.RuleError = err.Error()
+	} else if pack != nil {
+		info.RuleVersion = pack.Version
+	}
+	if status, ok := llog.GetRemoteStatus(); ok {
+		info.RemoteLog = &status
+	}
+	return info
+}
+
+// processInfo 检查程序是否正在运行
+func processInfo(name string, exeNames ...string) ProcessInfo {
+	info := ProcessInfo{Name: name}
+	pids, err := lkit.IsProcessRunning(exeNames...)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+	for _, pid := range pids {
+		if pid > 0 {
+			info.Running = true
+			info.PIDs = append(info.PIDs, pid)
+		}
+	}
+	return info
+}
+
+// pluginInfo 读取插件文件信息
+func pluginInfo(path string) PluginInfo {
+	info := PluginInfo{Path: path}
+	if path == "" {
+		return info
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return info
+	}
+	defer file.Close()
+
+	stat, err := file.Stat()
+	if err != nil {
+		return info
+	}
+	info.Exists, info.Size, info.ModTime = true, stat.Size(), stat.ModTime()
+
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err == nil {
+		info.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	}
+	return info
+}
+
+// bundleWriter 向诊断包中写入文件并记录到说明中
+type bundleWriter struct {
+	zip      *zip.Writer
+	manifest *Manifest
+}
+
+// add 写入文件, 默认脱敏
+func (b *bundleWriter) add(name string, content []byte) error {
+	if b.manifest.Redacted {
+		content = []byte(llog.Redact(string(content)))
+	}
+	return b.addRaw(name, content)
+}
+
+// addRaw 写入不需要脱敏的文件
+func (b *bundleWriter) addRaw(name string, content []byte) error {
+	writer, err := b.zip.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: b.manifest.CreatedAt})
+	if err != nil {
+		return err
+	}
+	if _, err = writer.Write(content); err != nil {
+		return err
+	}
+	b.manifest.Files = append(b.manifest.Files, name)
+	return nil
+}
+
+// addJSON 以JSON格式写入文件
+func (b *bundleWriter) addJSON(name string, value any) error {
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	return b.add(name, data)
+}
+
+// addFile 写入磁盘上的文件, 文件不存在时跳过
+func (b *bundleWriter) addFile(name, path string) error {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		b.manifest.Skipped = append(b.manifest.Skipped, fmt.Sprintf("%s: 文件不存在", name))
+		return nil
+	}
+	if err != nil {
+		b.manifest.Errors = append(b.manifest.Errors, fmt.Sprintf("读取 %s 失败: %v", path, err))
+		return nil
+	}
+	return b.add(name, data)
+}
+
+// Export 导出诊断包, 默认对日志等内容脱敏
+// 包含 manifest.json、system.json、脱敏后的配置、配置问题、日志文件、内存中的最近日志、崩溃报告和最近的推流会话
+func Export(w io.Writer, options Options) (*Manifest, error) {
+	manifest := &Manifest{
+		CreatedAt:  time.Now(),
+		AppVersion: lkit.AppVersion(),
+		Redacted:   !options.Unredacted,
+	}
+	bundle := &bundleWriter{zip: zip.NewWriter(w), manifest: manifest}
+
+	system := CollectSystem()
+	steps := []func() error{
+		func() error { return bundle.addJSON("system.json", system) },
+		func() error { return addConfig(bundle, system) },
+		func() error { return addLogs(bundle) },
+		func() error { return addCrashReports(bundle) },
+		func() error { return addSessions(bundle) },
+	}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, fmt.Errorf("写入诊断包失败: %v", err)
+		}
+	}
+
+	// 说明文件最后写入, 包含前面写入的文件列表
+	sort.Strings(manifest.Files)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	writer, err := bundle.zip.Create("manifest.json")
+	if err != nil {
+		return nil, fmt.Errorf("写入诊断包失败: %v", err)
+	}
+	if _, err = writer.Write(data); err != nil {
+		return nil, fmt.Errorf("写入诊断包失败: %v", err)
+	}
+	if err = bundle.zip.Close(); err != nil {
+		return nil, fmt.Errorf("写入诊断包失败: %v", err)
+	}
+	return manifest, nil
+}
+
+// addCrashReports 写入保留的全部崩溃报告
+func addCrashReports(bundle *bundleWriter) error {
+	reports := lkit.CrashReports()
+	if len(reports) == 0 {
+		bundle.manifest.Skipped = append(bundle.manifest.Skipped, "crash: 没有崩溃报告")
+		return nil
+	}
+	for _, report := range reports {
+		if err := bundle.addFile("crash/"+report.Name, report.Path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addConfig 写入生效配置(敏感配置项已隐藏)和配置问题
+func addConfig(bundle *bundleWriter, system *SystemInfo) error {
+	var buf bytes.Buffer
+	if err := config.PrintConfig(&buf); err != nil {
+		bundle.manifest.Errors = append(bundle.manifest.Errors, fmt.Sprintf("导出配置失败: %v", err))
+	} else {
+		content := buf.String()
+		if bundle.manifest.Redacted {
+			content = redactConfig(content)
+		}
+		if err = bundle.addRaw("config.toml", []byte(content)); err != nil {
+			return err
+		}
+	}
+
+	names := make([]string, 0, len(system.Devices))
+	for _, device := range system.Devices {
+		names = append(names, device.Description)
+	}
+	var problems strings.Builder
+	for _, problem := range config.GetConfig().Validate(names) {
+		problems.WriteString(problem.String() + "\n")
+	}
+	if problems.Len() == 0 {
+		problems.WriteString("没有发现配置问题\n")
+	}
+	return bundle.add("config_problems.txt", []byte(problems.String()))
+}
+
+// redactConfig 按行脱敏配置, 例如远程日志地址和规则更新地址中的 token=
+// 正则表达式包含 sign= 等文本, 脱敏后无法排查正则问题, 保持原样
+func redactConfig(content string) string {
+	lines := strings.Split(content, "\n")
+	for i, line := range lines {
+		key, _, ok := strings.Cut(line, "=")
+		if ok && strings.HasSuffix(strings.TrimSpace(key), "_regex") {
+			continue
+		}
+		lines[i] = llog.Redact(line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// addLogs 写入日志目录下的日志文件(从新到旧, 不超过大小上限)和内存中的最近日志
+func addLogs(bundle *bundleWriter) error {
+	var recent strings.Builder
+	for _, record := range llog.RecentLogs() {
+		recent.WriteString(record.String() + "\n")
+	}
+	if err := bundle.add("logs/recent.log", []byte(recent.String())); err != nil {
+		return err
+	}
+
+	dir := llog.LogDir()
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		bundle.manifest.Errors = append(bundle.manifest.Errors, fmt.Sprintf("读取日志目录失败: %v", err))
+		return nil
+	}
+
+	type logFile struct {
+		name    string
+		size    int64
+		modTime time.Time
+	}
+	var files []logFile
+	for _, entry := range entries {
+		info, err := entry.Info()
+		if err != nil || entry.IsDir() || !strings.HasSuffix(entry.Name(), ".log") {
+			if err == nil && !entry.IsDir() {
+				// 压缩的历史日志无法脱敏, 不导出
+				bundle.manifest.Skipped = append(bundle.manifest.Skipped, fmt.Sprintf("logs/%s: 不是文本日志文件", entry.Name()))
+			}
+			continue
+		}
+		files = append(files, logFile{name: entry.Name(), size: info.Size(), modTime: info.ModTime()})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].modTime.After(files[j].modTime)
+	})
+
+	var total int64
+	for _, file := range files {
+		name := "logs/" + file.name
+		if total+file.size > maxLogSize {
+			bundle.manifest.Skipped = append(bundle.manifest.Skipped, fmt.Sprintf("%s: 超过日志大小上限", name))
+			continue
+		}
+		total += file.size
+		if err = bundle.addFile(name, filepath.Join(dir, file.name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addSessions 写入最近的推流会话记录
+func addSessions(bundle *bundleWriter) error {
+	file, err := os.Open(filepath.Join(config.CfgFilePath, capture.SessionFileName))
+	if os.IsNotExist(err) {
+		bundle.manifest.Skipped = append(bundle.manifest.Skipped, capture.SessionFileName+": 没有推流会话记录")
+		return nil
+	}
+	if err != nil {
+		bundle.manifest.Errors = append(bundle.manifest.Errors, fmt.Sprintf("读取推流会话记录失败: %v", err))
+		return nil
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > maxSessions {
+			lines = lines[1:]
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return bundle.add(capture.SessionFileName, []byte(strings.Join(lines, "\n")+"\n"))
+}
@@ -0,0 +1,78 @@
+package diag
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+
+	"tiktok_tool/capture"
+	"tiktok_tool/config"
+	"tiktok_tool/llog"
+)
+
+func TestExport(t *testing.T) {
+	capture.SetBackend(capture.NewMemoryBackend())
+	defer capture.SetBackend(nil)
+	if err := llog.Init(&llog.LogSetting{Level: "info"}); err != nil {
+		t.Fatal(err)
+	}
+	llog.Info("找到推流码字符串: stream-694123456789?expire=1700000000&sign=abcdef")
+
+	cfg := config.DefaultConfig.Clone()
+	cfg.LogConfig.RemoteURL = "https://log.example.com/push?token=remote-secret"
+	cfg.RuleSettings.UpdateURL = "https://rules.example.com/rules.json?sign=update-secret"
+	config.SetConfig(cfg)
+	defer config.SetConfig(config.DefaultConfig.Clone())
+
+	var buf bytes.Buffer
+	manifest, err := Export(&buf, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !manifest.Redacted {
+		t.Error("默认应脱敏")
+	}
+
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, file := range reader.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		_ = rc.Close()
+		files[file.Name] = string(data)
+	}
+
+	for _, name := range []string{"manifest.json", "system.json", "config.toml", "config_problems.txt", "logs/recent.log"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("诊断包缺少 %s", name)
+		}
+	}
+	if recent := files["logs/recent.log"]; strings.Contains(recent, "abcdef") || !strings.Contains(recent, "sign=***") {
+		t.Errorf("日志未脱敏: %s", recent)
+	}
+
+	content := files["config.toml"]
+	if strings.Contains(content, "remote-secret") || strings.Contains(content, "update-secret") {
+		t.Errorf("配置中的地址未脱敏: %s", content)
+	}
+	if !strings.Contains(content, `&sign=[^\\s]+`) {
+		t.Errorf("配置中的正则表达式不应脱敏: %s", content)
+	}
+
+	var system SystemInfo
+	if err = json.Unmarshal([]byte(files["system.json"]), &system); err != nil {
+		t.Fatal(err)
+	}
+	if system.Backend != capture.BackendMemory || len(system.Processes) != 2 {
+		t.Errorf("运行环境信息错误: %+v", system)
+	}
+}
@@ -0,0 +1,233 @@
+// Package ipc 本机实例间通信: 已运行的实例监听本地套接字, 再次启动程序时把命令转发给它并返回执行结果
+// 可用于桌面快捷方式、Stream Deck 按键等场景, 例如 tiktok_tool --one-click --profile=B
+package ipc
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"tiktok_tool/appdir"
+	"tiktok_tool/config"
+	"tiktok_tool/lkit"
+	"tiktok_tool/llog"
+)
+
+// 实例命令, 命令行中以 --<命令> 指定
+const (
+	CommandShow         = "show"          // 显示主窗口
+	CommandStartCapture = "start-capture" // 开始抓包
+	CommandStopCapture  = "stop-capture"  // 停止抓包
+	CommandOneClick     = "one-click"     // 一键开播, 不再弹出确认对话框
+)
+
+// ProfileFlag --profile <名称> 执行命令前切换配置方案
+const ProfileFlag = "profile"
+
+// Commands 支持的全部命令
+var Commands = []string{CommandShow, CommandStartCapture, CommandStopCapture, CommandOneClick}
+
+const (
+	socketName = ".tiktok.sock"
+	// 套接字所在目录, 仅当前用户可访问
+	socketDirName = "ipc"
+	// 套接字路径的长度上限, 超过时改用临时目录
+	maxSocketPath = 100
+
+	dialTimeout = 3 * time.Second
+	// RequestTimeout 等待命令执行结果的最长时间
+	RequestTimeout = 30 * time.Second
+)
+
+// logger 实例通信日志
+var logger = llog.Named("ipc")
+
+// socketPath 为空时使用 SocketPath 的默认路径, 测试时修改
+var socketPath string
+
+// Request 转发给已运行实例的命令
+type Request struct {
+	Command string `json:"command"`
+	Profile string `json:"profile,omitempty"`
+}
+
+// Response 命令执行结果
+type Response struct {
+	OK      bool   `json:"ok"`
+	Message string `json:"message,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Handler 执行命令并返回结果
+type Handler func(request Request) Response
+
+// Succeeded 执行成功的结果
+func Succeeded(message string) Response {
+	return Response{OK: true, Message: message}
+}
+
+// Failed 执行失败的结果
+func Failed(err error) Response {
+	return Response{Error: err.Error()}
+}
+
+// ParseArgs 解析命令行中的实例命令, 没有指定命令时返回 nil
+// 只指定 --profile 时切换配置方案并显示主窗口
+func ParseArgs(args []string) *Request {
+	request := &Request{}
+	for _, command := range Commands {
+		if _, ok := config.LookupFlag(args, command); ok {
+			request.Command = command
+			break
+		}
+	}
+	if profile, ok := config.LookupFlag(args, ProfileFlag); ok {
+		request.Profile = profile
+	}
+	if request.Command == "" {
+		if request.Profile == "" {
+			return nil
+		}
+		request.Command = CommandShow
+	}
+	return request
+}
+
+// SocketPath 本地套接字路径, 放在数据根目录下的 ipc 目录中, 从不同目录启动时也能连接到已运行的实例
+// Windows 10 1803 及以上版本同样支持本地套接字
+func SocketPath() string {
+	if socketPath != "" {
+		return socketPath
+	}
+	path := appdir.Join(socketDirName, socketName)
+	if len(path) <= maxSocketPath {
+		return path
+	}
+	// 套接字路径长度有限制, 数据目录较深时(如便携模式)使用临时目录下的子目录, 按用户和数据目录区分
+	sum := sha256.Sum256([]byte(appdir.Root()))
+	return filepath.Join(os.TempDir(), fmt.Sprintf("tiktok_tool_%d_%s", os.Getuid(), hex.EncodeToString(sum[:6])), socketName)
+}
+
+// prepareSocketDir 创建仅当前用户可访问的套接字目录
+// 临时目录为所有用户共享, 已存在的目录需为当前用户所有, 避免其他用户预先创建目录后接管套接字
+func prepareSocketDir(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+	info, err := os.Lstat(dir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s 不是目录", dir)
+	}
+	if err = checkOwner(info); err != nil {
+		return err
+	}
+	if info.Mode().Perm() != 0700 {
+		return os.Chmod(dir, 0700)
+	}
+	return nil
+}
+
+// Serve 监听本地套接字并在后台处理命令, 需要在获取单实例锁之后调用
+// 套接字在仅当前用户可访问的目录中创建, 并拒绝其他用户的连接; 程序退出时关闭监听
+func Serve(handler Handler) error {
+	path := SocketPath()
+	if err := prepareSocketDir(filepath.Dir(path)); err != nil {
+		return fmt.Errorf("创建本地命令接口目录失败: %v", err)
+	}
+	// 已持有单实例锁, 残留的套接字文件来自异常退出的实例
+	_ = os.Remove(path)
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return fmt.Errorf("监听本地命令接口失败: %v", err)
+	}
+	_ = os.Chmod(path, 0600)
+	info, err := os.Lstat(path)
+	if err == nil {
+		err = checkOwner(info)
+	}
+	if err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("检查本地命令接口失败: %v", err)
+	}
+	logger.Debug("已监听本地命令接口", llog.String("path", path))
+
+	lkit.OnShutdown("ipc", func() {
+		_ = listener.Close()
+	})
+	lkit.Go("ipc.accept", func(ctx context.Context) {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				if ctx.Err() != nil || errors.Is(err, net.ErrClosed) {
+					return
+				}
+				logger.Warn("接受命令连接失败", llog.Err(err))
+				continue
+			}
+			lkit.Go("ipc.conn", func(context.Context) {
+				serveConn(conn, handler)
+			})
+		}
+	})
+	return nil
+}
+
+// serveConn 读取一条命令, 执行后写回结果
+func serveConn(conn net.Conn, handler Handler) {
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(RequestTimeout))
+
+	if err := checkPeer(conn); err != nil {
+		logger.Warn("拒绝命令连接", llog.Err(err))
+		return
+	}
+
+	var request Request
+	if err := json.NewDecoder(conn).Decode(&request); err != nil {
+		logger.Warn("读取命令失败", llog.Err(err))
+		return
+	}
+	logger.Info("收到命令", llog.String("command", request.Command), llog.String("profile", request.Profile))
+
+	response := handler(request)
+	if !response.OK {
+		logger.Warn("命令执行失败", llog.String("command", request.Command), llog.String("error", response.Error))
+	}
+	if err := json.NewEncoder(conn).Encode(response); err != nil {
+		logger.Warn("返回命令结果失败", llog.Err(err))
+	}
+}
+
+// Send 把命令发送给已运行的实例并等待执行结果
+func Send(request Request) (*Response, error) {
+	conn, err := net.DialTimeout("unix", SocketPath(), dialTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("连接已运行的程序失败: %v", err)
+	}
+	defer func() {
+		_ = conn.Close()
+	}()
+	_ = conn.SetDeadline(time.Now().Add(RequestTimeout))
+
+	if err = json.NewEncoder(conn).Encode(request); err != nil {
+		return nil, fmt.Errorf("发送命令失败: %v", err)
+	}
+	var response Response
+	if err = json.NewDecoder(conn).Decode(&response); err != nil {
+		return nil, fmt.Errorf("读取命令结果失败: %v", err)
+	}
+	return &response, nil
+}
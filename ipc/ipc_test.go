@@ -0,0 +1,77 @@
+package ipc
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestParseArgs(t *testing.T) {
+	if request := ParseArgs([]string{"--config", "a.toml"}); request != nil {
+		t.Errorf("没有命令时应返回 nil, 实际 %+v", request)
+	}
+	request := ParseArgs([]string{"--one-click", "--profile=B"})
+	if request == nil || request.Command != CommandOneClick || request.Profile != "B" {
+		t.Errorf("解析命令不正确: %+v", request)
+	}
+	request = ParseArgs([]string{"--profile", "B"})
+	if request == nil || request.Command != CommandShow || request.Profile != "B" {
+		t.Errorf("只指定配置方案时应显示窗口: %+v", request)
+	}
+}
+
+func TestServe(t *testing.T) {
+	socketPath = filepath.Join(t.TempDir(), socketDirName, socketName)
+	defer func() {
+		socketPath = ""
+	}()
+
+	err := Serve(func(request Request) Response {
+		if request.Command == CommandStartCapture {
+			return Succeeded("已开始抓包, 方案: " + request.Profile)
+		}
+		return Failed(errors.New("不支持的命令"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	response, err := Send(Request{Command: CommandStartCapture, Profile: "B"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !response.OK || response.Message != "已开始抓包, 方案: B" {
+		t.Errorf("命令结果不正确: %+v", response)
+	}
+	if response, err = Send(Request{Command: "unknown"}); err != nil || response.OK || response.Error == "" {
+		t.Errorf("失败的命令应返回错误: %+v, %v", response, err)
+	}
+}
+
+func TestPrepareSocketDir(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("Windows 下由目录所在位置限制访问")
+	}
+	// 已存在的目录权限过宽时收紧为仅当前用户可访问
+	dir := filepath.Join(t.TempDir(), socketDirName)
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := prepareSocketDir(dir); err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Errorf("套接字目录权限错误: %v %v", info.Mode(), err)
+	}
+
+	// 同名文件不能作为套接字目录
+	file := filepath.Join(t.TempDir(), "file")
+	if err := os.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := prepareSocketDir(file); err == nil {
+		t.Error("文件不能作为套接字目录")
+	}
+}
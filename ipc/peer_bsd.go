@@ -0,0 +1,14 @@
+//go:build darwin || freebsd
+
+package ipc
+
+import "golang.org/x/sys/unix"
+
+// peerUID 通过 LOCAL_PEERCRED 获取连接方的用户ID, 与 getpeereid 相同
+func peerUID(fd int) (uint32, error) {
+	cred, err := unix.GetsockoptXucred(fd, unix.SOL_LOCAL, unix.LOCAL_PEERCRED)
+	if err != nil {
+		return 0, err
+	}
+	return cred.Uid, nil
+}
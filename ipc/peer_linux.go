@@ -0,0 +1,12 @@
+package ipc
+
+import "golang.org/x/sys/unix"
+
+// peerUID 通过 SO_PEERCRED 获取连接方的用户ID
+func peerUID(fd int) (uint32, error) {
+	cred, err := unix.GetsockoptUcred(fd, unix.SOL_SOCKET, unix.SO_PEERCRED)
+	if err != nil {
+		return 0, err
+	}
+	return cred.Uid, nil
+}
@@ -0,0 +1,49 @@
+//go:build unix
+
+package ipc
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+)
+
+// checkOwner 检查目录或套接字文件是否为当前用户所有
+func checkOwner(info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fmt.Errorf("无法获取 %s 的所有者", info.Name())
+	}
+	if uid := os.Getuid(); int(stat.Uid) != uid {
+		return fmt.Errorf("%s 属于其他用户(uid %d)", info.Name(), stat.Uid)
+	}
+	return nil
+}
+
+// checkPeer 检查连接方是否为当前用户
+func checkPeer(conn net.Conn) error {
+	unixConn, ok := conn.(*net.UnixConn)
+	if !ok {
+		return fmt.Errorf("不支持的连接类型: %T", conn)
+	}
+	raw, err := unixConn.SyscallConn()
+	if err != nil {
+		return err
+	}
+
+	var uid uint32
+	var uidErr error
+	if err = raw.Control(func(fd uintptr) {
+		uid, uidErr = peerUID(int(fd))
+	}); err != nil {
+		return err
+	}
+	if uidErr != nil {
+		return fmt.Errorf("获取连接方用户失败: %v", uidErr)
+	}
+	if int(uid) != os.Getuid() {
+		return fmt.Errorf("连接方属于其他用户(uid %d)", uid)
+	}
+	return nil
+}
@@ -0,0 +1,10 @@
+//go:build unix && !linux && !darwin && !freebsd
+
+package ipc
+
+import "errors"
+
+// peerUID 其他平台无法获取连接方的用户ID, 拒绝所有连接
+func peerUID(int) (uint32, error) {
+	return 0, errors.New("当前平台不支持检查连接方用户")
+}
@@ -0,0 +1,16 @@
+package ipc
+
+import (
+	"net"
+	"os"
+)
+
+// checkOwner Windows 下套接字目录位于当前用户的数据目录或临时目录(%TEMP%)中, 由目录的访问控制限制其他用户
+func checkOwner(os.FileInfo) error {
+	return nil
+}
+
+// checkPeer Windows 的本地套接字无法获取连接方用户, 只依赖套接字目录的访问控制
+func checkPeer(net.Conn) error {
+	return nil
+}
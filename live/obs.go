@@ -0,0 +1,205 @@
+// Package live 开播相关的操作: 启动和导入配置到OBS、启动和操作直播伴侣、一键开播流程
+// 不依赖界面, 界面和命令行共用
+package live
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/andreykaipov/goobs"
+	goobsCfg "github.com/andreykaipov/goobs/api/requests/config"
+	"github.com/andreykaipov/goobs/api/typedefs"
+
+	"tiktok_tool/config"
+	"tiktok_tool/lkit"
+	"tiktok_tool/llog"
+)
+
+// 导入OBS配置的方式
+const (
+	ImportByWebSocket = "websocket" // OBS运行时通过WebSocket导入
+	ImportByFile      = "file"      // OBS未运行时写入配置文件
+)
+
+// ErrOBSRunning OBS正在运行且未配置WebSocket, 需要关闭OBS后才能写入配置文件
+var ErrOBSRunning = errors.New("OBS正在运行，请先关闭OBS后再导入配置")
+
+// obsLog OBS模块日志
+var obsLog = llog.Named("obs")
+
+// OBSRunning 检查OBS是否正在运行, 返回进程ID, 未运行时返回-1
+func OBSRunning() int32 {
+	pids, err := lkit.IsProcessRunning("obs64.exe", "obs32.exe")
+	if err != nil {
+		obsLog.Error("检查OBS进程失败", llog.Err(err))
+		return -1
+	}
+	if pids[0] > 0 {
+		return pids[0]
+	}
+	if pids[1] > 0 {
+		return pids[1]
+	}
+	return -1
+}
+
+// StartOBS 启动OBS, 已在运行时置顶窗口, check 为true时已在运行返回错误
+func StartOBS(check bool) error {
+	obsPath := strings.TrimSpace(config.GetConfig().Profile().PathSettings.OBSLaunchPath)
+	if obsPath == "" {
+		return fmt.Errorf("请先在设置中配置OBS启动路径")
+	}
+
+	// 检查文件是否存在
+	if _, err := os.Stat(obsPath); os.IsNotExist(err) {
+		return fmt.Errorf("OBS文件不存在：%s", obsPath)
+	}
+
+	// 检查OBS是否正在运行
+	if pid := OBSRunning(); pid != -1 {
+		success, err := lkit.BringWindowToFront("OBS")
+		if err != nil || !success {
+			return fmt.Errorf("检测到OBS已经正在运行！\n置顶OBS窗口失败: %v", err)
+		}
+		if check {
+			return fmt.Errorf("检测到OBS已经正在运行！\n请勿重复运行OBS(已置顶窗口)")
+		}
+		return nil
+	}
+
+	// 获取OBS安装目录作为工作目录
+	obsDir := filepath.Dir(obsPath)
+
+	// 启动OBS，设置正确的工作目录
+	cmd := exec.Command(obsPath)
+	cmd.Dir = obsDir
+	err := cmd.Start()
+	if err != nil {
+		return fmt.Errorf("启动OBS失败：%v", err)
+	}
+
+	obsLog.Debug("OBS启动", llog.String("path", obsPath))
+	return nil
+}
+
+// ImportOBSConfig 导入推流配置, OBS运行时通过WebSocket导入, 未运行时写入配置文件
+// OBS正在运行且未配置WebSocket时返回 ErrOBSRunning, 返回值为导入方式
+func ImportOBSConfig(server, key string) (string, error) {
+	server = strings.TrimSpace(server)
+	key = strings.TrimSpace(key)
+	if server == "" || key == "" {
+		return "", fmt.Errorf("推流信息不完整")
+	}
+
+	if pid := OBSRunning(); pid != -1 {
+		if config.GetConfig().Profile().OBSWsIp == "" {
+			return "", ErrOBSRunning
+		}
+		if err := SetStreamByWebSocket(server, key); err != nil {
+			return "", fmt.Errorf("通过WebSocket导入OBS配置失败：%v", err)
+		}
+		return ImportByWebSocket, nil
+	}
+
+	obsConfigPath := strings.TrimSpace(config.GetConfig().Profile().PathSettings.OBSConfigPath)
+	if obsConfigPath == "" {
+		return "", fmt.Errorf("请先在设置中配置OBS配置文件路径")
+	}
+	return ImportByFile, WriteOBSConfig(obsConfigPath, server, key)
+}
+
+// SetStreamByWebSocket 通过OBS WebSocket设置推流服务器和推流码
+func SetStreamByWebSocket(server, key string) error {
+	cfg := config.GetConfig().Profile()
+	client, err := goobs.New(lkit.GetAddr(cfg.OBSWsIp, cfg.OBSWsPort), goobs.WithPassword(cfg.OBSWsPassword))
+	if err != nil {
+		return fmt.Errorf("连接OBS WebSocket失败: %v", err)
+	}
+	// 程序退出时仍在通信的连接也需要断开, 只断开一次
+	var disconnectOnce sync.Once
+	disconnect := func() {
+		disconnectOnce.Do(func() {
+			_ = client.Disconnect()
+		})
+	}
+	removeHook := lkit.OnShutdown("obs.websocket", disconnect)
+	defer func() {
+		removeHook()
+		disconnect()
+	}()
+
+	serviceType := "rtmp_custom"
+	settings := &typedefs.StreamServiceSettings{
+		Server: server,
+		Key:    key,
+	}
+	_, err = client.Config.SetStreamServiceSettings(&goobsCfg.SetStreamServiceSettingsParams{
+		StreamServiceType:     &serviceType,
+		StreamServiceSettings: settings,
+	})
+	if err != nil {
+		return fmt.Errorf("设置OBS推流服务失败: %v", err)
+	}
+
+	obsLog.Debug("OBS推流配置已通过WebSocket更新")
+
+	return nil
+}
+
+// WriteOBSConfig 将推流配置写入OBS配置文件(service.json)
+func WriteOBSConfig(configPath, server, key string) error {
+	// 检查文件是否存在
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		return fmt.Errorf("配置文件不存在: %s", configPath)
+	}
+
+	// 读取JSON配置文件
+	content, err := os.ReadFile(configPath)
+	if err != nil {
+		return fmt.Errorf("读取配置文件失败: %v", err)
+	}
+
+	// 解析JSON
+	var cfgMap map[string]interface{}
+	err = json.Unmarshal(content, &cfgMap)
+	if err != nil {
+		return fmt.Errorf("解析JSON配置文件失败: %v", err)
+	}
+
+	// 确保settings字段存在
+	if cfgMap["settings"] == nil {
+		cfgMap["settings"] = make(map[string]interface{})
+	}
+
+	// 获取settings对象
+	settings, ok := cfgMap["settings"].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("配置文件格式错误: settings字段不是对象")
+	}
+
+	// 更新server和key字段
+	settings["server"] = server
+	settings["key"] = key
+
+	// 将修改后的配置转换回JSON
+	newContent, err := json.MarshalIndent(cfgMap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化JSON配置失败: %v", err)
+	}
+
+	// 写回文件
+	err = os.WriteFile(configPath, newContent, 0644)
+	if err != nil {
+		return fmt.Errorf("写入配置文件失败: %v", err)
+	}
+
+	obsLog.Debug("OBS推流配置已写入配置文件", llog.String("path", configPath))
+
+	return nil
+}
@@ -0,0 +1,262 @@
+package live
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"tiktok_tool/capture"
+	"tiktok_tool/config"
+	"tiktok_tool/lkit"
+	"tiktok_tool/llog"
+)
+
+const (
+	// 一键开播的步骤数, 用于计算进度
+	oneClickSteps = 7
+	// 点击开始直播后等待推流信息的最长时间
+	streamInfoTimeout = 20 * time.Second
+	// 找到推流码后等待解析推流IP的时间
+	streamIPWait = time.Second
+)
+
+// ErrCaptureTimeout 超时未抓取到推流信息
+var ErrCaptureTimeout = errors.New("获取推流信息超时，请检查配置文件或检查网络连接或重试")
+
+// autoLog 一键开播模块日志
+var autoLog = llog.Named("auto")
+
+// StreamInfo 抓包得到的推流信息
+type StreamInfo struct {
+	Server    string `json:"server"`
+	StreamKey string `json:"stream_key"`
+	StreamIP  string `json:"stream_ip,omitempty"`
+}
+
+// CaptureHooks 抓包过程中找到推流信息时的回调, 均可为空
+type CaptureHooks struct {
+	OnServer    func(server string)
+	OnStreamKey func(key string)
+	OnStreamIP  func(ip string)
+}
+
+// CaptureSession 一次抓包, 找到服务器地址和推流码后自动停止
+type CaptureSession struct {
+	mu        sync.Mutex
+	info      StreamInfo
+	found     chan struct{}
+	foundOnce sync.Once
+	ipFound   chan struct{}
+	ipOnce    sync.Once
+}
+
+// StartCapture 开始抓包, 已在抓包时返回错误
+func StartCapture(hooks CaptureHooks) (*CaptureSession, error) {
+	if !capture.BeginCapture() {
+		return nil, fmt.Errorf("当前正在抓包，请先停止抓包")
+	}
+	s := &CaptureSession{found: make(chan struct{}), ipFound: make(chan struct{})}
+
+	// 开始抓包时的错误(正则无效、没有可用网卡)在 StartCapture 返回前回调
+	var startErr error
+	capture.StartCapture(
+		func(server string) {
+			s.update(func(info *StreamInfo) { info.Server = server })
+			notify(hooks.OnServer, server)
+		},
+		func(key string) {
+			s.update(func(info *StreamInfo) { info.StreamKey = key })
+			notify(hooks.OnStreamKey, key)
+		},
+		func(ip string) {
+			s.update(func(info *StreamInfo) { info.StreamIP = ip })
+			notify(hooks.OnStreamIP, ip)
+			s.ipOnce.Do(func() { close(s.ipFound) })
+		},
+		func(err error) {
+			startErr = fmt.Errorf("抓包过程中发生错误: %v", err)
+		},
+		func() {
+			s.foundOnce.Do(func() { close(s.found) })
+		},
+	)
+	if startErr != nil {
+		capture.StopCapturing()
+		return nil, startErr
+	}
+	return s, nil
+}
+
+func notify(hook func(string), value string) {
+	if hook != nil {
+		hook(value)
+	}
+}
+
+func (s *CaptureSession) update(fn func(info *StreamInfo)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(&s.info)
+}
+
+// Info 当前已找到的推流信息
+func (s *CaptureSession) Info() StreamInfo {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.info
+}
+
+// Wait 等待找到服务器地址和推流码, 超时或取消时停止抓包并返回错误
+// 推流IP在找到推流码后才能解析, 找到后再稍等推流IP
+func (s *CaptureSession) Wait(ctx context.Context, timeout time.Duration) (StreamInfo, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-s.found:
+	case <-timer.C:
+		s.Stop()
+		return s.Info(), ErrCaptureTimeout
+	case <-ctx.Done():
+		s.Stop()
+		return s.Info(), ctx.Err()
+	}
+
+	select {
+	case <-s.ipFound:
+	case <-time.After(streamIPWait):
+	case <-ctx.Done():
+	}
+	return s.Info(), nil
+}
+
+// Stop 停止抓包
+func (s *CaptureSession) Stop() {
+	capture.StopCapturing()
+}
+
+// OneClickHooks 一键开播过程中的回调, 均可为空
+type OneClickHooks struct {
+	CaptureHooks
+	OnProgress func(text string, value float64) // value 为 0-1 的进度
+}
+
+// ValidateOneClick 验证一键开播所需的配置
+func ValidateOneClick() error {
+	cfg := config.GetConfig().Profile().PathSettings
+
+	// 检查直播伴侣路径
+	if strings.TrimSpace(cfg.LiveCompanionPath) == "" {
+		return fmt.Errorf("请先在设置中配置直播伴侣启动路径")
+	}
+	if _, err := os.Stat(cfg.LiveCompanionPath); os.IsNotExist(err) {
+		return fmt.Errorf("直播伴侣文件不存在：%s", cfg.LiveCompanionPath)
+	}
+
+	// 检查OBS路径
+	if strings.TrimSpace(cfg.OBSLaunchPath) == "" {
+		return fmt.Errorf("请先在设置中配置OBS启动路径")
+	}
+	if _, err := os.Stat(cfg.OBSLaunchPath); os.IsNotExist(err) {
+		return fmt.Errorf("OBS文件不存在：%s", cfg.OBSLaunchPath)
+	}
+
+	// 检查OBS配置路径
+	if strings.TrimSpace(cfg.OBSConfigPath) == "" {
+		return fmt.Errorf("请先在设置中配置OBS配置文件路径")
+	}
+	if _, err := os.Stat(cfg.OBSConfigPath); os.IsNotExist(err) {
+		return fmt.Errorf("OBS配置文件不存在：%s", cfg.OBSConfigPath)
+	}
+
+	// 检查auto.exe脚本路径
+	if strings.TrimSpace(cfg.PluginScriptPath) == "" {
+		return fmt.Errorf("请先在设置中配置自动化脚本路径")
+	}
+	if _, err := os.Stat(cfg.PluginScriptPath); os.IsNotExist(err) {
+		return fmt.Errorf("自动化脚本文件不存在：%s", cfg.PluginScriptPath)
+	}
+
+	// 检查是否已有程序在运行
+	if pid := OBSRunning(); pid != -1 && config.GetConfig().Profile().OBSWsIp == "" {
+		return fmt.Errorf("OBS已在运行，请先关闭后再使用一键开播")
+	}
+	// if pid := CompanionRunning(); pid != -1 {
+	// 	return fmt.Errorf("直播伴侣已在运行，请先关闭后再使用一键开播")
+	// }
+
+	// 检查是否正在抓包
+	if capture.IsCapturing() {
+		return fmt.Errorf("当前正在抓包，请先停止抓包后再使用一键开播")
+	}
+
+	return nil
+}
+
+// RunOneClick 执行一键开播流程, ctx 取消时中断
+// 流程：启动直播伴侣 -> 开始抓包 -> 模拟点击开始直播 -> 获取推流信息 -> 导入OBS -> 启动OBS -> 关闭直播伴侣
+func RunOneClick(ctx context.Context, hooks OneClickHooks) (info StreamInfo, err error) {
+	progress := func(text string, step int) {
+		if hooks.OnProgress != nil {
+			hooks.OnProgress(text, float64(step)/oneClickSteps)
+		}
+	}
+
+	// 启动直播伴侣
+	progress("正在启动直播伴侣...", 1)
+	if err = StartCompanion(false); err != nil {
+		return info, err
+	}
+	if err = lkit.Sleep(ctx, 1*time.Second); err != nil {
+		return info, err
+	}
+
+	// 开始抓包
+	progress("正在抓包...", 2)
+	session, err := StartCapture(hooks.CaptureHooks)
+	if err != nil {
+		return info, err
+	}
+	defer func() {
+		if err != nil {
+			session.Stop()
+		}
+	}()
+
+	// 模拟点击开始直播
+	progress("正在模拟点击开始直播...", 3)
+	if err = ClickStartLive(); err != nil {
+		return info, err
+	}
+	if info, err = session.Wait(ctx, streamInfoTimeout); err != nil {
+		return info, err
+	}
+	autoLog.Debug("成功获取到推流信息", llog.String("server", info.Server), llog.String("stream_key", info.StreamKey))
+	if err = lkit.Sleep(ctx, 500*time.Millisecond); err != nil {
+		return info, err
+	}
+
+	// 导入OBS配置
+	progress("正在导入OBS配置...", 4)
+	if _, err = ImportOBSConfig(info.Server, info.StreamKey); err != nil {
+		return info, fmt.Errorf("导入OBS配置失败：%v", err)
+	}
+
+	// 启动OBS
+	progress("正在启动OBS...", 5)
+	if err = StartOBS(false); err != nil {
+		return info, err
+	}
+
+	// 关闭直播伴侣
+	progress("正在关闭直播伴侣...", 6)
+	if err = CloseCompanion(); err != nil {
+		return info, err
+	}
+
+	progress("一键开播完成！", oneClickSteps)
+	return info, nil
+}
@@ -0,0 +1,13 @@
+package lkit
+
+import "errors"
+
+// IsAdmin 当前程序是否具有管理员权限(Linux/macOS下为root或抓包所需的capability)
+var IsAdmin bool
+
+// ErrUnsupported 当前平台不支持该操作
+var ErrUnsupported = errors.New("当前平台不支持该操作")
+
+func init() {
+	IsAdmin = IsRunAsAdmin()
+}
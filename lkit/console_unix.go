@@ -0,0 +1,6 @@
+//go:build !windows
+
+package lkit
+
+// AttachConsole 非Windows平台从终端启动时已有标准输出
+func AttachConsole() {}
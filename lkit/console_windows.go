@@ -0,0 +1,38 @@
+package lkit
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+var procAttachConsole = windows.NewLazySystemDLL("kernel32.dll").NewProc("AttachConsole")
+
+// attachParentProcess ATTACH_PARENT_PROCESS, 即 (DWORD)-1
+const attachParentProcess = ^uint32(0)
+
+// AttachConsole 以窗口程序编译(-H=windowsgui)时没有控制台, 命令行模式下连接到启动程序的控制台输出结果
+// 标准输出已重定向到文件或管道时保持不变
+func AttachConsole() {
+	if validStdHandle(windows.STD_OUTPUT_HANDLE) && validStdHandle(windows.STD_ERROR_HANDLE) {
+		return
+	}
+	if r, _, _ := procAttachConsole.Call(uintptr(attachParentProcess)); r == 0 {
+		return
+	}
+	if !validStdHandle(windows.STD_OUTPUT_HANDLE) {
+		if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+			os.Stdout = f
+		}
+	}
+	if !validStdHandle(windows.STD_ERROR_HANDLE) {
+		if f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0); err == nil {
+			os.Stderr = f
+		}
+	}
+}
+
+func validStdHandle(id uint32) bool {
+	handle, err := windows.GetStdHandle(id)
+	return err == nil && handle != 0 && handle != windows.InvalidHandle
+}
@@ -1,37 +1,289 @@
 package lkit
 
 import (
+	"bufio"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
 	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tiktok_tool/appdir"
+	"tiktok_tool/llog"
+)
+
+const (
+	// MaxCrashReports 保留的崩溃报告数量, 超过时删除最旧的报告
+	MaxCrashReports = 10
+	// CrashLogLines 崩溃报告中包含的最近日志条数
+	CrashLogLines = 200
+
+	// CrashSourceMain 主协程崩溃, 程序已退出
+	CrashSourceMain = "主协程"
+	// CrashSourceGoroutine 后台任务崩溃, 已恢复, 程序继续运行
+	CrashSourceGoroutine = "后台任务"
+
+	crashDirName      = "crash"
+	crashFilePrefix   = "crash_"
+	crashFileExt      = ".log"
+	crashSeenName     = ".seen"
+	crashTimeLayout   = "20060102_150405.000"
+	legacyCrashLog    = "panic_error.log"
+	crashErrorPrefix  = "错误: "
+	crashSourcePrefix = "来源: "
+	// 协程堆栈的最大长度, 协程很多时截断
+	maxStackDumpSize = 4 << 20
+)
+
+var (
+	crashDir   = crashDirName
+	crashMutex sync.Mutex
 )
 
-var panicErrorLogName = "panic_error.log"
+// CrashReport 崩溃报告文件
+type CrashReport struct {
+	Name    string
+	Path    string
+	Time    time.Time
+	Source  string
+	Summary string // 崩溃原因
+}
 
+// InitCrashLog 设置崩溃报告目录, 旧版本的 panic_error.log 移动到崩溃报告目录保留
 func InitCrashLog() {
-	currentDir, err := os.Getwd()
-	if err == nil {
-		panicErrorLogName = filepath.Join(currentDir, panicErrorLogName)
+	crashDir = appdir.Join(crashDirName)
+	if err := os.MkdirAll(crashDir, 0755); err != nil {
+		fmt.Println("创建崩溃报告目录失败: ", err)
+		return
+	}
+
+	legacy := appdir.Join(legacyCrashLog)
+	if info, err := os.Stat(legacy); err == nil {
+		crashMutex.Lock()
+		_ = os.Rename(legacy, uniqueCrashPath(info.ModTime()))
+		crashMutex.Unlock()
+	}
+}
+
+// CrashDir 崩溃报告目录
+func CrashDir() string {
+	return crashDir
+}
+
+// AppVersion 程序版本, 来自构建信息
+func AppVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
 	}
-	_ = os.Remove(panicErrorLogName)
+	version := info.Main.Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= 7 {
+			version += " (" + setting.Value[:7] + ")"
+		}
+	}
+	return version
 }
 
+// CrashLog 在主协程中 defer 调用, 程序崩溃时记录崩溃报告
 func CrashLog() {
 	r := recover()
 	if r == nil {
 		return
 	}
 
-	file, err := os.Create(panicErrorLogName)
+	stack := debug.Stack()
+	llog.Error(fmt.Sprintf("程序发生严重错误: %v\n%s", r, stack))
+	llog.Sync()
+	path, err := RecordPanic(CrashSourceMain, r, stack)
 	if err != nil {
-		fmt.Println("创建崩溃日志文件失败: ", err)
+		fmt.Printf("程序发生严重错误: %v\n%s\n写入崩溃报告失败: %v\n", r, stack, err)
 		return
 	}
+	fmt.Printf("程序发生严重错误: %v\n崩溃报告: %s\n", r, path)
+}
+
+// recoverGoroutine 后台任务崩溃时记录日志和崩溃报告, 任务已恢复不影响程序运行
+func recoverGoroutine(name string, r interface{}) {
+	stack := debug.Stack()
+	taskLog.Error(fmt.Sprintf("后台任务崩溃: %v\n %s\n", r, stack), llog.String("task", name))
+	if _, err := RecordPanic(CrashSourceGoroutine+" "+name, r, stack); err != nil {
+		taskLog.Error("写入崩溃报告失败", llog.Err(err))
+	}
+}
+
+// RecordPanic 写入崩溃报告, 包含崩溃原因、崩溃协程堆栈、全部协程堆栈、程序版本和最近的日志
+// 返回报告文件路径
+func RecordPanic(source string, r interface{}, stack []byte) (string, error) {
+	now := time.Now()
+	var b strings.Builder
+	b.WriteString("程序发生严重错误\n")
+	fmt.Fprintf(&b, "时间: %s\n", now.Format("2006-01-02 15:04:05.000"))
+	fmt.Fprintf(&b, "版本: %s\n", AppVersion())
+	fmt.Fprintf(&b, "系统: %s/%s %s\n", runtime.GOOS, runtime.GOARCH, runtime.Version())
+	fmt.Fprintf(&b, "%s%s\n", crashSourcePrefix, source)
+	// 崩溃原因只取第一行, 便于读取摘要
+	fmt.Fprintf(&b, "%s%s\n", crashErrorPrefix, strings.ReplaceAll(fmt.Sprint(r), "\n", " "))
+
+	fmt.Fprintf(&b, "\n==== 崩溃协程堆栈 ====\n%s\n", stack)
+	fmt.Fprintf(&b, "\n==== 全部协程堆栈 ====\n%s\n", allStacks())
+
+	records := llog.RecentLogs()
+	if len(records) > CrashLogLines {
+		records = records[len(records)-CrashLogLines:]
+	}
+	fmt.Fprintf(&b, "\n==== 最近 %d 条日志 ====\n", len(records))
+	for _, record := range records {
+		b.WriteString(record.String())
+		b.WriteString("\n")
+	}
+
+	crashMutex.Lock()
+	defer crashMutex.Unlock()
+
+	if err := os.MkdirAll(crashDir, 0755); err != nil {
+		return "", fmt.Errorf("创建崩溃报告目录失败: %v", err)
+	}
+	path := uniqueCrashPath(now)
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("写入崩溃报告失败: %v", err)
+	}
+	rotateCrashReports()
+	return path, nil
+}
+
+// allStacks 全部协程的堆栈, 缓冲区不够时加倍
+func allStacks() []byte {
+	buf := make([]byte, 64<<10)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) || len(buf) >= maxStackDumpSize {
+			return buf[:n]
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+}
+
+// uniqueCrashPath 按时间命名的报告路径, 同一时间已有报告时加序号, 调用方需持有 crashMutex
+func uniqueCrashPath(t time.Time) string {
+	name := crashFilePrefix + t.Format(crashTimeLayout)
+	path := filepath.Join(crashDir, name+crashFileExt)
+	for i := 1; ; i++ {
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = filepath.Join(crashDir, fmt.Sprintf("%s_%d%s", name, i, crashFileExt))
+	}
+}
+
+// rotateCrashReports 删除超出数量的旧报告, 调用方需持有 crashMutex
+func rotateCrashReports() {
+	names := crashReportNames()
+	for len(names) > MaxCrashReports {
+		_ = os.Remove(filepath.Join(crashDir, names[0]))
+		names = names[1:]
+	}
+}
+
+// crashReportNames 按时间从旧到新排列的报告文件名, 文件名以时间开头所以可以直接按名称排序
+func crashReportNames() []string {
+	entries, err := os.ReadDir(crashDir)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasPrefix(name, crashFilePrefix) && strings.HasSuffix(name, crashFileExt) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CrashReports 全部崩溃报告, 最新的在前
+func CrashReports() []CrashReport {
+	crashMutex.Lock()
+	names := crashReportNames()
+	crashMutex.Unlock()
+
+	reports := make([]CrashReport, 0, len(names))
+	for i := len(names) - 1; i >= 0; i-- {
+		reports = append(reports, readCrashReport(names[i]))
+	}
+	return reports
+}
+
+// readCrashReport 从报告文件头部读取来源和崩溃原因
+func readCrashReport(name string) CrashReport {
+	report := CrashReport{Name: name, Path: filepath.Join(crashDir, name)}
+	stamp := strings.TrimSuffix(strings.TrimPrefix(name, crashFilePrefix), crashFileExt)
+	if len(stamp) > len(crashTimeLayout) {
+		stamp = stamp[:len(crashTimeLayout)]
+	}
+	report.Time, _ = time.ParseInLocation(crashTimeLayout, stamp, time.Local)
 
+	file, err := os.Open(report.Path)
+	if err != nil {
+		report.Summary = err.Error()
+		return report
+	}
 	defer func() {
 		_ = file.Close()
 	}()
 
-	_, _ = file.WriteString(fmt.Sprintf("程序发生严重错误: %v\n堆栈信息:\n%s", r, debug.Stack()))
+	scanner := bufio.NewScanner(file)
+	var first string
+	for i := 0; i < 10 && scanner.Scan(); i++ {
+		line := scanner.Text()
+		if i == 0 {
+			first = line
+		}
+		if value, ok := strings.CutPrefix(line, crashSourcePrefix); ok {
+			report.Source = value
+		}
+		if value, ok := strings.CutPrefix(line, crashErrorPrefix); ok {
+			report.Summary = value
+		}
+	}
+	// 旧版本的崩溃日志只有一行原因
+	if report.Summary == "" {
+		report.Summary = strings.TrimPrefix(first, "程序发生严重错误: ")
+	}
+	return report
+}
+
+// PendingCrashReports 上次查看后新产生的崩溃报告, 最新的在前
+func PendingCrashReports() []CrashReport {
+	seen, _ := os.ReadFile(filepath.Join(crashDir, crashSeenName))
+	last := strings.TrimSpace(string(seen))
+
+	var pending []CrashReport
+	for _, report := range CrashReports() {
+		if report.Name <= last {
+			break
+		}
+		pending = append(pending, report)
+	}
+	return pending
+}
+
+// MarkCrashReportsSeen 将当前的崩溃报告标记为已查看, 下次启动时不再提示
+func MarkCrashReportsSeen() error {
+	crashMutex.Lock()
+	defer crashMutex.Unlock()
+
+	names := crashReportNames()
+	if len(names) == 0 {
+		return nil
+	}
+	if err := os.WriteFile(filepath.Join(crashDir, crashSeenName), []byte(names[len(names)-1]), 0644); err != nil {
+		return fmt.Errorf("保存崩溃报告查看状态失败: %v", err)
+	}
+	return nil
 }
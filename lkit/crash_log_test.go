@@ -0,0 +1,53 @@
+package lkit
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCrashReports(t *testing.T) {
+	crashDir = t.TempDir()
+
+	path, err := RecordPanic(CrashSourceGoroutine, "boom\nsecond line", []byte("goroutine 1 [running]"))
+	if err != nil {
+		t.Fatalf("写入崩溃报告失败: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"版本: ", "错误: boom second line", "goroutine 1 [running]", "全部协程堆栈", "TestCrashReports"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("崩溃报告缺少 %q", want)
+		}
+	}
+
+	pending := PendingCrashReports()
+	if len(pending) != 1 || pending[0].Summary != "boom second line" || pending[0].Source != CrashSourceGoroutine {
+		t.Fatalf("未查看的崩溃报告不正确: %+v", pending)
+	}
+	if err = MarkCrashReportsSeen(); err != nil {
+		t.Fatal(err)
+	}
+	if pending = PendingCrashReports(); len(pending) != 0 {
+		t.Fatalf("标记已查看后仍有未查看的报告: %+v", pending)
+	}
+
+	// 超过保留数量时删除最旧的报告
+	for i := 0; i < MaxCrashReports+2; i++ {
+		if _, err = RecordPanic(CrashSourceMain, i, nil); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reports := CrashReports()
+	if len(reports) != MaxCrashReports {
+		t.Fatalf("保留的崩溃报告数量 = %d, 期望 %d", len(reports), MaxCrashReports)
+	}
+	if reports[0].Summary != "11" {
+		t.Errorf("最新的报告应在前, 实际为 %q", reports[0].Summary)
+	}
+	if pending = PendingCrashReports(); len(pending) != MaxCrashReports {
+		t.Errorf("新产生的报告应未查看, 实际 %d 个", len(pending))
+	}
+}
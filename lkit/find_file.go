@@ -6,90 +6,50 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
-	"syscall"
 	"time"
-	"unsafe"
 
 	"tiktok_tool/llog"
 )
 
 func FindFileInAllDrives(fileName string) string {
-	llog.Info("开始在所有盘符中搜索文件：", fileName)
+	llog.With(llog.String("file", fileName)).Info("开始在所有盘符中搜索文件")
 	// 获取所有可用的盘符
 	drives := getAllDrives()
 	if len(drives) == 0 {
 		return ""
 	}
-	llog.Debug("可用盘符：", drives)
-
-	// 创建上下文用于取消搜索
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	llog.With(llog.Strings("drives", drives)).Debug("可用盘符")
 
 	resultChan := make(chan string)
 
-	// 为每个盘符启动一个goroutine进行搜索
+	// 为每个盘符启动一个任务进行搜索, 找到文件、超时或程序退出时取消全部搜索
+	tasks := make([]*Task, 0, len(drives))
+	defer func() {
+		for _, task := range tasks {
+			task.Cancel()
+		}
+	}()
 	for _, drive := range drives {
-		SafeGo(func() {
+		tasks = append(tasks, Go("find_file."+drive, func(ctx context.Context) {
 			if result := searchFileInDrive(ctx, drive, fileName); result != "" {
 				select {
 				case resultChan <- result:
-					cancel() // 找到文件后取消其他搜索
 				default:
 				}
 			}
-		})
+		}))
 	}
 
 	select {
 	case result := <-resultChan:
 		return result
 	case <-time.After(15 * time.Second):
-		cancel()
+		return ""
+	case <-ShuttingDown():
 		return ""
 	}
 }
 
-func getAllDrives() []string {
-	var drives []string
-
-	// 使用Windows API获取逻辑驱动器
-	kernel32 := syscall.NewLazyDLL("kernel32.dll")
-	getLogicalDrives := kernel32.NewProc("GetLogicalDrives")
-
-	ret, _, _ := getLogicalDrives.Call()
-	if ret == 0 {
-		return drives
-	}
-
-	// 检查每个可能的驱动器字母（A-Z）
-	for i := 0; i < 26; i++ {
-		if ret&(1<<uint(i)) != 0 {
-			driveLetter := string(rune('A' + i))
-			drivePath := driveLetter + ":\\"
-
-			// 检查驱动器是否可访问
-			if isDriveAccessible(drivePath) {
-				drives = append(drives, drivePath)
-			}
-		}
-	}
-
-	return drives
-}
-
-func isDriveAccessible(drivePath string) bool {
-	kernel32 := syscall.NewLazyDLL("kernel32.dll")
-	getDriveType := kernel32.NewProc("GetDriveTypeW")
-
-	drivePathPtr, _ := syscall.UTF16PtrFromString(drivePath)
-	driveType, _, _ := getDriveType.Call(uintptr(unsafe.Pointer(drivePathPtr)))
-
-	// 只搜索固定磁盘和可移动磁盘
-	// DRIVE_FIXED = 3, DRIVE_REMOVABLE = 2
-	return driveType == 3 || driveType == 2
-}
-
 func searchFileInDrive(ctx context.Context, searchPath, fileName string) string {
 	var result string
 
@@ -108,8 +68,11 @@ func searchFileInDrive(ctx context.Context, searchPath, fileName string) string
 			}
 			return err
 		}
+		if d.IsDir() && skipSearchDir(path) {
+			return filepath.SkipDir
+		}
 		if !d.IsDir() && strings.EqualFold(d.Name(), fileName) {
-			llog.Info("找到文件：", path)
+			llog.With(llog.String("path", path)).Info("找到文件")
 			result = path
 			return filepath.SkipAll // 找到文件后立即停止遍历
 		}
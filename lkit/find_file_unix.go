@@ -0,0 +1,76 @@
+//go:build !windows
+
+package lkit
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// 需要搜索的文件系统类型, 其余(proc/sysfs/tmpfs/overlay等)均为虚拟或临时文件系统
+var searchFsTypes = []string{
+	"ext2", "ext3", "ext4", "xfs", "btrfs", "zfs", "f2fs", "jfs", "reiserfs",
+	"vfat", "exfat", "ntfs", "ntfs3", "fuseblk", "apfs", "hfs",
+}
+
+// 搜索时跳过的虚拟目录
+var skipDirs = []string{"/proc", "/sys", "/dev", "/run"}
+
+// getAllDrives 获取所有挂载点, 作为Windows盘符的等价物
+func getAllDrives() []string {
+	drives := mountPoints()
+	if len(drives) == 0 {
+		drives = []string{"/"}
+	}
+
+	// macOS 的外接磁盘挂载在 /Volumes 下
+	if entries, err := os.ReadDir("/Volumes"); err == nil {
+		for _, entry := range entries {
+			drives = append(drives, filepath.Join("/Volumes", entry.Name()))
+		}
+	}
+
+	return drives
+}
+
+// mountPoints 从 /proc/mounts 读取真实文件系统的挂载点
+func mountPoints() []string {
+	file, err := os.Open("/proc/mounts")
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var points []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || !slices.Contains(searchFsTypes, fields[2]) {
+			continue
+		}
+		// 挂载点中的空格等字符以八进制转义
+		point := strings.ReplaceAll(fields[1], `\040`, " ")
+		if !isUnderMountPoint(points, point) {
+			points = append(points, point)
+		}
+	}
+	return points
+}
+
+// isUnderMountPoint 已有的挂载点会递归搜索其子目录, 避免重复搜索
+func isUnderMountPoint(points []string, point string) bool {
+	for _, p := range points {
+		if p == "/" || point == p || strings.HasPrefix(point, p+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// skipSearchDir 跳过 /proc 等虚拟目录
+func skipSearchDir(path string) bool {
+	return slices.Contains(skipDirs, path)
+}
@@ -0,0 +1,51 @@
+package lkit
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+func getAllDrives() []string {
+	var drives []string
+
+	// 使用Windows API获取逻辑驱动器
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	getLogicalDrives := kernel32.NewProc("GetLogicalDrives")
+
+	ret, _, _ := getLogicalDrives.Call()
+	if ret == 0 {
+		return drives
+	}
+
+	// 检查每个可能的驱动器字母（A-Z）
+	for i := 0; i < 26; i++ {
+		if ret&(1<<uint(i)) != 0 {
+			driveLetter := string(rune('A' + i))
+			drivePath := driveLetter + ":\\"
+
+			// 检查驱动器是否可访问
+			if isDriveAccessible(drivePath) {
+				drives = append(drives, drivePath)
+			}
+		}
+	}
+
+	return drives
+}
+
+func isDriveAccessible(drivePath string) bool {
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	getDriveType := kernel32.NewProc("GetDriveTypeW")
+
+	drivePathPtr, _ := syscall.UTF16PtrFromString(drivePath)
+	driveType, _, _ := getDriveType.Call(uintptr(unsafe.Pointer(drivePathPtr)))
+
+	// 只搜索固定磁盘和可移动磁盘
+	// DRIVE_FIXED = 3, DRIVE_REMOVABLE = 2
+	return driveType == 3 || driveType == 2
+}
+
+// skipSearchDir Windows下不需要跳过任何目录
+func skipSearchDir(string) bool {
+	return false
+}
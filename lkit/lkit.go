@@ -35,7 +35,7 @@ func AnyToStr(v interface{}) string {
 func Str2Int32(str string) int32 {
 	num := Str2Int64(str)
 	if num > MaxInt32 {
-		llog.Error("Str2Int32 overflow", str)
+		llog.With(llog.String("value", str)).Error("Str2Int32 overflow")
 	}
 	return int32(num)
 }
@@ -51,7 +51,7 @@ func TryParseStr2Int64(str string) (int64, error) {
 func Str2Int64(str string) int64 {
 	i64, err := TryParseStr2Int64(str)
 	if err != nil {
-		llog.Error(err.Error(), 0, "[Str2Int64]", "["+str+"]")
+		llog.With(llog.String("value", str), llog.Err(err)).Error("Str2Int64 failed")
 		return 0
 	}
 	return i64
@@ -64,7 +64,7 @@ func Str2UInt64(str string) uint64 {
 func Str2UInt32(str string) uint32 {
 	num := Str2Int64(str)
 	if num > MaxUInt32 {
-		llog.Error("Str2Int32 overflow", str)
+		llog.With(llog.String("value", str)).Error("Str2UInt32 overflow")
 	}
 	return uint32(num)
 }
@@ -135,7 +135,7 @@ func KillProcess(pid int32) error {
 		return fmt.Errorf("无法终止进程: %w", err)
 	}
 
-	llog.Info("进程已终止", pid)
+	llog.InfoF("进程已终止: %d", pid)
 
 	return nil
 }
@@ -179,8 +179,8 @@ type AutoResult struct {
 // args: 命令行参数
 // 返回值: 解析后的AutoResult结构体和可能的错误
 func RunAutoTool(exePath string, args []string) (*AutoResult, error) {
-	llog.Debug("运行自动化工具:", args)
-	cfg := config.GetConfig().ScriptSettings
+	llog.Named("auto").Debug("运行自动化工具", llog.String("path", exePath), llog.Strings("args", args))
+	cfg := config.GetConfig().Profile().ScriptSettings
 	args = append(args,
 		"--check-interval", AnyToStr(cfg.PluginCheckInterval),
 		"--wait-after-found", AnyToStr(cfg.PluginWaitAfterFound),
@@ -5,59 +5,90 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
-	"runtime"
-	"syscall"
+	"strings"
 
 	"github.com/nightlyone/lockfile"
+	"github.com/shirou/gopsutil/v4/process"
 
-	"tiktok_tool/config"
+	"tiktok_tool/appdir"
+	"tiktok_tool/llog"
 )
 
+// ErrAlreadyRunning 已有实例在运行
+var ErrAlreadyRunning = errors.New("程序已在运行")
+
 // 全局变量，用于在程序退出时释放锁
 var appLock lockfile.Lockfile
 
+// EnsureSingleInstance 获取单实例锁, 已有实例在运行时返回 ErrAlreadyRunning
 func EnsureSingleInstance() error {
-	// 使用当前工作目录作为锁文件存放位置
-	currentDir, err := os.Getwd()
+	// 使用数据根目录作为锁文件存放位置, 从不同目录启动时也能检测到已运行的实例
+	// 锁文件路径 - 使用点开头使其成为隐藏文件
+	lockPath := appdir.Join(".tiktok.lock")
+
+	lock, err := acquireLock(lockPath)
 	if err != nil {
-		return fmt.Errorf("获取当前工作目录失败: %v", err)
+		return err
 	}
 
-	// 锁文件路径 - 使用点开头使其成为隐藏文件
-	lockPath := filepath.Join(currentDir, ".tiktok.lock")
+	if err := hideFile(lockPath); err != nil {
+		return fmt.Errorf("设置锁文件为隐藏失败: %v", err)
+	}
 
+	// 保存锁对象以便后续释放
+	appLock = lock
+	return nil
+}
+
+// acquireLock 获取锁文件, 锁文件中的进程已退出或已不是本程序时视为残留的锁
+func acquireLock(lockPath string) (lockfile.Lockfile, error) {
 	// 创建锁
 	lock, err := lockfile.New(lockPath)
 	if err != nil {
-		return fmt.Errorf("创建锁文件失败: %v", err)
-	}
-
-	// 尝试获取锁
-	if err := lock.TryLock(); err != nil {
-		// 如果锁已存在，检查进程是否还在运行
-		if errors.Is(err, lockfile.ErrBusy) {
-			success, err := BringWindowToFront("抖音直播推流配置抓取")
-			if err != nil || !success {
-				return fmt.Errorf("置顶抖音直播推流配置抓取窗口失败: %v", err)
-			}
-			return config.AlreadyTop
-		}
-		return fmt.Errorf("获取锁失败: %v", err)
+		return "", fmt.Errorf("创建锁文件失败: %v", err)
 	}
 
-	if runtime.GOOS == "windows" {
-		filenameW, err := syscall.UTF16PtrFromString(lockPath)
-		if err != nil {
-			return err
+	// 尝试获取锁, 进程已退出的锁会被自动清理
+	err = lock.TryLock()
+	if errors.Is(err, lockfile.ErrBusy) {
+		// 异常退出后进程ID可能被其他程序复用, 需要检查进程是否为本程序
+		owner, ownerErr := lock.GetOwner()
+		if ownerErr != nil || isSameProgram(owner.Pid) {
+			return "", ErrAlreadyRunning
 		}
-		if err := syscall.SetFileAttributes(filenameW, syscall.FILE_ATTRIBUTE_HIDDEN); err != nil {
-			return fmt.Errorf("设置锁文件为隐藏失败: %v", err)
+		llog.With(llog.Int("pid", owner.Pid)).Warn("锁文件中的进程不是本程序, 删除残留的锁文件")
+		if err = os.Remove(lockPath); err != nil {
+			return "", fmt.Errorf("删除残留的锁文件失败: %v", err)
 		}
+		err = lock.TryLock()
 	}
+	if errors.Is(err, lockfile.ErrBusy) {
+		return "", ErrAlreadyRunning
+	}
+	if err != nil {
+		return "", fmt.Errorf("获取锁失败: %v", err)
+	}
+	return lock, nil
+}
 
-	// 保存锁对象以便后续释放
-	appLock = lock
-	return nil
+// isSameProgram 指定进程是否为本程序, 无法判断时视为本程序
+func isSameProgram(pid int) bool {
+	exe, err := os.Executable()
+	if err != nil {
+		return true
+	}
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return true
+	}
+	// 管理员权限运行的进程可能无法读取路径, 改用进程名判断
+	name, err := p.Exe()
+	if err == nil {
+		name = filepath.Base(name)
+	} else if name, err = p.Name(); err != nil {
+		return true
+	}
+	return strings.EqualFold(name, filepath.Base(exe))
 }
 
 func CleanupLock() {
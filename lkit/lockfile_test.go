@@ -0,0 +1,31 @@
+package lkit
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAcquireLockStale(t *testing.T) {
+	lockPath := filepath.Join(t.TempDir(), ".tiktok.lock")
+
+	// 锁文件中的进程ID已被其他程序复用, 视为残留的锁
+	if err := os.WriteFile(lockPath, []byte("1\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	lock, err := acquireLock(lockPath)
+	if err != nil {
+		t.Fatalf("残留的锁应被清理: %v", err)
+	}
+	if owner, err := lock.GetOwner(); err != nil || owner.Pid != os.Getpid() {
+		t.Errorf("锁文件应属于当前进程: %v, %v", owner, err)
+	}
+
+	if !isSameProgram(os.Getpid()) {
+		t.Error("当前进程应被识别为本程序")
+	}
+	if _, err = acquireLock(filepath.Join(t.TempDir(), "missing", ".lock")); err == nil || errors.Is(err, ErrAlreadyRunning) {
+		t.Errorf("锁文件目录不存在时应返回获取锁失败: %v", err)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package lkit
+
+// hideFile 以点开头的文件本身就是隐藏文件, 无需处理
+func hideFile(string) error {
+	return nil
+}
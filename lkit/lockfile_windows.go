@@ -0,0 +1,12 @@
+package lkit
+
+import "syscall"
+
+// hideFile 设置文件的隐藏属性
+func hideFile(path string) error {
+	filenameW, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return err
+	}
+	return syscall.SetFileAttributes(filenameW, syscall.FILE_ATTRIBUTE_HIDDEN)
+}
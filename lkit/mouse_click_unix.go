@@ -0,0 +1,10 @@
+//go:build !windows
+
+package lkit
+
+import "fmt"
+
+// SimulateMouseClick 非Windows平台不支持模拟鼠标点击
+func SimulateMouseClick(x, y int, button string) error {
+	return fmt.Errorf("模拟鼠标%s点击失败(x=%d, y=%d): %w", button, x, y, ErrUnsupported)
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package lkit
+
+import "os/exec"
+
+// HideWindow 非Windows平台启动子进程不会弹出控制台窗口
+func HideWindow(*exec.Cmd) {}
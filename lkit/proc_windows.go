@@ -0,0 +1,11 @@
+package lkit
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// HideWindow 启动子进程时不显示控制台窗口
+func HideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
@@ -0,0 +1,394 @@
+package lkit
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tiktok_tool/llog"
+)
+
+const (
+	// ShutdownTimeout 程序退出时等待后台任务结束的最长时间
+	ShutdownTimeout = 5 * time.Second
+
+	// 任务重启前的默认等待时间
+	defaultRestartBackoff = time.Second
+)
+
+// taskLog 后台任务日志
+var taskLog = llog.Named("task")
+
+// RestartPolicy 任务结束后的重启策略
+type RestartPolicy int
+
+const (
+	// RestartNever 任务结束或崩溃后不重启
+	RestartNever RestartPolicy = iota
+	// RestartOnPanic 任务崩溃后重启, 正常结束时不重启
+	RestartOnPanic
+	// RestartAlways 任务结束或崩溃后都重启, 直到任务被取消
+	RestartAlways
+)
+
+func (p RestartPolicy) String() string {
+	switch p {
+	case RestartOnPanic:
+		return "崩溃后重启"
+	case RestartAlways:
+		return "总是重启"
+	default:
+		return "不重启"
+	}
+}
+
+// 任务状态
+const (
+	TaskRunning    = "运行中"
+	TaskRestarting = "等待重启"
+	TaskStopping   = "正在停止"
+)
+
+// TaskOptions 启动任务的选项
+type TaskOptions struct {
+	Restart     RestartPolicy
+	MaxRestarts int           // 最大重启次数, 0 为不限制
+	Backoff     time.Duration // 重启前的等待时间, 默认 1s
+}
+
+// TaskInfo 任务运行状态, 用于查看当前运行的后台任务
+type TaskInfo struct {
+	ID        uint64
+	Name      string
+	State     string
+	Policy    RestartPolicy
+	Started   time.Time
+	Restarts  int
+	Panics    int
+	LastPanic string
+}
+
+// Task 由 Supervisor 管理的后台任务
+type Task struct {
+	id      uint64
+	name    string
+	options TaskOptions
+	ctx     context.Context
+	cancel  context.CancelFunc
+	done    chan struct{}
+
+	mu        sync.Mutex
+	state     string
+	started   time.Time
+	restarts  int
+	panics    int
+	lastPanic string
+}
+
+// Name 任务名称
+func (t *Task) Name() string {
+	return t.name
+}
+
+// Cancel 取消任务, 任务函数需要检查 ctx 才能及时结束
+func (t *Task) Cancel() {
+	t.cancel()
+}
+
+// Done 任务结束(不再重启)时关闭
+func (t *Task) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *Task) info() TaskInfo {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	state := t.state
+	if t.ctx.Err() != nil {
+		state = TaskStopping
+	}
+	return TaskInfo{
+		ID:        t.id,
+		Name:      t.name,
+		State:     state,
+		Policy:    t.options.Restart,
+		Started:   t.started,
+		Restarts:  t.restarts,
+		Panics:    t.panics,
+		LastPanic: t.lastPanic,
+	}
+}
+
+// shutdownHook 程序退出时执行的清理函数, 例如关闭抓包句柄和OBS连接
+type shutdownHook struct {
+	id   uint64
+	name string
+	f    func()
+}
+
+// Supervisor 管理后台任务: 任务崩溃时记录崩溃报告并按策略重启, 退出时取消全部任务并等待结束
+type Supervisor struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex
+	nextID  uint64
+	tasks   map[uint64]*Task
+	hooks   []shutdownHook
+	closing bool
+}
+
+// NewSupervisor 创建任务管理器
+func NewSupervisor() *Supervisor {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Supervisor{
+		ctx:    ctx,
+		cancel: cancel,
+		tasks:  make(map[uint64]*Task),
+	}
+}
+
+// Go 启动不重启的任务
+func (s *Supervisor) Go(name string, f func(ctx context.Context)) *Task {
+	return s.GoWith(name, TaskOptions{}, f)
+}
+
+// GoWith 按选项启动任务, 任务崩溃时记录崩溃报告, 程序退出时任务的 ctx 会被取消
+// 程序正在退出时不再启动新任务, 返回已结束的任务
+func (s *Supervisor) GoWith(name string, options TaskOptions, f func(ctx context.Context)) *Task {
+	if options.Backoff <= 0 {
+		options.Backoff = defaultRestartBackoff
+	}
+	ctx, cancel := context.WithCancel(s.ctx)
+	t := &Task{
+		name:    name,
+		options: options,
+		ctx:     ctx,
+		cancel:  cancel,
+		done:    make(chan struct{}),
+		state:   TaskRunning,
+		started: time.Now(),
+	}
+
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		cancel()
+		close(t.done)
+		taskLog.Warn("程序正在退出, 不再启动任务", llog.String("task", name))
+		return t
+	}
+	s.nextID++
+	t.id = s.nextID
+	s.tasks[t.id] = t
+	s.wg.Add(1)
+	s.mu.Unlock()
+
+	go s.run(t, f)
+	return t
+}
+
+// run 运行任务, 按重启策略重启, 直到任务结束或被取消
+func (s *Supervisor) run(t *Task, f func(ctx context.Context)) {
+	defer func() {
+		t.cancel()
+		close(t.done)
+		s.mu.Lock()
+		delete(s.tasks, t.id)
+		s.mu.Unlock()
+		s.wg.Done()
+	}()
+
+	for {
+		panicked := t.runOnce(f)
+		if t.ctx.Err() != nil {
+			return
+		}
+
+		restart := t.options.Restart == RestartAlways || (t.options.Restart == RestartOnPanic && panicked)
+		if !restart {
+			return
+		}
+		t.mu.Lock()
+		if t.options.MaxRestarts > 0 && t.restarts >= t.options.MaxRestarts {
+			t.mu.Unlock()
+			taskLog.Warn("任务重启次数已达上限, 不再重启", llog.String("task", t.name), llog.Int("restarts", t.options.MaxRestarts))
+			return
+		}
+		t.state = TaskRestarting
+		t.mu.Unlock()
+
+		taskLog.Info("任务已结束, 等待重启", llog.String("task", t.name), llog.Bool("panic", panicked), llog.Duration("backoff", t.options.Backoff))
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-time.After(t.options.Backoff):
+		}
+
+		t.mu.Lock()
+		t.restarts++
+		t.state = TaskRunning
+		t.mu.Unlock()
+	}
+}
+
+// runOnce 运行一次任务函数, 崩溃时记录日志和崩溃报告, 返回是否崩溃
+func (t *Task) runOnce(f func(ctx context.Context)) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			t.mu.Lock()
+			t.panics++
+			t.lastPanic = fmt.Sprint(r)
+			t.mu.Unlock()
+			recoverGoroutine(t.name, r)
+		}
+	}()
+	f(t.ctx)
+	return false
+}
+
+// Tasks 当前运行的任务, 按启动顺序排列
+func (s *Supervisor) Tasks() []TaskInfo {
+	s.mu.Lock()
+	tasks := make([]*Task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		tasks = append(tasks, t)
+	}
+	s.mu.Unlock()
+
+	infos := make([]TaskInfo, 0, len(tasks))
+	for _, t := range tasks {
+		infos = append(infos, t.info())
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ID < infos[j].ID
+	})
+	return infos
+}
+
+// Done 程序开始退出时关闭
+func (s *Supervisor) Done() <-chan struct{} {
+	return s.ctx.Done()
+}
+
+// OnShutdown 注册程序退出时执行的清理函数, 按注册的相反顺序执行
+// 返回的函数用于在资源已释放时取消注册
+func (s *Supervisor) OnShutdown(name string, f func()) (remove func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	id := s.nextID
+	s.hooks = append(s.hooks, shutdownHook{id: id, name: name, f: f})
+
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		for i, hook := range s.hooks {
+			if hook.id == id {
+				s.hooks = append(s.hooks[:i], s.hooks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Shutdown 取消全部任务, 执行清理函数并等待任务结束, 超时后返回仍在运行的任务
+func (s *Supervisor) Shutdown(timeout time.Duration) error {
+	s.mu.Lock()
+	if s.closing {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closing = true
+	hooks := s.hooks
+	s.hooks = nil
+	count := len(s.tasks)
+	s.mu.Unlock()
+
+	taskLog.Info("正在停止后台任务", llog.Int("tasks", count), llog.Int("hooks", len(hooks)))
+	s.cancel()
+
+	// 清理函数可能阻塞, 和等待任务结束一起受超时限制
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		for i := len(hooks) - 1; i >= 0; i-- {
+			runShutdownHook(hooks[i])
+		}
+		s.wg.Wait()
+	}()
+
+	select {
+	case <-drained:
+		taskLog.Info("后台任务已全部停止")
+		return nil
+	case <-time.After(timeout):
+		var names []string
+		for _, info := range s.Tasks() {
+			names = append(names, info.Name)
+		}
+		return fmt.Errorf("等待后台任务结束超时(%v), 仍在运行: %s", timeout, strings.Join(names, ", "))
+	}
+}
+
+// runShutdownHook 执行清理函数, 崩溃时只记录不影响其他清理函数
+func runShutdownHook(hook shutdownHook) {
+	defer func() {
+		if r := recover(); r != nil {
+			recoverGoroutine("shutdown."+hook.name, r)
+		}
+	}()
+	taskLog.Debug("执行退出清理", llog.String("hook", hook.name))
+	hook.f()
+}
+
+// supervisor 程序的任务管理器
+var supervisor = NewSupervisor()
+
+// Go 启动不重启的后台任务
+// 用法: Go("capture.device", func(ctx context.Context) { ... })
+func Go(name string, f func(ctx context.Context)) *Task {
+	return supervisor.Go(name, f)
+}
+
+// GoWith 按选项启动后台任务
+func GoWith(name string, options TaskOptions, f func(ctx context.Context)) *Task {
+	return supervisor.GoWith(name, options, f)
+}
+
+// Tasks 当前运行的后台任务
+func Tasks() []TaskInfo {
+	return supervisor.Tasks()
+}
+
+// ShuttingDown 程序开始退出时关闭
+func ShuttingDown() <-chan struct{} {
+	return supervisor.Done()
+}
+
+// OnShutdown 注册程序退出时执行的清理函数
+func OnShutdown(name string, f func()) (remove func()) {
+	return supervisor.OnShutdown(name, f)
+}
+
+// Shutdown 程序退出时停止全部后台任务
+func Shutdown(timeout time.Duration) error {
+	return supervisor.Shutdown(timeout)
+}
+
+// Sleep 等待指定时间, ctx 取消时提前返回 ctx 的错误
+func Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,73 @@
+package lkit
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSupervisor(t *testing.T) {
+	crashDir = t.TempDir()
+	s := NewSupervisor()
+
+	// 崩溃后按策略重启, 达到上限后结束
+	var runs atomic.Int32
+	task := s.GoWith("panic", TaskOptions{Restart: RestartOnPanic, MaxRestarts: 2, Backoff: time.Millisecond}, func(context.Context) {
+		runs.Add(1)
+		panic("boom")
+	})
+	select {
+	case <-task.Done():
+	case <-time.After(time.Second):
+		t.Fatal("任务没有结束")
+	}
+	if n := runs.Load(); n != 3 {
+		t.Errorf("运行次数 = %d, 期望 3", n)
+	}
+	if reports := CrashReports(); len(reports) != 3 {
+		t.Errorf("崩溃报告数量 = %d, 期望 3", len(reports))
+	}
+
+	// 退出时取消任务并执行清理函数
+	started := make(chan struct{})
+	s.Go("loop", func(ctx context.Context) {
+		close(started)
+		<-ctx.Done()
+	})
+	<-started
+	if tasks := s.Tasks(); len(tasks) != 1 || tasks[0].Name != "loop" || tasks[0].State != TaskRunning {
+		t.Fatalf("运行中的任务不正确: %+v", tasks)
+	}
+	var hooked, removed bool
+	s.OnShutdown("hook", func() { hooked = true })
+	remove := s.OnShutdown("removed", func() { removed = true })
+	remove()
+
+	if err := s.Shutdown(time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if !hooked || removed {
+		t.Errorf("清理函数执行不正确: hooked=%v removed=%v", hooked, removed)
+	}
+	if tasks := s.Tasks(); len(tasks) != 0 {
+		t.Errorf("退出后仍有任务: %+v", tasks)
+	}
+	select {
+	case <-s.Go("late", func(context.Context) {}).Done():
+	default:
+		t.Error("退出后不应启动新任务")
+	}
+}
+
+func TestSupervisorShutdownTimeout(t *testing.T) {
+	s := NewSupervisor()
+	block := make(chan struct{})
+	defer close(block)
+	s.Go("stuck", func(context.Context) {
+		<-block
+	})
+	if err := s.Shutdown(10 * time.Millisecond); err == nil {
+		t.Fatal("任务未结束时应返回超时错误")
+	}
+}
@@ -0,0 +1,66 @@
+//go:build !windows
+
+package lkit
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"tiktok_tool/llog"
+)
+
+const (
+	capNetAdmin = 12 // CAP_NET_ADMIN
+	capNetRaw   = 13 // CAP_NET_RAW
+)
+
+var adminLog = llog.Named("admin")
+
+// IsRunAsAdmin 检测当前程序是否具有抓包所需的权限
+// root 用户或拥有 CAP_NET_RAW 能力(例如 setcap cap_net_raw+ep)时返回true
+func IsRunAsAdmin() bool {
+	if os.Geteuid() == 0 {
+		adminLog.Info("当前程序以root身份运行")
+		return true
+	}
+
+	caps, err := effectiveCapabilities()
+	if err != nil {
+		adminLog.Warn("读取当前程序的能力失败", llog.Err(err))
+		return false
+	}
+	hasCap := caps&(1<<capNetRaw) != 0 || caps&(1<<capNetAdmin) != 0
+	adminLog.Info("当前程序的抓包权限(CAP_NET_RAW)", llog.Bool("has_cap", hasCap))
+
+	return hasCap
+}
+
+// effectiveCapabilities 读取 /proc/self/status 中的 CapEff
+func effectiveCapabilities() (uint64, error) {
+	file, err := os.Open("/proc/self/status")
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		value, found := strings.CutPrefix(scanner.Text(), "CapEff:")
+		if !found {
+			continue
+		}
+		return strconv.ParseUint(strings.TrimSpace(value), 16, 64)
+	}
+	return 0, fmt.Errorf("未找到CapEff")
+}
+
+// BringWindowToFront 非Windows平台不依赖X11/wmctrl, 不支持置顶其他程序的窗口
+func BringWindowToFront(windowTitle string) (bool, error) {
+	if windowTitle == "" {
+		return false, fmt.Errorf("窗口标题不能为空")
+	}
+	return false, fmt.Errorf("置顶窗口 '%s' 失败: %w", windowTitle, ErrUnsupported)
+}
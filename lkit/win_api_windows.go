@@ -11,8 +11,6 @@ import (
 	"tiktok_tool/llog"
 )
 
-var IsAdmin bool
-
 var (
 	user32                  = windows.NewLazySystemDLL("user32.dll")
 	procEnumWindows         = user32.NewProc("EnumWindows")
@@ -29,10 +27,6 @@ const (
 	SW_RESTORE = 9
 )
 
-func init() {
-	IsAdmin = IsRunAsAdmin()
-}
-
 // IsRunAsAdmin 检测当前程序是否以管理员身份运行
 // 返回值：true表示以管理员身份运行，false表示普通用户权限
 func IsRunAsAdmin() bool {
@@ -71,7 +65,7 @@ func IsRunAsAdmin() bool {
 		return false
 	}
 
-	llog.Info("当前程序以管理员身份运行: %v", elevation != 0)
+	llog.InfoF("当前程序以管理员身份运行: %v", elevation != 0)
 
 	return elevation != 0
 }
@@ -127,7 +121,7 @@ func BringWindowToFront(windowTitle string) (bool, error) {
 		return false, fmt.Errorf("设置窗口到前台失败: %v (窗口: %s)", err, foundTitle)
 	}
 
-	llog.Debug("窗口 '%s' 已经被置顶", foundTitle)
+	llog.DebugF("窗口 '%s' 已经被置顶", foundTitle)
 
 	return true, nil
 }
@@ -39,4 +39,5 @@ var DefaultConfig = &LogSetting{
 	Format:       "%s.log",
 	Level:        "debug",
 	OutputFormat: "text",
+	RemoteLevel:  "warn",
 }
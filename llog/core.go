@@ -0,0 +1,66 @@
+package llog
+
+import (
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// coreHolder 包装 zapcore.Core 以便存入 atomic.Pointer
+type coreHolder struct {
+	core zapcore.Core
+}
+
+// dynamicCore 可在运行时替换底层输出的日志核心
+// With 派生的核心共享同一个底层核心, 替换后同样生效
+type dynamicCore struct {
+	current *atomic.Pointer[coreHolder]
+	fields  []zapcore.Field
+}
+
+func newDynamicCore(core zapcore.Core) *dynamicCore {
+	c := &dynamicCore{current: &atomic.Pointer[coreHolder]{}}
+	c.swap(core)
+	return c
+}
+
+// swap 替换底层核心
+func (c *dynamicCore) swap(core zapcore.Core) {
+	c.current.Store(&coreHolder{core: core})
+}
+
+// load 获取附加了字段的底层核心
+func (c *dynamicCore) load() zapcore.Core {
+	core := c.current.Load().core
+	if len(c.fields) > 0 {
+		core = core.With(c.fields)
+	}
+	return core
+}
+
+func (c *dynamicCore) Enabled(lvl zapcore.Level) bool {
+	return c.current.Load().core.Enabled(lvl)
+}
+
+func (c *dynamicCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &dynamicCore{current: c.current, fields: merged}
+}
+
+func (c *dynamicCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+// Write 写入时附加全局上下文字段, 如抓包会话ID
+func (c *dynamicCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.load().Write(entry, withContext(fields))
+}
+
+func (c *dynamicCore) Sync() error {
+	return c.current.Load().core.Sync()
+}
@@ -0,0 +1,180 @@
+package llog
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// CaptureSessionKey 抓包会话ID, 从开始抓包到停止抓包期间的日志都会带上该字段
+	CaptureSessionKey = "capture_session"
+	// RunIDKey 一键开播流程ID, 一键开播期间的日志都会带上该字段
+	RunIDKey = "run_id"
+)
+
+// Field 日志字段, JSON 格式输出时为单独的键, 可以按字段查询
+type Field = zap.Field
+
+// 常用的字段构造函数
+var (
+	String   = zap.String
+	Strings  = zap.Strings
+	Int      = zap.Int
+	Int64    = zap.Int64
+	Bool     = zap.Bool
+	Duration = zap.Duration
+	Any      = zap.Any
+	Err      = zap.Error
+	NamedErr = zap.NamedError
+)
+
+// generation 日志系统初始化或清理时递增, 模块日志器据此重新创建
+var generation atomic.Uint64
+
+// Logger 带名称和固定字段的日志器, 可以在日志系统初始化前创建
+type Logger struct {
+	name   string
+	fields []Field
+	cache  atomic.Pointer[cachedLogger]
+}
+
+type cachedLogger struct {
+	generation uint64
+	log        *zap.Logger
+}
+
+// Named 创建模块日志器, 名称会输出在 logger 字段中, 例如 capture, obs, auto, ui
+func Named(name string) *Logger {
+	return &Logger{name: name}
+}
+
+// With 创建带固定字段的日志器
+func With(fields ...Field) *Logger {
+	return &Logger{fields: fields}
+}
+
+// Named 创建子模块日志器, 名称为 父模块.子模块
+func (l *Logger) Named(name string) *Logger {
+	if l.name != "" {
+		name = l.name + "." + name
+	}
+	return &Logger{name: name, fields: l.fields}
+}
+
+// With 创建在当前字段基础上追加字段的日志器
+func (l *Logger) With(fields ...Field) *Logger {
+	merged := make([]Field, 0, len(l.fields)+len(fields))
+	merged = append(merged, l.fields...)
+	merged = append(merged, fields...)
+	return &Logger{name: l.name, fields: merged}
+}
+
+// zap 当前日志系统下的 zap 日志器
+func (l *Logger) zap() *zap.Logger {
+	current := generation.Load()
+	if cached := l.cache.Load(); cached != nil && cached.generation == current {
+		return cached.log
+	}
+	logger := log
+	if l.name != "" {
+		logger = logger.Named(l.name)
+	}
+	logger = logger.With(l.fields...)
+	l.cache.Store(&cachedLogger{generation: current, log: logger})
+	return logger
+}
+
+func (l *Logger) Debug(msg string, fields ...Field) {
+	l.zap().Debug(msg, fields...)
+}
+
+func (l *Logger) Info(msg string, fields ...Field) {
+	l.zap().Info(msg, fields...)
+}
+
+func (l *Logger) Warn(msg string, fields ...Field) {
+	l.zap().Warn(msg, fields...)
+}
+
+func (l *Logger) Error(msg string, fields ...Field) {
+	l.zap().Error(msg, fields...)
+}
+
+func (l *Logger) DebugF(format string, args ...interface{}) {
+	l.zap().Sugar().Debugf(format, args...)
+}
+
+func (l *Logger) InfoF(format string, args ...interface{}) {
+	l.zap().Sugar().Infof(format, args...)
+}
+
+func (l *Logger) WarnF(format string, args ...interface{}) {
+	l.zap().Sugar().Warnf(format, args...)
+}
+
+func (l *Logger) ErrorF(format string, args ...interface{}) {
+	l.zap().Sugar().Errorf(format, args...)
+}
+
+var (
+	contextMutex  sync.Mutex
+	contextValues = make(map[string]string)
+	contextFields atomic.Pointer[[]zapcore.Field]
+)
+
+// Bind 设置全局上下文字段, 之后所有的日志都会带上该字段, 直到调用返回的函数移除
+// 同一个键再次设置时覆盖原来的值
+func Bind(key, value string) (unbind func()) {
+	contextMutex.Lock()
+	contextValues[key] = value
+	storeContextFields()
+	contextMutex.Unlock()
+
+	return func() {
+		contextMutex.Lock()
+		defer contextMutex.Unlock()
+		if contextValues[key] == value {
+			delete(contextValues, key)
+			storeContextFields()
+		}
+	}
+}
+
+// storeContextFields 按键名排序后保存上下文字段, 调用方需持有 contextMutex
+func storeContextFields() {
+	keys := make([]string, 0, len(contextValues))
+	for key := range contextValues {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	fields := make([]zapcore.Field, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, zap.String(key, contextValues[key]))
+	}
+	contextFields.Store(&fields)
+}
+
+// withContext 在日志字段前加上全局上下文字段
+func withContext(fields []zapcore.Field) []zapcore.Field {
+	context := contextFields.Load()
+	if context == nil || len(*context) == 0 {
+		return fields
+	}
+	merged := make([]zapcore.Field, 0, len(*context)+len(fields))
+	merged = append(merged, *context...)
+	return append(merged, fields...)
+}
+
+// NewID 生成用于会话ID、流程ID的短随机ID
+func NewID() string {
+	id := make([]byte, 4)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
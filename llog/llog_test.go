@@ -0,0 +1,133 @@
+package llog
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRingCore(t *testing.T) {
+	buffer := &ringBuffer{records: make([]Record, 0, 3)}
+	logger := zap.New(newRingCore(zapcore.InfoLevel, buffer)).With(zap.String("profile", "默认"))
+
+	logger.Debug("忽略")
+	for i := 0; i < 5; i++ {
+		logger.Info("日志", zap.Int("index", i))
+	}
+
+	records := buffer.snapshot()
+	if len(records) != 3 || buffer.count() != 5 {
+		t.Fatalf("缓冲区日志数量错误: %d, 总数 %d", len(records), buffer.count())
+	}
+	for i, record := range records {
+		if record.Seq != uint64(i+3) {
+			t.Fatalf("日志顺序错误: %+v", records)
+		}
+	}
+	if got, want := records[2].Fields, "index=4 profile=默认"; got != want {
+		t.Fatalf("字段格式错误: %q, 期望 %q", got, want)
+	}
+
+	record := Record{Time: time.Date(2024, 1, 2, 3, 4, 5, 0, time.Local), Level: zapcore.WarnLevel, Message: "消息", Fields: "a=1"}
+	if got, want := record.String(), "2024-01-02 03:04:05.000\tWARN\t消息\ta=1"; got != want {
+		t.Fatalf("格式化错误: %q", got)
+	}
+}
+
+func TestRedact(t *testing.T) {
+	SetSecrets("test", "obs-password", "abc")
+	defer SetSecrets("test")
+
+	buffer := &ringBuffer{records: make([]Record, 0, 4)}
+	logger := zap.New(newRedactCore(newRingCore(zapcore.DebugLevel, buffer)))
+	logger.Sugar().Info("找到推流码字符串: stream-694123456789?expire=1700000000&sign=abcdef&volcSecret=xyz&volcTime=1")
+	logger.Info("连接OBS", zap.String("password", "obs-password"), zap.Error(errors.New("sign=abcdef")))
+	logger.Info("运行自动化工具", Strings("args", []string{"--password", "obs-password", "sign=abcdef"}))
+
+	records := buffer.snapshot()
+	if got, want := records[0].Message, "找到推流码字符串: stream-***6789?expire=1700000000&sign=***&volcSecret=***&volcTime=1"; got != want {
+		t.Fatalf("消息脱敏错误: %q", got)
+	}
+	if got, want := records[1].Fields, "error=sign=*** password=***"; got != want {
+		t.Fatalf("字段脱敏错误: %q", got)
+	}
+	if got, want := records[2].Fields, "args=[--password *** sign=***]"; got != want {
+		t.Fatalf("数组字段脱敏错误: %q", got)
+	}
+
+	unredacted.Store(true)
+	defer unredacted.Store(false)
+	logger.Info("sign=abcdef")
+	if got := buffer.snapshot()[3].Message; got != "sign=abcdef" {
+		t.Fatalf("关闭脱敏后仍然脱敏: %q", got)
+	}
+}
+
+func TestNamedLogger(t *testing.T) {
+	buffer := &ringBuffer{records: make([]Record, 0, 4)}
+	previous := log
+	defer func() {
+		log = previous
+		generation.Add(1)
+	}()
+	core := newDynamicCore(newRingCore(zapcore.DebugLevel, buffer))
+	logger := Named("capture").With(String("device", "eth0"))
+
+	// 日志系统初始化后, 之前创建的模块日志器使用新的日志核心
+	log = zap.New(core)
+	generation.Add(1)
+
+	unbind := Bind(RunIDKey, "r1")
+	logger.Info("开始抓包", Int("count", 2))
+	unbind()
+	logger.Named("ws").Warn("连接断开")
+
+	records := buffer.snapshot()
+	if len(records) != 2 {
+		t.Fatalf("日志数量错误: %+v", records)
+	}
+	if records[0].Logger != "capture" || records[0].Fields != "count=2 device=eth0 run_id=r1" {
+		t.Errorf("日志字段错误: %+v", records[0])
+	}
+	if records[1].Logger != "capture.ws" || records[1].Fields != "device=eth0" {
+		t.Errorf("子模块日志错误: %+v", records[1])
+	}
+}
+
+// sugarCall 包级别日志函数以逗号拼接多个参数的调用, 应改用模块日志器和类型化字段
+var sugarCall = regexp.MustCompile(`\bllog\.(Debug|Info|Warn|Error|Fatal)\("(?:[^"\\]|\\.)*",`)
+
+func TestNoSugarLogging(t *testing.T) {
+	err := filepath.WalkDir("..", func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && strings.HasPrefix(d.Name(), ".") && path != ".." {
+			return filepath.SkipDir
+		}
+		if d.IsDir() || !strings.HasSuffix(path, ".go") {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for i, line := range strings.Split(string(data), "\n") {
+			if sugarCall.MatchString(line) {
+				t.Errorf("%s:%d: 使用模块日志器和类型化字段代替拼接参数: %s", path, i+1, strings.TrimSpace(line))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
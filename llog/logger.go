@@ -2,21 +2,30 @@ package llog
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
-	"runtime/debug"
 	"strings"
 	"sync"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
+
+	"tiktok_tool/appdir"
 )
 
 var (
 	log   *zap.Logger
 	sugar *zap.SugaredLogger
 	once  sync.Once
+
+	// 以下变量用于运行时热更新日志配置
+	reconfigureMutex sync.Mutex
+	level            = zap.NewAtomicLevel()
+	root             = newDynamicCore(zapcore.NewNopCore())
+	outputs          []io.Closer // 重新配置或退出时需要关闭的输出, 如日志文件和远程日志
+	currentSetting   LogSetting
 )
 
 // 初始化一个空的logger，避免在未初始化前调用日志函数导致空指针异常
@@ -45,16 +54,122 @@ func initLogger(logConfig *LogSetting) error {
 		logConfig = DefaultConfig
 	}
 
-	// 创建日志目录
-	if logConfig.File {
-		logDir := filepath.Clean(logConfig.FilePath)
-		if err := os.MkdirAll(logDir, 0755); err != nil {
-			return fmt.Errorf("创建日志目录失败: %v", err)
+	reconfigureMutex.Lock()
+	err := applySetting(logConfig)
+	reconfigureMutex.Unlock()
+	if err != nil {
+		return err
+	}
+
+	// 创建logger, 核心可在运行时替换
+	log = zap.New(root,
+		zap.AddCaller(),
+		zap.AddCallerSkip(1),
+		zap.AddStacktrace(zapcore.ErrorLevel),
+	)
+
+	// 创建sugar logger
+	sugar = log.Sugar()
+	generation.Add(1)
+
+	_, err = zap.RedirectStdLogAt(log, zapcore.ErrorLevel)
+	if err != nil {
+		return fmt.Errorf("重定向标准日志失败: %v", zap.Error(err))
+	}
+
+	// 输出初始化信息
+	sugar.Info("日志系统初始化完成，日志级别:", strings.ToUpper(logConfig.Level))
+	warnUnredacted(logConfig)
+
+	return nil
+}
+
+// Reconfigure 运行时更新日志配置
+// 只修改日志级别时直接调整级别, 输出方式变化时重新创建日志核心
+func Reconfigure(logConfig *LogSetting) error {
+	if logConfig == nil {
+		logConfig = DefaultConfig
+	}
+
+	reconfigureMutex.Lock()
+	defer reconfigureMutex.Unlock()
+
+	if *logConfig == currentSetting {
+		return nil
+	}
+	if err := applySetting(logConfig); err != nil {
+		return err
+	}
+
+	sugar.Info("日志配置已更新，日志级别:", strings.ToUpper(logConfig.Level))
+	warnUnredacted(logConfig)
+	return nil
+}
+
+// warnUnredacted 关闭脱敏时在日志中提示
+func warnUnredacted(logConfig *LogSetting) {
+	if logConfig.Unredacted {
+		sugar.Warn("已关闭日志脱敏, 日志中包含推流码等敏感信息, 请勿将日志发送给他人")
+	}
+}
+
+// applySetting 应用日志配置, 调用方需持有 reconfigureMutex
+func applySetting(logConfig *LogSetting) error {
+	level.SetLevel(getLogLevel(logConfig.Level))
+	unredacted.Store(logConfig.Unredacted)
+
+	onlyLevelChanged := currentSetting != LogSetting{}
+	if onlyLevelChanged {
+		previous := currentSetting
+		previous.Level = logConfig.Level
+		previous.Unredacted = logConfig.Unredacted
+		onlyLevelChanged = previous == *logConfig
+	}
+	if onlyLevelChanged {
+		currentSetting = *logConfig
+		return nil
+	}
+
+	core, closers, err := buildCore(logConfig)
+	if err != nil {
+		return err
+	}
+
+	root.swap(core)
+	closeOutputs()
+	outputs = closers
+	for _, output := range outputs {
+		if shipper, ok := output.(*shipper); ok {
+			remoteMutex.Lock()
+			currentRemote = shipper
+			remoteMutex.Unlock()
 		}
 	}
+	currentSetting = *logConfig
+	return nil
+}
 
-	// 设置日志级别
-	logLevel := getLogLevel(logConfig.Level)
+// closeOutputs 关闭当前的输出, 调用方需持有 reconfigureMutex
+func closeOutputs() {
+	for _, output := range outputs {
+		_ = output.Close()
+	}
+	outputs = nil
+
+	remoteMutex.Lock()
+	currentRemote = nil
+	remoteMutex.Unlock()
+}
+
+// buildCore 根据配置创建日志核心, 同时返回需要在替换核心后关闭的输出
+func buildCore(logConfig *LogSetting) (zapcore.Core, []io.Closer, error) {
+	// 创建日志目录, 相对路径位于数据根目录下
+	logDir := appdir.Resolve(filepath.Clean(logConfig.FilePath))
+	if logConfig.File || logConfig.RemoteURL != "" {
+		if err := os.MkdirAll(logDir, 0755); err != nil {
+			return nil, nil, fmt.Errorf("创建日志目录失败: %v", err)
+		}
+	}
 
 	// 创建编码器配置
 	encoderConfig := zapcore.EncoderConfig{
@@ -78,14 +193,15 @@ func initLogger(logConfig *LogSetting) error {
 	// 添加控制台输出
 	if logConfig.Console {
 		consoleEncoder := zapcore.NewConsoleEncoder(encoderConfig)
-		cores = append(cores, zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), logLevel))
+		cores = append(cores, zapcore.NewCore(consoleEncoder, zapcore.AddSync(os.Stdout), level))
 	}
 
 	// 添加文件输出
+	var closers []io.Closer
 	if logConfig.File {
 		// 使用当前日期作为日志文件名
 		fileName := fmt.Sprintf(logConfig.Format, "tiktok_tool")
-		path := filepath.Join(logConfig.FilePath, fileName)
+		path := filepath.Join(logDir, fileName)
 
 		var fileEncoder zapcore.Encoder
 		if logConfig.OutputFormat != "text" {
@@ -94,39 +210,50 @@ func initLogger(logConfig *LogSetting) error {
 			fileEncoder = zapcore.NewConsoleEncoder(encoderConfig)
 		}
 
-		writer := zapcore.AddSync(&lumberjack.Logger{
+		writer := &lumberjack.Logger{
 			Filename:   filepath.Clean(path),
 			MaxSize:    logConfig.MaxSize,
 			MaxBackups: logConfig.MaxBackups,
 			MaxAge:     logConfig.MaxAge,
 			Compress:   logConfig.Compress,
 			LocalTime:  logConfig.LocalTime,
+		}
+		cores = append(cores, zapcore.NewCore(fileEncoder, zapcore.AddSync(writer), level))
+		closers = append(closers, writer)
+	}
+
+	// 添加远程输出, 网络不可用时暂存在日志目录下
+	if logConfig.RemoteURL != "" {
+		sender, err := newRemoteSender(logConfig.RemoteURL)
+		if err != nil {
+			return nil, nil, err
+		}
+		remoteLevel := getLogLevel(logConfig.RemoteLevel)
+		enabler := zap.LevelEnablerFunc(func(lvl zapcore.Level) bool {
+			return lvl >= remoteLevel && level.Enabled(lvl)
 		})
-		cores = append(cores, zapcore.NewCore(fileEncoder, writer, logLevel))
+		shipper := newShipper(sender, filepath.Join(logDir, remoteSpoolName))
+		cores = append(cores, newRemoteCore(enabler, shipper))
+		closers = append(closers, shipper)
 	}
 
-	// 创建核心
-	core := zapcore.NewTee(cores...)
+	// 添加内存输出, 供程序内查看日志, 关闭控制台和文件输出时同样可用
+	cores = append(cores, newRingCore(level, ring))
 
-	// 创建logger
-	log = zap.New(core,
-		zap.AddCaller(),
-		zap.AddCallerSkip(1),
-		zap.AddStacktrace(zapcore.ErrorLevel),
-	)
+	// 创建核心, 写入任何输出前先脱敏
+	return newRedactCore(zapcore.NewTee(cores...)), closers, nil
+}
 
-	// 创建sugar logger
-	sugar = log.Sugar()
+// LogDir 当前日志文件所在目录
+func LogDir() string {
+	reconfigureMutex.Lock()
+	setting := currentSetting
+	reconfigureMutex.Unlock()
 
-	_, err := zap.RedirectStdLogAt(log, zapcore.ErrorLevel)
-	if err != nil {
-		return fmt.Errorf("重定向标准日志失败: %v", zap.Error(err))
+	if setting == (LogSetting{}) {
+		setting = *DefaultConfig
 	}
-
-	// 输出初始化信息
-	sugar.Info("日志系统初始化完成，日志级别:", strings.ToUpper(logConfig.Level))
-
-	return nil
+	return appdir.Resolve(filepath.Clean(setting.FilePath))
 }
 
 // FormatError 格式化错误信息，去除重复
@@ -212,15 +339,15 @@ func Cleanup() {
 		Sync()
 	}
 
+	// 关闭日志文件, 发送剩余的远程日志
+	reconfigureMutex.Lock()
+	root.swap(zapcore.NewNopCore())
+	closeOutputs()
+	reconfigureMutex.Unlock()
+
 	// 重置为nopLogger
 	nopCore := zapcore.NewNopCore()
 	log = zap.New(nopCore)
 	sugar = log.Sugar()
-}
-
-// HandlePanic 处理panic并记录日志
-func HandlePanic() {
-	if r := recover(); r != nil {
-		Fatal(fmt.Sprintf("程序发生严重错误: %v\n堆栈信息:\n%s", r, debug.Stack()))
-	}
+	generation.Add(1)
 }
@@ -0,0 +1,166 @@
+package llog
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// RedactMask 脱敏后替换敏感内容的文本
+const RedactMask = "***"
+
+// 短于该长度的配置密钥不参与脱敏, 避免误替换日志中的普通文本
+const minSecretLength = 4
+
+var (
+	// 推流地址中的签名参数, 泄露后可以被他人用来推流
+	signPattern = regexp.MustCompile(`(?i)\b(sign|volcSecret|token)=[^&\s"'\x00]+`)
+	// 推流码中的直播流ID, 保留最后4位用于区分
+	streamIDPattern = regexp.MustCompile(`\bstream-\d*(\d{4})\b`)
+
+	// unredacted 为true时不脱敏, 仅用于调试
+	unredacted atomic.Bool
+
+	secretsMutex sync.Mutex
+	secretGroups = make(map[string][]string)
+	secretsRef   atomic.Pointer[strings.Replacer]
+)
+
+// IsUnredacted 日志是否未脱敏
+func IsUnredacted() bool {
+	return unredacted.Load()
+}
+
+// SetSecrets 设置需要在日志中脱敏的文本, 同一分组再次设置时替换原来的文本
+// 例如配置中的密码, 抓包得到的推流码
+func SetSecrets(group string, values ...string) {
+	secretsMutex.Lock()
+	defer secretsMutex.Unlock()
+
+	secretGroups[group] = values
+
+	var all []string
+	for _, group := range secretGroups {
+		for _, value := range group {
+			if len(value) >= minSecretLength {
+				all = append(all, value)
+			}
+		}
+	}
+	if len(all) == 0 {
+		secretsRef.Store(nil)
+		return
+	}
+
+	// 先替换较长的文本, 避免其中包含的较短文本先被替换
+	sort.Slice(all, func(i, j int) bool {
+		return len(all[i]) > len(all[j])
+	})
+	pairs := make([]string, 0, len(all)*2)
+	for _, value := range all {
+		pairs = append(pairs, value, RedactMask)
+	}
+	secretsRef.Store(strings.NewReplacer(pairs...))
+}
+
+// Redact 隐藏文本中的推流签名、直播流ID和已设置的敏感文本
+// 不受不脱敏调试开关影响, 可用于导出诊断包等需要强制脱敏的场景
+func Redact(text string) string {
+	if text == "" {
+		return text
+	}
+	if replacer := secretsRef.Load(); replacer != nil {
+		text = replacer.Replace(text)
+	}
+	text = signPattern.ReplaceAllString(text, "${1}="+RedactMask)
+	return streamIDPattern.ReplaceAllString(text, "stream-"+RedactMask+"${1}")
+}
+
+// redactFields 脱敏字符串类型的字段, 数组逐个元素脱敏, 错误和其他类型的字段格式化为字符串后脱敏
+func redactFields(fields []zapcore.Field) []zapcore.Field {
+	if len(fields) == 0 || unredacted.Load() {
+		return fields
+	}
+	redacted := make([]zapcore.Field, len(fields))
+	for i, field := range fields {
+		switch field.Type {
+		case zapcore.StringType:
+			field.String = Redact(field.String)
+		case zapcore.ByteStringType:
+			field = zap.String(field.Key, Redact(string(field.Interface.([]byte))))
+		case zapcore.ErrorType, zapcore.StringerType, zapcore.ReflectType:
+			field = zap.String(field.Key, Redact(fmt.Sprint(field.Interface)))
+		case zapcore.ArrayMarshalerType:
+			encoder := zapcore.NewMapObjectEncoder()
+			field.AddTo(encoder)
+			values, _ := encoder.Fields[field.Key].([]interface{})
+			field = zap.Array(field.Key, redactedArray(values))
+		}
+		redacted[i] = field
+	}
+	return redacted
+}
+
+// redactedArray 逐个元素脱敏的数组字段, 嵌套的对象格式化为字符串后脱敏
+type redactedArray []interface{}
+
+func (a redactedArray) MarshalLogArray(encoder zapcore.ArrayEncoder) error {
+	for _, value := range a {
+		switch v := value.(type) {
+		case string:
+			encoder.AppendString(Redact(v))
+		case []interface{}:
+			if err := encoder.AppendArray(redactedArray(v)); err != nil {
+				return err
+			}
+		case map[string]interface{}:
+			encoder.AppendString(Redact(fmt.Sprint(v)))
+		default:
+			if err := encoder.AppendReflected(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// redactCore 写入前对日志内容脱敏的日志核心
+type redactCore struct {
+	core zapcore.Core
+}
+
+func newRedactCore(core zapcore.Core) zapcore.Core {
+	return &redactCore{core: core}
+}
+
+func (c *redactCore) Enabled(lvl zapcore.Level) bool {
+	return c.core.Enabled(lvl)
+}
+
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{core: c.core.With(redactFields(fields))}
+}
+
+func (c *redactCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *redactCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if !unredacted.Load() {
+		entry.Message = Redact(entry.Message)
+	}
+	return c.core.Write(entry, redactFields(fields))
+}
+
+func (c *redactCore) Sync() error {
+	return c.core.Sync()
+}
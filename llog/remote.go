@@ -0,0 +1,436 @@
+package llog
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// RemoteQueueSize 等待发送的日志数量上限, 队列已满时丢弃新日志
+	RemoteQueueSize = 1000
+	// RemoteSpoolSize 网络不可用时暂存到磁盘的日志文件大小上限
+	RemoteSpoolSize = 10 << 20
+
+	remoteBatchSize   = 100
+	remoteMaxBackoff  = time.Minute
+	remoteSendTimeout = 10 * time.Second
+	remoteCloseWait   = 3 * time.Second
+	remoteSpoolName   = "remote_spool.jsonl"
+)
+
+// 以下变量在测试中会被修改
+var (
+	remoteFlushInterval = 2 * time.Second
+	remoteMinBackoff    = time.Second
+)
+
+// RemoteStatus 远程日志发送状态
+type RemoteStatus struct {
+	Sent    uint64 // 已发送的日志数量
+	Dropped uint64 // 队列或暂存文件已满时丢弃的日志数量
+	Spooled int64  // 暂存文件大小
+	LastErr string // 最近一次发送失败的原因, 发送成功后清空
+}
+
+// remoteSender 将一批日志发送到日志收集服务
+type remoteSender interface {
+	send(entries []LogEntry) error
+	Close() error
+}
+
+// newRemoteSender 根据地址创建发送器, 支持 http(s):// 和 udp:// (syslog)
+func newRemoteSender(address string) (remoteSender, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("远程日志地址格式错误: %v", err)
+	}
+	switch u.Scheme {
+	case "http", "https":
+		return &httpSender{url: address, client: &http.Client{Timeout: remoteSendTimeout}}, nil
+	case "udp", "syslog":
+		if u.Host == "" {
+			return nil, fmt.Errorf("远程日志地址缺少主机: %s", address)
+		}
+		return &syslogSender{address: u.Host}, nil
+	default:
+		return nil, fmt.Errorf("不支持的远程日志地址: %s, 需为 http(s):// 或 udp://", address)
+	}
+}
+
+// httpSender 以JSON数组的形式 POST 到 HTTP 接口
+type httpSender struct {
+	url    string
+	client *http.Client
+}
+
+func (s *httpSender) send(entries []LogEntry) error {
+	body, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	resp, err := s.client.Post(s.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("服务器返回 %s", resp.Status)
+	}
+	return nil
+}
+
+func (s *httpSender) Close() error {
+	s.client.CloseIdleConnections()
+	return nil
+}
+
+// syslogSender 以 RFC 5424 格式通过 UDP 发送到 syslog 收集服务, 每条日志一个数据包
+type syslogSender struct {
+	address string
+	conn    net.Conn
+}
+
+// syslog 严重程度, 设施使用 user(1)
+var syslogSeverity = map[string]int{
+	"DEBUG": 7,
+	"INFO":  6,
+	"WARN":  4,
+	"ERROR": 3,
+}
+
+func (s *syslogSender) send(entries []LogEntry) error {
+	if s.conn == nil {
+		conn, err := net.DialTimeout("udp", s.address, remoteSendTimeout)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+	for _, entry := range entries {
+		severity, ok := syslogSeverity[entry.Level]
+		if !ok {
+			severity = 2
+		}
+		line := fmt.Sprintf("<%d>1 %s %s tiktok_tool - - - %s", 8+severity,
+			time.Unix(entry.Time, 0).Format(time.RFC3339), entry.Host, entry.Message)
+		if _, err := s.conn.Write([]byte(line)); err != nil {
+			_ = s.conn.Close()
+			s.conn = nil
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *syslogSender) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// shipper 批量发送日志, 发送失败时按指数退避重试, 期间的日志暂存到磁盘
+type shipper struct {
+	sender    remoteSender
+	spoolPath string
+	host      string
+	queue     chan LogEntry
+	done      chan struct{}
+
+	// 替换日志核心时可能仍有协程在写入旧核心, 关闭队列前需要等待写入完成
+	closeMutex sync.RWMutex
+	closed     bool
+
+	// 以下字段只在发送协程中使用
+	backoff time.Duration
+	retryAt time.Time
+
+	sent    atomic.Uint64
+	dropped atomic.Uint64
+	lastErr atomic.Pointer[string]
+}
+
+func newShipper(sender remoteSender, spoolPath string) *shipper {
+	host, _ := os.Hostname()
+	s := &shipper{
+		sender:    sender,
+		spoolPath: spoolPath,
+		host:      host,
+		queue:     make(chan LogEntry, RemoteQueueSize),
+		done:      make(chan struct{}),
+	}
+	// 这里不能使用 lkit.Go, lkit 依赖 llog
+	go s.run()
+	return s
+}
+
+// enqueue 将日志加入发送队列, 不会阻塞写日志的协程
+func (s *shipper) enqueue(entry LogEntry) {
+	s.closeMutex.RLock()
+	defer s.closeMutex.RUnlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.queue <- entry:
+	default:
+		s.dropped.Add(1)
+	}
+}
+
+func (s *shipper) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(remoteFlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]LogEntry, 0, remoteBatchSize)
+	for {
+		select {
+		case entry, ok := <-s.queue:
+			if !ok {
+				s.flush(batch)
+				return
+			}
+			batch = append(batch, entry)
+			if len(batch) >= remoteBatchSize {
+				s.flush(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			s.flush(batch)
+			batch = batch[:0]
+		}
+	}
+}
+
+// flush 先发送暂存的日志再发送当前批次, 等待重试期间或发送失败时写入暂存文件
+func (s *shipper) flush(batch []LogEntry) {
+	if time.Now().Before(s.retryAt) {
+		s.spool(batch)
+		return
+	}
+	if err := s.sendSpool(); err != nil {
+		s.fail(err)
+		s.spool(batch)
+		return
+	}
+	if len(batch) == 0 {
+		return
+	}
+	if err := s.sender.send(batch); err != nil {
+		s.fail(err)
+		s.spool(batch)
+		return
+	}
+	s.succeed(len(batch))
+}
+
+func (s *shipper) fail(err error) {
+	s.backoff = min(max(s.backoff*2, remoteMinBackoff), remoteMaxBackoff)
+	s.retryAt = time.Now().Add(s.backoff)
+	message := err.Error()
+	s.lastErr.Store(&message)
+}
+
+func (s *shipper) succeed(count int) {
+	s.backoff = 0
+	s.sent.Add(uint64(count))
+	s.lastErr.Store(nil)
+}
+
+// spool 将日志追加到暂存文件, 超过大小上限时丢弃
+func (s *shipper) spool(batch []LogEntry) {
+	if len(batch) == 0 {
+		return
+	}
+	if info, err := os.Stat(s.spoolPath); err == nil && info.Size() >= RemoteSpoolSize {
+		s.dropped.Add(uint64(len(batch)))
+		return
+	}
+
+	file, err := os.OpenFile(s.spoolPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		s.dropped.Add(uint64(len(batch)))
+		return
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for i, entry := range batch {
+		if err = encoder.Encode(entry); err != nil {
+			s.dropped.Add(uint64(len(batch) - i))
+			return
+		}
+	}
+}
+
+// sendSpool 按顺序发送暂存的日志, 发送失败时保留未发送的部分
+func (s *shipper) sendSpool() error {
+	entries, err := readSpool(s.spoolPath)
+	if err != nil || len(entries) == 0 {
+		return err
+	}
+
+	for len(entries) > 0 {
+		n := min(len(entries), remoteBatchSize)
+		if err = s.sender.send(entries[:n]); err != nil {
+			if writeErr := writeSpool(s.spoolPath, entries); writeErr != nil {
+				s.dropped.Add(uint64(len(entries)))
+			}
+			return err
+		}
+		s.succeed(n)
+		entries = entries[n:]
+	}
+	return os.Remove(s.spoolPath)
+}
+
+// readSpool 读取暂存文件中的日志, 文件不存在时返回空, 无法解析的行会被跳过
+func readSpool(path string) ([]LogEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []LogEntry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), RemoteSpoolSize)
+	for scanner.Scan() {
+		var entry LogEntry
+		if json.Unmarshal(scanner.Bytes(), &entry) == nil {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// writeSpool 用剩余的日志覆盖暂存文件
+func writeSpool(path string, entries []LogEntry) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return err
+		}
+	}
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// status 当前发送状态
+func (s *shipper) status() RemoteStatus {
+	status := RemoteStatus{Sent: s.sent.Load(), Dropped: s.dropped.Load()}
+	if info, err := os.Stat(s.spoolPath); err == nil {
+		status.Spooled = info.Size()
+	}
+	if message := s.lastErr.Load(); message != nil {
+		status.LastErr = *message
+	}
+	return status
+}
+
+// Close 停止接收日志并尝试发送剩余的日志, 无法发送的日志保留在暂存文件中, 下次启动后发送
+func (s *shipper) Close() error {
+	s.closeMutex.Lock()
+	if s.closed {
+		s.closeMutex.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.queue)
+	s.closeMutex.Unlock()
+
+	select {
+	case <-s.done:
+	case <-time.After(remoteCloseWait):
+	}
+	return s.sender.Close()
+}
+
+// remoteCore 将日志加入远程发送队列的日志核心
+type remoteCore struct {
+	zapcore.LevelEnabler
+	shipper *shipper
+	fields  []zapcore.Field
+}
+
+func newRemoteCore(enabler zapcore.LevelEnabler, shipper *shipper) *remoteCore {
+	return &remoteCore{LevelEnabler: enabler, shipper: shipper}
+}
+
+func (c *remoteCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &remoteCore{LevelEnabler: c.LevelEnabler, shipper: c.shipper, fields: merged}
+}
+
+func (c *remoteCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *remoteCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	// 多个核心组合后写入时不会逐个检查级别, 远程日志的级别可能高于其他输出
+	if !c.Enabled(entry.Level) {
+		return nil
+	}
+	message := entry.Message
+	if extra := encodeFields(c.fields, fields); extra != "" {
+		message += " " + extra
+	}
+	if entry.Caller.Defined {
+		message = entry.Caller.TrimmedPath() + " " + message
+	}
+	if entry.LoggerName != "" {
+		message = "[" + entry.LoggerName + "] " + message
+	}
+	c.shipper.enqueue(LogEntry{
+		Time:    entry.Time.Unix(),
+		Level:   strings.ToUpper(entry.Level.String()),
+		Message: message,
+		Host:    c.shipper.host,
+	})
+	return nil
+}
+
+func (c *remoteCore) Sync() error {
+	return nil
+}
+
+var (
+	remoteMutex   sync.Mutex
+	currentRemote *shipper
+)
+
+// GetRemoteStatus 远程日志发送状态, 未开启远程日志时返回 false
+func GetRemoteStatus() (RemoteStatus, bool) {
+	remoteMutex.Lock()
+	defer remoteMutex.Unlock()
+	if currentRemote == nil {
+		return RemoteStatus{}, false
+	}
+	return currentRemote.status(), true
+}
@@ -0,0 +1,98 @@
+package llog
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRemoteShipping(t *testing.T) {
+	defer func(interval, backoff time.Duration) {
+		remoteFlushInterval, remoteMinBackoff = interval, backoff
+	}(remoteFlushInterval, remoteMinBackoff)
+	remoteFlushInterval, remoteMinBackoff = 20*time.Millisecond, 50*time.Millisecond
+
+	var (
+		down     atomic.Bool
+		mutex    sync.Mutex
+		received []LogEntry
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if down.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var entries []LogEntry
+		if err := json.NewDecoder(r.Body).Decode(&entries); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mutex.Lock()
+		received = append(received, entries...)
+		mutex.Unlock()
+	}))
+	defer server.Close()
+
+	sender, err := newRemoteSender(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spoolPath := filepath.Join(t.TempDir(), remoteSpoolName)
+	shipper := newShipper(sender, spoolPath)
+	defer shipper.Close()
+	logger := zap.New(newRemoteCore(zapcore.WarnLevel, shipper))
+
+	// 服务不可用时暂存到磁盘
+	down.Store(true)
+	logger.Warn("离线时的日志")
+	logger.Info("低于远程日志级别")
+	waitFor(t, "写入暂存文件", func() bool {
+		_, err := os.Stat(spoolPath)
+		return err == nil
+	})
+	if status := shipper.status(); status.LastErr == "" || status.Spooled == 0 {
+		t.Fatalf("发送失败状态错误: %+v", status)
+	}
+
+	// 服务恢复后先发送暂存的日志, 发送后删除暂存文件
+	down.Store(false)
+	logger.Error("恢复后的日志", zap.Int("code", 1))
+	waitFor(t, "发送日志", func() bool {
+		return shipper.status().Spooled == 0 && shipper.status().Sent == 2
+	})
+
+	mutex.Lock()
+	defer mutex.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("收到的日志数量错误: %+v", received)
+	}
+	if received[0].Message != "离线时的日志" || received[0].Level != "WARN" {
+		t.Errorf("暂存的日志错误: %+v", received[0])
+	}
+	if received[1].Message != "恢复后的日志 code=1" || received[1].Level != "ERROR" || received[1].Host == "" {
+		t.Errorf("日志内容错误: %+v", received[1])
+	}
+	if status := shipper.status(); status.Sent != 2 || status.LastErr != "" {
+		t.Errorf("发送状态错误: %+v", status)
+	}
+}
+
+func waitFor(t *testing.T, what string, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !done() {
+		if time.Now().After(deadline) {
+			t.Fatalf("等待%s超时", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
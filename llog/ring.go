@@ -0,0 +1,164 @@
+package llog
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// RingSize 内存中保留的最近日志条数, 用于程序内查看日志
+const RingSize = 2000
+
+// Record 内存中的一条日志
+type Record struct {
+	Seq     uint64 // 序号, 从1开始递增
+	Time    time.Time
+	Level   zapcore.Level
+	Logger  string
+	Caller  string
+	Message string
+	Fields  string // 附加字段, 格式为 key=value, 按key排序
+}
+
+// String 格式化为一行文本, 与控制台输出格式一致
+func (r Record) String() string {
+	parts := []string{r.Time.Format("2006-01-02 15:04:05.000"), r.Level.CapitalString()}
+	if r.Logger != "" {
+		parts = append(parts, r.Logger)
+	}
+	if r.Caller != "" {
+		parts = append(parts, r.Caller)
+	}
+	parts = append(parts, r.Message)
+	if r.Fields != "" {
+		parts = append(parts, r.Fields)
+	}
+	return strings.Join(parts, "\t")
+}
+
+// ringBuffer 固定容量的日志环形缓冲区, 写满后覆盖最旧的日志
+type ringBuffer struct {
+	mutex   sync.Mutex
+	records []Record
+	next    int    // 下一条日志写入的位置
+	seq     uint64 // 已写入的日志总数
+}
+
+// ring 全局日志缓冲区, 重新配置日志时保留
+var ring = &ringBuffer{records: make([]Record, 0, RingSize)}
+
+func (b *ringBuffer) add(record Record) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.seq++
+	record.Seq = b.seq
+	if len(b.records) < cap(b.records) {
+		b.records = append(b.records, record)
+		return
+	}
+	b.records[b.next] = record
+	b.next = (b.next + 1) % len(b.records)
+}
+
+// snapshot 按时间顺序返回缓冲区中的日志
+func (b *ringBuffer) snapshot() []Record {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	records := make([]Record, 0, len(b.records))
+	records = append(records, b.records[b.next:]...)
+	return append(records, b.records[:b.next]...)
+}
+
+func (b *ringBuffer) count() uint64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.seq
+}
+
+// RecentLogs 内存中最近的日志, 按时间顺序排列
+func RecentLogs() []Record {
+	return ring.snapshot()
+}
+
+// LogCount 程序启动以来写入的日志总数, 可用于判断是否有新日志
+func LogCount() uint64 {
+	return ring.count()
+}
+
+// ringCore 将日志写入内存缓冲区的日志核心, 不依赖控制台和文件输出
+type ringCore struct {
+	zapcore.LevelEnabler
+	buffer *ringBuffer
+	fields []zapcore.Field
+}
+
+func newRingCore(enabler zapcore.LevelEnabler, buffer *ringBuffer) *ringCore {
+	return &ringCore{LevelEnabler: enabler, buffer: buffer}
+}
+
+func (c *ringCore) With(fields []zapcore.Field) zapcore.Core {
+	merged := make([]zapcore.Field, 0, len(c.fields)+len(fields))
+	merged = append(merged, c.fields...)
+	merged = append(merged, fields...)
+	return &ringCore{LevelEnabler: c.LevelEnabler, buffer: c.buffer, fields: merged}
+}
+
+func (c *ringCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *ringCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	record := Record{
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Logger:  entry.LoggerName,
+		Message: entry.Message,
+		Fields:  encodeFields(c.fields, fields),
+	}
+	if entry.Caller.Defined {
+		record.Caller = entry.Caller.TrimmedPath()
+	}
+	if entry.Stack != "" {
+		record.Message += "\n" + entry.Stack
+	}
+	c.buffer.add(record)
+	return nil
+}
+
+func (c *ringCore) Sync() error {
+	return nil
+}
+
+// encodeFields 将附加字段格式化为 key=value
+func encodeFields(groups ...[]zapcore.Field) string {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, fields := range groups {
+		for _, field := range fields {
+			field.AddTo(encoder)
+		}
+	}
+	if len(encoder.Fields) == 0 {
+		return ""
+	}
+
+	keys := make([]string, 0, len(encoder.Fields))
+	for key := range encoder.Fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", key, encoder.Fields[key]))
+	}
+	return strings.Join(parts, " ")
+}
@@ -1,6 +1,6 @@
 package llog
 
-// LogEntry 表示一条要发送到服务器的日志
+// LogEntry 表示一条要发送到服务器的日志, 远程日志以该格式发送
 type LogEntry struct {
 	Time    int64  `json:"time"` // 使用 Unix 时间戳代替 time.Time
 	Level   string `json:"level"`
@@ -21,4 +21,7 @@ type LogSetting struct {
 	Format       string `json:"format" toml:"format"`               // 日志文件名格式
 	Level        string `json:"level" toml:"level"`                 // 日志级别
 	OutputFormat string `json:"output_format" toml:"output_format"` // 日志输出格式 ("json" 或 "text")
+	Unredacted   bool   `json:"unredacted" toml:"unredacted"`       // 不脱敏调试, 日志中显示推流码等敏感信息
+	RemoteURL    string `json:"remote_url" toml:"remote_url"`       // 远程日志地址, http(s):// 或 udp:// (syslog), 为空时不发送
+	RemoteLevel  string `json:"remote_level" toml:"remote_level"`   // 发送到远程的最低日志级别
 }
@@ -4,25 +4,59 @@ import (
 	_ "embed"
 	"errors"
 	"fmt"
+	"os"
 
+	"tiktok_tool/appdir"
+	"tiktok_tool/cli"
 	"tiktok_tool/config"
+	"tiktok_tool/ipc"
 	"tiktok_tool/lkit"
 	"tiktok_tool/llog"
 	"tiktok_tool/ui"
 )
 
+var appLog = llog.Named("app")
+
 func main() {
+	// 数据目录需要在读取配置、日志和锁文件之前准备好, 首次运行时迁移旧版本工作目录下的数据
+	migrated, migrateErr := appdir.Init()
+
 	lkit.InitCrashLog()
 	defer lkit.CrashLog()
 
+	// --print-config 只输出合并后的生效配置, 不启动界面
+	// 未获取单实例锁, 配置文件可能属于已运行的实例, 只读加载
+	if config.PrintConfigRequested() {
+		if err := config.LoadConfigReadOnly(); err != nil {
+			panic(fmt.Sprintf("初始化配置失败: %v", err))
+		}
+		if err := config.PrintConfig(os.Stdout); err != nil {
+			panic(fmt.Sprintf("输出配置失败: %v", err))
+		}
+		return
+	}
+
+	// 命令行模式不启动界面, 也不占用单实例锁, 例如 tiktok_tool.exe capture --json
+	// 配置加载失败时由命令输出错误, doctor 使用默认配置继续检查
+	if cli.IsCommand(os.Args[1:]) {
+		loadErr := config.LoadConfigReadOnly()
+		lkit.AttachConsole()
+		os.Exit(cli.Run(os.Args[1:], loadErr))
+	}
+
+	// 启动参数中的实例命令, 例如 --start-capture、--one-click --profile=B
+	request := ipc.ParseArgs(os.Args[1:])
+
 	if err := lkit.EnsureSingleInstance(); err != nil {
-		if errors.Is(err, config.AlreadyTop) {
-			return
+		if errors.Is(err, lkit.ErrAlreadyRunning) {
+			// 已有实例在运行, 把命令转发给它执行
+			os.Exit(forwardCommand(request))
 		}
-		panic(fmt.Sprintf("程序已在运行: %v", err))
+		panic(fmt.Sprintf("获取单实例锁失败: %v", err))
 	}
 	defer lkit.CleanupLock()
 
+	// 获取单实例锁后才加载配置, 转发命令的实例不会迁移或恢复已运行实例的配置文件
 	if err := config.LoadConfig(); err != nil {
 		panic(fmt.Sprintf("初始化配置失败: %v", err))
 	}
@@ -32,8 +66,59 @@ func main() {
 	}
 	defer llog.Cleanup()
 
-	// 设置全局panic处理
-	defer llog.HandlePanic()
+	llog.InfoF("数据目录: %s, 便携模式: %v", appdir.Root(), appdir.IsPortable())
+	if migrateErr != nil {
+		appLog.Warn("迁移旧版本数据失败", llog.Err(migrateErr))
+	}
+	for _, path := range migrated {
+		appLog.Info("已迁移旧版本数据", llog.String("path", path))
+	}
 
-	ui.NewMainWindow()
+	// 配置修改后更新日志设置, 并监听配置文件的外部修改
+	config.Subscribe(func(_, cfg *config.Config) {
+		if err := llog.Reconfigure(cfg.LogConfig); err != nil {
+			appLog.Error("更新日志配置失败", llog.Err(err))
+		}
+	})
+	if stopWatch, err := config.Watch(); err != nil {
+		appLog.Warn("监听配置文件失败", llog.Err(err))
+	} else {
+		defer stopWatch()
+	}
+
+	// 日志系统可用后的崩溃同时写入日志, 并在清理日志前写入崩溃报告
+	defer lkit.CrashLog()
+
+	ui.NewMainWindow(request)
+
+	// 界面退出后停止后台任务, 关闭抓包句柄和OBS连接
+	if err := lkit.Shutdown(lkit.ShutdownTimeout); err != nil {
+		appLog.Warn("停止后台任务失败", llog.Err(err))
+	}
+}
+
+// forwardCommand 把命令转发给已运行的实例并输出执行结果, 返回进程退出码
+// 没有指定命令时显示已运行实例的窗口
+func forwardCommand(request *ipc.Request) int {
+	if request == nil {
+		request = &ipc.Request{Command: ipc.CommandShow}
+	}
+
+	response, err := ipc.Send(*request)
+	if err != nil {
+		// 旧版本的实例没有命令接口, 只能置顶其窗口
+		if request.Command == ipc.CommandShow {
+			if ok, bringErr := lkit.BringWindowToFront("抖音直播推流配置抓取"); ok && bringErr == nil {
+				return 0
+			}
+		}
+		_, _ = fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if !response.OK {
+		_, _ = fmt.Fprintln(os.Stderr, response.Error)
+		return 1
+	}
+	fmt.Println(response.Message)
+	return 0
 }
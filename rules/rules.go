@@ -0,0 +1,195 @@
+package rules
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"tiktok_tool/config"
+)
+
+// DefaultPlatform 抖音直播伴侣的规则平台名称
+const DefaultPlatform = "douyin"
+
+const (
+	currentName  = "pack.json"      // 当前使用的规则包
+	previousName = "pack.prev.json" // 上一个规则包, 用于回滚
+	etagName     = "pack.etag"      // 当前规则包的 ETag
+)
+
+// 规则包签名公钥, 目前为占位公钥, 尚未发布签名的规则包, 确定签名流程后替换为维护者私钥对应的公钥
+var publicKey = mustPublicKey("sgc+z9Qkcm50ql+pxRN6eHD2x8SoRJRkkCd8JyheRog=")
+
+// ErrNoPrevious 没有可回滚的规则包
+var ErrNoPrevious = errors.New("没有可回滚的规则包")
+
+// Rule 某个平台的推流信息提取规则
+type Rule struct {
+	Platform       string `json:"platform"`         // 平台名称, 例如 douyin
+	Name           string `json:"name"`             // 显示名称
+	ServerRegex    string `json:"server_regex"`     // 服务器地址正则
+	StreamKeyRegex string `json:"stream_key_regex"` // 推流码正则
+}
+
+// Pack 规则包
+type Pack struct {
+	Version     int       `json:"version"`      // 规则包版本, 只会更新到更高的版本
+	PublishedAt time.Time `json:"published_at"` // 发布时间
+	Changelog   string    `json:"changelog"`    // 更新说明
+	Rules       []Rule    `json:"rules"`
+}
+
+// SignedPack 规则包文件格式, Signature 为对 Pack 原始内容的 ed25519 签名(base64)
+type SignedPack struct {
+	Pack      json.RawMessage `json:"pack"`
+	Signature string          `json:"signature"`
+}
+
+func mustPublicKey(encoded string) ed25519.PublicKey {
+	key, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		panic("规则包公钥格式错误")
+	}
+	return key
+}
+
+// Rule 获取指定平台的规则
+func (p *Pack) Rule(platform string) *Rule {
+	for i := range p.Rules {
+		if p.Rules[i].Platform == platform {
+			return &p.Rules[i]
+		}
+	}
+	return nil
+}
+
+// validate 检查规则包内容, 正则无法编译的规则包不会被使用
+func (p *Pack) validate() error {
+	if p.Version <= 0 {
+		return fmt.Errorf("规则包版本无效: %d", p.Version)
+	}
+	if len(p.Rules) == 0 {
+		return fmt.Errorf("规则包中没有规则")
+	}
+	platforms := make(map[string]bool)
+	for _, rule := range p.Rules {
+		if rule.Platform == "" || platforms[rule.Platform] {
+			return fmt.Errorf("规则平台名称为空或重复: %s", rule.Platform)
+		}
+		platforms[rule.Platform] = true
+		for _, expr := range []string{rule.ServerRegex, rule.StreamKeyRegex} {
+			if strings.TrimSpace(expr) == "" {
+				return fmt.Errorf("平台 %s 的正则为空", rule.Platform)
+			}
+			if _, err := regexp.Compile(expr); err != nil {
+				return fmt.Errorf("平台 %s 的正则无效: %v", rule.Platform, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Verify 校验规则包签名并解析
+func Verify(data []byte) (*Pack, error) {
+	var signed SignedPack
+	if err := json.Unmarshal(data, &signed); err != nil {
+		return nil, fmt.Errorf("规则包格式错误: %v", err)
+	}
+	signature, err := base64.StdEncoding.DecodeString(signed.Signature)
+	if err != nil {
+		return nil, fmt.Errorf("规则包签名格式错误: %v", err)
+	}
+	if !ed25519.Verify(publicKey, signed.Pack, signature) {
+		return nil, fmt.Errorf("规则包签名校验失败")
+	}
+
+	var pack Pack
+	if err = json.Unmarshal(signed.Pack, &pack); err != nil {
+		return nil, fmt.Errorf("规则包格式错误: %v", err)
+	}
+	if err = pack.validate(); err != nil {
+		return nil, err
+	}
+	return &pack, nil
+}
+
+// packPath 规则包目录下的文件路径
+func packPath(name string) string {
+	return filepath.Join(config.RulesPath, name)
+}
+
+// readPack 读取并校验规则包文件, 文件不存在时返回 nil
+func readPack(name string) (*Pack, []byte, error) {
+	data, err := os.ReadFile(packPath(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, nil
+		}
+		return nil, nil, err
+	}
+	pack, err := Verify(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pack, data, nil
+}
+
+// Current 当前使用的规则包, 未安装规则包时返回 nil
+func Current() (*Pack, error) {
+	pack, _, err := readPack(currentName)
+	return pack, err
+}
+
+// builtinRule 程序内置的规则, 未安装规则包时使用
+func builtinRule() *Rule {
+	return &Rule{
+		Platform:       DefaultPlatform,
+		Name:           "内置规则",
+		ServerRegex:    config.DefaultProfile.ServerRegex,
+		StreamKeyRegex: config.DefaultProfile.StreamKeyRegex,
+	}
+}
+
+// ruleOf 规则包中的默认平台规则, 规则包为空或没有该平台时使用内置规则
+func ruleOf(pack *Pack) *Rule {
+	if pack != nil {
+		if rule := pack.Rule(DefaultPlatform); rule != nil {
+			return rule
+		}
+	}
+	return builtinRule()
+}
+
+// updateProfiles 将使用旧规则或内置规则的配置方案更新为新规则, 用户自定义的正则保持不变
+// 返回被更新的方案名称
+func updateProfiles(cfg *config.Config, old, new *Rule) []string {
+	builtin := builtinRule()
+	untouched := func(value, oldValue, builtinValue string) bool {
+		value = strings.TrimSpace(value)
+		return value == oldValue || value == builtinValue
+	}
+
+	var updated []string
+	for _, profile := range cfg.Profiles {
+		changed := false
+		if untouched(profile.ServerRegex, old.ServerRegex, builtin.ServerRegex) && profile.ServerRegex != new.ServerRegex {
+			profile.ServerRegex = new.ServerRegex
+			changed = true
+		}
+		if untouched(profile.StreamKeyRegex, old.StreamKeyRegex, builtin.StreamKeyRegex) && profile.StreamKeyRegex != new.StreamKeyRegex {
+			profile.StreamKeyRegex = new.StreamKeyRegex
+			changed = true
+		}
+		if changed {
+			updated = append(updated, profile.Name)
+		}
+	}
+	return updated
+}
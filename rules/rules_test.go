@@ -0,0 +1,163 @@
+package rules
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"tiktok_tool/config"
+	"tiktok_tool/llog"
+)
+
+// packServer 模拟规则包更新服务器
+type packServer struct {
+	mutex   sync.Mutex
+	data    []byte
+	version int
+}
+
+func (s *packServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	etag := `"` + strconv.Itoa(s.version) + `"`
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	_, _ = w.Write(s.data)
+}
+
+func (s *packServer) publish(t *testing.T, key ed25519.PrivateKey, pack Pack) {
+	raw, err := json.Marshal(pack)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := json.Marshal(SignedPack{Pack: raw, Signature: base64.StdEncoding.EncodeToString(ed25519.Sign(key, raw))})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.data, s.version = data, pack.Version
+}
+
+func newPack(version int, serverRegex string) Pack {
+	return Pack{
+		Version:   version,
+		Changelog: "更新推流地址格式",
+		Rules: []Rule{{
+			Platform:       DefaultPlatform,
+			ServerRegex:    serverRegex,
+			StreamKeyRegex: config.DefaultProfile.StreamKeyRegex,
+		}},
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	_ = llog.Init(&llog.LogSetting{Level: "error"})
+
+	pub, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey = pub
+	config.RulesPath = t.TempDir()
+
+	var saveErr error
+	saveConfig = func(cfg *config.Config) error {
+		if saveErr != nil {
+			return saveErr
+		}
+		config.SetConfig(cfg)
+		return nil
+	}
+	config.SetConfig(config.DefaultConfig.Clone())
+
+	server := &packServer{}
+	httpServer := httptest.NewServer(server)
+	defer httpServer.Close()
+	ctx := context.Background()
+
+	// 首次更新, 使用内置正则的方案被更新
+	server.publish(t, key, newPack(1, `(rtmp://v1\.example\.com/\S+)`))
+	update, err := Check(ctx, httpServer.URL)
+	if err != nil || update == nil {
+		t.Fatalf("检查更新失败: %v %v", update, err)
+	}
+	if len(update.Changes()) != 1 {
+		t.Errorf("规则变化错误: %v", update.Changes())
+	}
+	if _, err = Apply(update); err != nil {
+		t.Fatal(err)
+	}
+	if config.GetConfig().Profile().ServerRegex != `(rtmp://v1\.example\.com/\S+)` {
+		t.Errorf("方案正则未更新: %s", config.GetConfig().Profile().ServerRegex)
+	}
+
+	// 未修改时服务器返回 304
+	if update, err = Check(ctx, httpServer.URL); err != nil || update != nil {
+		t.Errorf("未修改的规则包不应更新: %v %v", update, err)
+	}
+
+	// 签名不正确的规则包被拒绝
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	server.publish(t, otherKey, newPack(2, `(rtmp://evil)`))
+	if _, err = Check(ctx, httpServer.URL); err == nil {
+		t.Error("签名错误的规则包应被拒绝")
+	}
+
+	// 用户自定义的正则不会被覆盖
+	cfg := config.GetConfig().Clone()
+	custom := cfg.Profile().Clone()
+	custom.Name, custom.ServerRegex = "自定义", `(rtmp://custom)`
+	cfg.Profiles = append(cfg.Profiles, custom)
+	config.SetConfig(cfg)
+
+	server.publish(t, key, newPack(2, `(rtmp://v2\.example\.com/\S+)`))
+	if update, err = Check(ctx, httpServer.URL); err != nil || update == nil {
+		t.Fatalf("检查更新失败: %v %v", update, err)
+	}
+	updated, err := Apply(update)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(updated) != 1 || config.GetConfig().FindProfile("自定义").ServerRegex != `(rtmp://custom)` {
+		t.Errorf("自定义正则被覆盖: %v", updated)
+	}
+
+	// 保存配置失败时恢复原规则包
+	server.publish(t, key, newPack(3, `(rtmp://v3\.example\.com/\S+)`))
+	if update, err = Check(ctx, httpServer.URL); err != nil || update == nil {
+		t.Fatalf("检查更新失败: %v %v", update, err)
+	}
+	saveErr = errors.New("磁盘已满")
+	if _, err = Apply(update); err == nil {
+		t.Error("保存配置失败时应用规则包应失败")
+	}
+	saveErr = nil
+	if current, _ := Current(); current == nil || current.Version != 2 {
+		t.Errorf("规则包未恢复: %v", current)
+	}
+
+	// 回滚到上一个规则包
+	if _, err = Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if config.GetConfig().Profile().ServerRegex != `(rtmp://v1\.example\.com/\S+)` {
+		t.Errorf("回滚后方案正则错误: %s", config.GetConfig().Profile().ServerRegex)
+	}
+	if current, _ := Current(); current == nil || current.Version != 1 {
+		t.Errorf("回滚后规则包版本错误: %v", current)
+	}
+}
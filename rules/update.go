@@ -0,0 +1,211 @@
+package rules
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"tiktok_tool/config"
+	"tiktok_tool/llog"
+)
+
+// 规则包文件大小上限
+const maxPackSize = 1 << 20
+
+var logger = llog.Named("rules")
+
+var (
+	client = &http.Client{Timeout: 15 * time.Second}
+
+	// applyMutex 保证规则包的应用和回滚不会同时进行
+	applyMutex sync.Mutex
+
+	// saveConfig 保存并重新加载配置
+	saveConfig = func(cfg *config.Config) error {
+		if err := config.SaveSettings(cfg); err != nil {
+			return err
+		}
+		return config.ReloadConfig()
+	}
+)
+
+// Update 可以应用的规则包更新
+type Update struct {
+	Pack    *Pack
+	Current *Pack // 当前使用的规则包, 未安装时为 nil
+
+	data []byte
+	etag string
+}
+
+// Changes 更新后默认平台规则的变化, 用于在确认前展示
+func (u *Update) Changes() []config.Change {
+	old, new := ruleOf(u.Current), ruleOf(u.Pack)
+	var changes []config.Change
+	if old.ServerRegex != new.ServerRegex {
+		changes = append(changes, config.Change{Key: "server_regex", Old: old.ServerRegex, New: new.ServerRegex})
+	}
+	if old.StreamKeyRegex != new.StreamKeyRegex {
+		changes = append(changes, config.Change{Key: "stream_key_regex", Old: old.StreamKeyRegex, New: new.StreamKeyRegex})
+	}
+	return changes
+}
+
+// Check 从更新地址检查规则包更新, 没有更高版本时返回 nil
+// 已安装规则包时使用 ETag 请求, 服务器返回 304 时不下载
+func Check(ctx context.Context, url string) (*Update, error) {
+	if strings.TrimSpace(url) == "" {
+		return nil, fmt.Errorf("未设置规则包更新地址")
+	}
+
+	current, err := Current()
+	if err != nil {
+		logger.Warn("当前规则包无效, 将重新下载", llog.Err(err))
+		current = nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil {
+		if etag, err := os.ReadFile(packPath(etagName)); err == nil && len(etag) > 0 {
+			req.Header.Set("If-None-Match", string(etag))
+		}
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("请求规则包失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("请求规则包失败: %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPackSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("下载规则包失败: %v", err)
+	}
+	if len(data) > maxPackSize {
+		return nil, fmt.Errorf("规则包过大")
+	}
+
+	pack, err := Verify(data)
+	if err != nil {
+		return nil, err
+	}
+	if current != nil && pack.Version <= current.Version {
+		saveETag(resp.Header.Get("ETag"))
+		return nil, nil
+	}
+	return &Update{Pack: pack, Current: current, data: data, etag: resp.Header.Get("ETag")}, nil
+}
+
+// saveETag 记录当前规则包的 ETag, 为空时删除
+func saveETag(etag string) {
+	if etag == "" {
+		_ = os.Remove(packPath(etagName))
+		return
+	}
+	if err := config.WriteFileAtomic(packPath(etagName), []byte(etag)); err != nil {
+		logger.Warn("保存规则包ETag失败", llog.Err(err))
+	}
+}
+
+// install 将规则包设为当前规则包, previous 为之后回滚使用的规则包, 为 nil 时删除
+func install(current, previous []byte) error {
+	if err := os.MkdirAll(config.RulesPath, 0755); err != nil {
+		return fmt.Errorf("创建规则包目录失败: %v", err)
+	}
+	if previous == nil {
+		if err := os.Remove(packPath(previousName)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	} else if err := config.WriteFileAtomic(packPath(previousName), previous); err != nil {
+		return err
+	}
+	if current == nil {
+		if err := os.Remove(packPath(currentName)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	return config.WriteFileAtomic(packPath(currentName), current)
+}
+
+// switchPack 切换规则包并更新配置方案中的正则, 保存配置失败时恢复原来的规则包
+func switchPack(from, to *Pack, current, previous, oldCurrent, oldPrevious []byte) ([]string, error) {
+	if err := install(current, previous); err != nil {
+		return nil, fmt.Errorf("写入规则包失败: %v", err)
+	}
+
+	cfg := config.GetConfig().Clone()
+	updated := updateProfiles(cfg, ruleOf(from), ruleOf(to))
+	if len(updated) == 0 {
+		return nil, nil
+	}
+	if err := saveConfig(cfg); err != nil {
+		if rollbackErr := install(oldCurrent, oldPrevious); rollbackErr != nil {
+			logger.Error("恢复规则包失败", llog.Err(rollbackErr))
+		}
+		return nil, fmt.Errorf("应用规则包失败, 已恢复原规则包: %v", err)
+	}
+	return updated, nil
+}
+
+// Apply 应用规则包更新, 原规则包保留用于回滚, 返回正则被更新的配置方案
+func Apply(update *Update) ([]string, error) {
+	applyMutex.Lock()
+	defer applyMutex.Unlock()
+
+	current, currentData, err := readPack(currentName)
+	if err != nil {
+		current, currentData = nil, nil
+	}
+	_, previousData, _ := readPack(previousName)
+
+	updated, err := switchPack(current, update.Pack, update.data, currentData, currentData, previousData)
+	if err != nil {
+		return nil, err
+	}
+	saveETag(update.etag)
+	llog.InfoF("已应用规则包版本 %d, 更新的方案: %v", update.Pack.Version, updated)
+	return updated, nil
+}
+
+// Rollback 回滚到上一个规则包, 返回正则被更新的配置方案
+func Rollback() ([]string, error) {
+	applyMutex.Lock()
+	defer applyMutex.Unlock()
+
+	previous, previousData, err := readPack(previousName)
+	if err != nil {
+		return nil, fmt.Errorf("上一个规则包无效: %v", err)
+	}
+	if previous == nil {
+		return nil, ErrNoPrevious
+	}
+	current, currentData, err := readPack(currentName)
+	if err != nil {
+		current, currentData = nil, nil
+	}
+
+	updated, err := switchPack(current, previous, previousData, currentData, currentData, previousData)
+	if err != nil {
+		return nil, err
+	}
+	// 回滚后下次检查时重新下载
+	saveETag("")
+	llog.InfoF("已回滚到规则包版本 %d, 更新的方案: %v", previous.Version, updated)
+	return updated, nil
+}
@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"fmt"
+	"os"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/lkit"
+	"tiktok_tool/llog"
+)
+
+// checkCrashReports 启动时检查上次运行后新产生的崩溃报告, 有新报告时提示查看或导出
+func (w *MainWindow) checkCrashReports() {
+	reports := lkit.PendingCrashReports()
+	if len(reports) == 0 {
+		return
+	}
+	uiLog.WarnF("发现 %d 个未查看的崩溃报告, 最新: %s", len(reports), reports[0].Name)
+	ShowCrashReportDialog(w.app, w.window, reports)
+}
+
+// ShowCrashReportDialog 显示崩溃报告列表, 可以查看或导出报告, 关闭后不再提示这些报告
+func ShowCrashReportDialog(app fyne.App, window fyne.Window, reports []lkit.CrashReport) {
+	options := make([]string, 0, len(reports))
+	for _, report := range reports {
+		options = append(options, crashReportTitle(report))
+	}
+	selected := 0
+	reportSelect := widget.NewSelect(options, nil)
+	reportSelect.OnChanged = func(string) {
+		selected = reportSelect.SelectedIndex()
+	}
+	reportSelect.SetSelectedIndex(0)
+
+	note := widget.NewLabel(fmt.Sprintf("上次运行时程序发生了 %d 次错误, 报告中包含错误原因、协程堆栈和最近的日志。"+
+		"反馈问题时请导出报告或诊断包。", len(reports)))
+	note.Wrapping = fyne.TextWrapWord
+
+	var crashDialog *dialog.CustomDialog
+	viewBtn := widget.NewButtonWithIcon("查看", theme.DocumentIcon(), func() {
+		showCrashReportWindow(app, window, reports[selected])
+	})
+	exportBtn := widget.NewButtonWithIcon("导出报告", theme.DocumentSaveIcon(), func() {
+		saveCrashReport(window, reports[selected])
+	})
+	diagBtn := widget.NewButtonWithIcon("导出诊断包", theme.DownloadIcon(), func() {
+		crashDialog.Hide()
+		ShowDiagnosticsDialog(window)
+	})
+	closeBtn := widget.NewButton("关闭", func() {
+		crashDialog.Hide()
+	})
+
+	content := container.NewVBox(note, reportSelect,
+		container.NewHBox(viewBtn, exportBtn, diagBtn, layout.NewSpacer(), closeBtn))
+	crashDialog = dialog.NewCustomWithoutButtons("崩溃报告", content, window)
+	crashDialog.SetOnClosed(func() {
+		if err := lkit.MarkCrashReportsSeen(); err != nil {
+			uiLog.Warn("保存崩溃报告查看状态失败", llog.Err(err))
+		}
+	})
+	crashDialog.Resize(SettingsWindowDialogSize)
+	crashDialog.Show()
+}
+
+// crashReportTitle 报告在列表中显示的标题
+func crashReportTitle(report lkit.CrashReport) string {
+	title := report.Name
+	if !report.Time.IsZero() {
+		title = report.Time.Format("2006-01-02 15:04:05")
+	}
+	if report.Source != "" {
+		title += " [" + report.Source + "]"
+	}
+	return title + " " + report.Summary
+}
+
+// showCrashReportWindow 在新窗口中显示崩溃报告全文
+func showCrashReportWindow(app fyne.App, parent fyne.Window, report lkit.CrashReport) {
+	data, err := os.ReadFile(report.Path)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("读取崩溃报告失败: %v", err), parent)
+		return
+	}
+
+	reportWindow := app.NewWindow("崩溃报告 - " + report.Name)
+	text := widget.NewLabelWithStyle(string(data), fyne.TextAlignLeading, fyne.TextStyle{Monospace: true})
+	text.Selectable = true
+	copyBtn := widget.NewButtonWithIcon("复制全部", theme.ContentCopyIcon(), func() {
+		app.Clipboard().SetContent(string(data))
+	})
+	actions := container.NewHBox(widget.NewLabel(report.Path), layout.NewSpacer(), copyBtn)
+
+	reportWindow.SetContent(container.NewBorder(nil, actions, nil, nil, container.NewScroll(text)))
+	reportWindow.Resize(fyne.NewSize(900, 600))
+	reportWindow.Show()
+}
+
+// saveCrashReport 将崩溃报告另存到用户选择的位置
+func saveCrashReport(window fyne.Window, report lkit.CrashReport) {
+	fileDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("导出崩溃报告失败: %v", err), window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		data, err := os.ReadFile(report.Path)
+		if err == nil {
+			_, err = writer.Write(data)
+		}
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("导出崩溃报告失败: %v", err), window)
+			return
+		}
+		dialog.ShowInformation("导出成功", "崩溃报告已导出到: "+writer.URI().Path(), window)
+	}, window)
+	fileDialog.SetFileName(report.Name)
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".log", ".txt"}))
+	fileDialog.Show()
+}
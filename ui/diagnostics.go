@@ -0,0 +1,89 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/diag"
+	"tiktok_tool/lkit"
+	"tiktok_tool/llog"
+)
+
+// ShowDiagnosticsDialog 导出诊断包, 用于反馈问题
+func ShowDiagnosticsDialog(window fyne.Window) {
+	unredacted := widget.NewCheck("包含推流码等敏感信息(不脱敏)", nil)
+	warning := widget.NewLabel("⚠ 不脱敏的诊断包中包含推流码, 他人拿到后可以使用你的直播间推流, 请勿公开发送")
+	warning.Importance = widget.DangerImportance
+	warning.Wrapping = fyne.TextWrapWord
+	warning.Hide()
+	unredacted.OnChanged = func(checked bool) {
+		if checked {
+			warning.Show()
+		} else {
+			warning.Hide()
+		}
+	}
+
+	note := widget.NewLabel("诊断包包含日志、崩溃报告、配置(已隐藏密码)、网卡列表、权限、OBS和直播伴侣运行状态、插件信息和最近的推流记录, " +
+		"日志中的推流码默认会被隐藏。反馈问题时请附上诊断包。")
+	note.Wrapping = fyne.TextWrapWord
+
+	content := container.NewVBox(note, unredacted, warning)
+	confirmDialog := dialog.NewCustomConfirm("导出诊断包", "选择保存位置", "取消", content, func(ok bool) {
+		if ok {
+			saveDiagnostics(window, diag.Options{Unredacted: unredacted.Checked})
+		}
+	}, window)
+	confirmDialog.Resize(SettingsWindowDialogSize)
+	confirmDialog.Show()
+}
+
+// saveDiagnostics 选择保存位置并写入诊断包, 收集进程和网卡信息可能较慢, 在后台执行
+func saveDiagnostics(window fyne.Window, options diag.Options) {
+	fileDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			showDiagnosticsResult(window, "", err)
+			return
+		}
+		if writer == nil {
+			return
+		}
+
+		progress := dialog.NewCustomWithoutButtons("导出诊断包", widget.NewProgressBarInfinite(), window)
+		progress.Show()
+		lkit.Go("diag.export", func(context.Context) {
+			defer writer.Close()
+			manifest, err := diag.Export(writer, options)
+			if err == nil {
+				uiLog.Info("已导出诊断包", llog.String("path", writer.URI().Path()), llog.Int("files", len(manifest.Files)))
+			}
+			fyne.Do(func() {
+				progress.Hide()
+				showDiagnosticsResult(window, writer.URI().Path(), err)
+			})
+		})
+	}, window)
+	fileDialog.SetFileName(diag.FileName(time.Now()))
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{".zip"}))
+	fileDialog.Show()
+}
+
+// showDiagnosticsResult 显示导出结果
+func showDiagnosticsResult(window fyne.Window, path string, err error) {
+	if err != nil {
+		errorDialog := dialog.NewError(fmt.Errorf("导出诊断包失败: %v", err), window)
+		errorDialog.Resize(SettingsWindowDialogSize)
+		errorDialog.Show()
+		return
+	}
+	infoDialog := dialog.NewInformation("导出成功", "诊断包已导出到: "+path, window)
+	infoDialog.Resize(SettingsWindowDialogSize)
+	infoDialog.Show()
+}
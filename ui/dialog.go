@@ -1,13 +1,17 @@
 package ui
 
 import (
+	"fmt"
 	"net/url"
 	"os/exec"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/config"
 )
 
 // parseURL 解析URL字符串
@@ -43,6 +47,48 @@ func ShowInstallDialog(window fyne.Window) {
 	})
 }
 
+// ShowBackendErrorDialog 显示抓包后端不可用对话框
+func ShowBackendErrorDialog(window fyne.Window, err error) {
+	errorDialog := dialog.NewError(fmt.Errorf("抓包功能不可用: %v\n请检查配置中的 capture_backend 设置", err), window)
+	errorDialog.Resize(MainWindowDialogSize)
+	errorDialog.Show()
+
+	errorDialog.SetOnClosed(func() {
+		window.Close()
+	})
+}
+
+// ProblemDialogSize 配置问题列表对话框大小
+var ProblemDialogSize = fyne.NewSize(560, 320)
+
+// newProblemList 创建配置问题列表
+func newProblemList(problems []config.Problem) fyne.CanvasObject {
+	items := container.NewVBox()
+	for _, problem := range problems {
+		label := widget.NewLabel(problem.String())
+		label.Wrapping = fyne.TextWrapWord
+		if problem.Level == config.ProblemError {
+			label.Importance = widget.DangerImportance
+		} else {
+			label.Importance = widget.WarningImportance
+		}
+		items.Add(label)
+	}
+	return container.NewVScroll(items)
+}
+
+// ShowConfigProblemsDialog 显示启动时检查出的配置问题
+func ShowConfigProblemsDialog(window fyne.Window, problems []config.Problem) {
+	content := container.NewBorder(
+		widget.NewLabel("配置存在以下问题, 相关功能可能无法正常使用, 请在设置中修改:"),
+		nil, nil, nil,
+		newProblemList(problems),
+	)
+	problemDialog := dialog.NewCustom("配置检查", "知道了", content, window)
+	problemDialog.Resize(ProblemDialogSize)
+	problemDialog.Show()
+}
+
 // ShowHelpDialog 显示帮助对话框
 func ShowHelpDialog(window fyne.Window) {
 	// 创建超链接
@@ -74,15 +120,22 @@ func ShowHelpDialog(window fyne.Window) {
 		),
 	)
 
+	// 导出诊断包, 反馈问题时使用
+	var helpDialog *dialog.CustomDialog
+	diagBtn := widget.NewButtonWithIcon("导出诊断包", theme.DocumentSaveIcon(), func() {
+		helpDialog.Hide()
+		ShowDiagnosticsDialog(window)
+	})
+
 	content := container.NewBorder(
 		nil,
-		nil,
+		container.NewCenter(diagBtn),
 		nil,
 		nil,
 		scroll,
 	)
 
-	helpDialog := dialog.NewCustom("使用说明", "关闭", content, window)
+	helpDialog = dialog.NewCustom("使用说明", "关闭", content, window)
 	helpDialog.Resize(fyne.NewSize(450, 350))
 	helpDialog.Show()
 }
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// HealthGraph 简单的折线图, 用于显示推流码率变化
+type HealthGraph struct {
+	widget.BaseWidget
+
+	values    []float64
+	maxPoints int
+	warning   bool
+}
+
+// NewHealthGraph 创建折线图, maxPoints 为保留的最大数据点数
+func NewHealthGraph(maxPoints int) *HealthGraph {
+	g := &HealthGraph{maxPoints: maxPoints}
+	g.ExtendBaseWidget(g)
+	return g
+}
+
+// Add 添加一个数据点, warning 为true时折线显示为告警颜色
+func (g *HealthGraph) Add(value float64, warning bool) {
+	g.values = append(g.values, value)
+	if len(g.values) > g.maxPoints {
+		g.values = g.values[len(g.values)-g.maxPoints:]
+	}
+	g.warning = warning
+	g.Refresh()
+}
+
+// Clear 清空数据
+func (g *HealthGraph) Clear() {
+	g.values = nil
+	g.warning = false
+	g.Refresh()
+}
+
+func (g *HealthGraph) MinSize() fyne.Size {
+	return fyne.NewSize(300, 100)
+}
+
+func (g *HealthGraph) CreateRenderer() fyne.WidgetRenderer {
+	background := canvas.NewRectangle(theme.Color(theme.ColorNameInputBackground))
+	return &healthGraphRenderer{graph: g, background: background}
+}
+
+type healthGraphRenderer struct {
+	graph      *HealthGraph
+	background *canvas.Rectangle
+	lines      []*canvas.Line
+	size       fyne.Size
+}
+
+func (r *healthGraphRenderer) Layout(size fyne.Size) {
+	r.size = size
+	r.background.Resize(size)
+	r.buildLines()
+}
+
+func (r *healthGraphRenderer) MinSize() fyne.Size {
+	return r.graph.MinSize()
+}
+
+func (r *healthGraphRenderer) Refresh() {
+	r.background.FillColor = theme.Color(theme.ColorNameInputBackground)
+	r.background.Refresh()
+	r.buildLines()
+	canvas.Refresh(r.graph)
+}
+
+func (r *healthGraphRenderer) Objects() []fyne.CanvasObject {
+	objects := []fyne.CanvasObject{r.background}
+	for _, line := range r.lines {
+		objects = append(objects, line)
+	}
+	return objects
+}
+
+func (r *healthGraphRenderer) Destroy() {}
+
+// buildLines 根据当前数据重新生成折线
+func (r *healthGraphRenderer) buildLines() {
+	values := r.graph.values
+	r.lines = r.lines[:0]
+	if len(values) < 2 || r.size.Width <= 0 || r.size.Height <= 0 {
+		return
+	}
+
+	maxValue := 1.0
+	for _, value := range values {
+		if value > maxValue {
+			maxValue = value
+		}
+	}
+
+	var lineColor color.Color = theme.Color(theme.ColorNameSuccess)
+	if r.graph.warning {
+		lineColor = theme.Color(theme.ColorNameError)
+	}
+
+	step := r.size.Width / float32(r.graph.maxPoints-1)
+	offset := r.size.Width - step*float32(len(values)-1)
+	point := func(i int) fyne.Position {
+		y := r.size.Height - float32(values[i]/maxValue)*r.size.Height*0.9
+		return fyne.NewPos(offset+step*float32(i), y)
+	}
+
+	for i := 1; i < len(values); i++ {
+		line := canvas.NewLine(lineColor)
+		line.StrokeWidth = 2
+		line.Position1 = point(i - 1)
+		line.Position2 = point(i)
+		r.lines = append(r.lines, line)
+	}
+}
@@ -1,3 +1,5 @@
+//go:build windows
+
 package ui
 
 import (
@@ -35,5 +37,5 @@ func TestClick(t *testing.T) {
 }
 
 func TestUI(t *testing.T) {
-	NewMainWindow()
+	NewMainWindow(nil)
 }
@@ -0,0 +1,329 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"image/color"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"go.uber.org/zap/zapcore"
+
+	"tiktok_tool/lkit"
+	"tiktok_tool/llog"
+)
+
+// 日志窗口检查新日志的间隔
+const logRefreshInterval = 500 * time.Millisecond
+
+// 日志级别筛选选项, 选择某个级别时显示该级别及以上的日志
+var logLevelOptions = []string{"全部", "DEBUG", "INFO", "WARN", "ERROR"}
+
+// LogWindow 程序内日志查看窗口, 显示内存中最近的日志
+type LogWindow struct {
+	window fyne.Window
+	app    fyne.App
+
+	levelSelect *widget.Select
+	search      *widget.Entry
+	pauseBtn    *widget.Button
+	follow      *widget.Check
+	list        *widget.List
+	status      *widget.Label
+	banner      *widget.Label
+
+	all       []llog.Record // 最近一次读取的全部日志
+	records   []llog.Record // 筛选后显示的日志
+	selected  map[uint64]bool
+	paused    bool
+	lastCount atomic.Uint64 // 上次刷新时的日志总数
+	stop      chan struct{}
+}
+
+// showLogWindow 显示日志窗口, 已打开时显示到前台
+func (w *MainWindow) showLogWindow() {
+	if w.logWindow != nil {
+		w.logWindow.window.Show()
+		w.logWindow.window.RequestFocus()
+		return
+	}
+
+	w.logWindow = newLogWindow(w.app)
+	w.logWindow.window.SetOnClosed(func() {
+		close(w.logWindow.stop)
+		w.logWindow = nil
+	})
+	w.logWindow.window.Show()
+}
+
+func newLogWindow(app fyne.App) *LogWindow {
+	l := &LogWindow{
+		window:   app.NewWindow("日志"),
+		app:      app,
+		selected: make(map[uint64]bool),
+		stop:     make(chan struct{}),
+	}
+	l.window.Resize(fyne.NewSize(900, 520))
+	l.window.SetContent(l.setupUI())
+	l.reload()
+
+	// 定时检查新日志, 窗口关闭或程序退出时停止
+	lkit.Go("ui.log_window", func(ctx context.Context) {
+		ticker := time.NewTicker(logRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if llog.LogCount() == l.lastCount.Load() {
+					continue
+				}
+				fyne.Do(func() {
+					select {
+					case <-l.stop:
+					default:
+						if !l.paused {
+							l.reload()
+						}
+					}
+				})
+			}
+		}
+	})
+	return l
+}
+
+func (l *LogWindow) setupUI() fyne.CanvasObject {
+	l.levelSelect = widget.NewSelect(logLevelOptions, func(string) {
+		l.applyFilter()
+	})
+	l.levelSelect.SetSelectedIndex(0)
+
+	l.search = widget.NewEntry()
+	l.search.SetPlaceHolder("搜索日志内容")
+	l.search.OnChanged = func(string) {
+		l.applyFilter()
+	}
+
+	l.pauseBtn = widget.NewButtonWithIcon("暂停", theme.MediaPauseIcon(), l.togglePause)
+	l.follow = widget.NewCheck("跟随最新", func(checked bool) {
+		if checked && l.list != nil {
+			l.list.ScrollToBottom()
+		}
+	})
+	l.follow.SetChecked(true)
+
+	l.list = widget.NewList(
+		func() int {
+			return len(l.records)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return container.NewStack(canvas.NewRectangle(color.Transparent), label)
+		},
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			if id >= len(l.records) {
+				return
+			}
+			record := l.records[id]
+			stack := item.(*fyne.Container)
+			background := stack.Objects[0].(*canvas.Rectangle)
+			label := stack.Objects[1].(*widget.Label)
+
+			if l.selected[record.Seq] {
+				background.FillColor = theme.Color(theme.ColorNameSelection)
+			} else {
+				background.FillColor = color.Transparent
+			}
+			background.Refresh()
+
+			label.Importance = levelImportance(record.Level)
+			// 多行日志(如堆栈)在列表中只显示第一行, 复制时保留完整内容
+			text, _, _ := strings.Cut(record.String(), "\n")
+			label.SetText(text)
+		},
+	)
+	// 点击切换选中状态, 可以选中多条日志
+	l.list.OnSelected = func(id widget.ListItemID) {
+		if id < len(l.records) {
+			seq := l.records[id].Seq
+			if l.selected[seq] {
+				delete(l.selected, seq)
+			} else {
+				l.selected[seq] = true
+			}
+		}
+		l.list.UnselectAll()
+		l.list.RefreshItem(id)
+		l.updateStatus()
+	}
+
+	copySelectedBtn := widget.NewButtonWithIcon("复制选中", theme.ContentCopyIcon(), l.copySelected)
+	copyAllBtn := widget.NewButtonWithIcon("复制全部", theme.ContentCopyIcon(), l.copyAll)
+	clearBtn := widget.NewButtonWithIcon("取消选择", theme.ContentClearIcon(), func() {
+		l.selected = make(map[uint64]bool)
+		l.list.Refresh()
+		l.updateStatus()
+	})
+	tasksBtn := widget.NewButtonWithIcon("后台任务", theme.ListIcon(), func() {
+		showTaskWindow(l.app)
+	})
+	l.status = widget.NewLabel("")
+	l.banner = newRedactBanner()
+	l.refreshRedactBanner()
+
+	toolbar := container.NewBorder(l.banner, nil,
+		container.NewHBox(widget.NewLabel("级别"), l.levelSelect),
+		container.NewHBox(l.pauseBtn, l.follow),
+		l.search,
+	)
+	actions := container.NewHBox(l.status, layout.NewSpacer(), tasksBtn, clearBtn, copySelectedBtn, copyAllBtn)
+
+	return container.NewPadded(container.NewBorder(toolbar, actions, nil, nil, l.list))
+}
+
+// newRedactBanner 关闭日志脱敏时显示的警告
+func newRedactBanner() *widget.Label {
+	banner := widget.NewLabel("⚠ 已关闭日志脱敏, 日志中包含推流码等敏感信息, 请勿将日志发送给他人")
+	banner.Importance = widget.DangerImportance
+	banner.Alignment = fyne.TextAlignCenter
+	banner.Wrapping = fyne.TextWrapWord
+	return banner
+}
+
+// refreshRedactBanner 根据日志是否脱敏显示或隐藏警告
+func (l *LogWindow) refreshRedactBanner() {
+	if llog.IsUnredacted() {
+		l.banner.Show()
+	} else {
+		l.banner.Hide()
+	}
+}
+
+// levelImportance 不同级别的日志使用不同颜色
+func levelImportance(level zapcore.Level) widget.Importance {
+	switch {
+	case level >= zapcore.ErrorLevel:
+		return widget.DangerImportance
+	case level == zapcore.WarnLevel:
+		return widget.WarningImportance
+	case level == zapcore.DebugLevel:
+		return widget.LowImportance
+	default:
+		return widget.MediumImportance
+	}
+}
+
+// reload 读取内存中的最新日志并刷新列表
+func (l *LogWindow) reload() {
+	l.lastCount.Store(llog.LogCount())
+	l.all = llog.RecentLogs()
+
+	// 已经被新日志覆盖的日志不再保留选中状态
+	if len(l.selected) > 0 && len(l.all) > 0 {
+		first := l.all[0].Seq
+		for seq := range l.selected {
+			if seq < first {
+				delete(l.selected, seq)
+			}
+		}
+	}
+	l.applyFilter()
+}
+
+// applyFilter 按级别和搜索内容筛选日志
+func (l *LogWindow) applyFilter() {
+	if l.list == nil {
+		return
+	}
+
+	minLevel := zapcore.DebugLevel - 1
+	if index := l.levelSelect.SelectedIndex(); index > 0 {
+		_ = minLevel.UnmarshalText([]byte(logLevelOptions[index]))
+	}
+	keyword := strings.ToLower(strings.TrimSpace(l.search.Text))
+
+	l.records = l.records[:0]
+	for _, record := range l.all {
+		if record.Level < minLevel {
+			continue
+		}
+		if keyword != "" && !strings.Contains(strings.ToLower(record.String()), keyword) {
+			continue
+		}
+		l.records = append(l.records, record)
+	}
+
+	l.list.Refresh()
+	if l.follow.Checked && !l.paused {
+		l.list.ScrollToBottom()
+	}
+	l.updateStatus()
+}
+
+// togglePause 暂停或继续刷新日志, 暂停时可以查看和选择日志而不会被新日志打断
+func (l *LogWindow) togglePause() {
+	l.paused = !l.paused
+	if l.paused {
+		l.pauseBtn.SetText("继续")
+		l.pauseBtn.SetIcon(theme.MediaPlayIcon())
+		l.updateStatus()
+		return
+	}
+	l.pauseBtn.SetText("暂停")
+	l.pauseBtn.SetIcon(theme.MediaPauseIcon())
+	l.reload()
+}
+
+// updateStatus 显示日志条数和选中条数
+func (l *LogWindow) updateStatus() {
+	text := fmt.Sprintf("显示 %d / %d 条, 已选中 %d 条", len(l.records), len(l.all), len(l.selected))
+	if l.paused {
+		text += " (已暂停)"
+	}
+	l.status.SetText(text)
+}
+
+// copySelected 复制选中的日志, 按时间顺序排列
+func (l *LogWindow) copySelected() {
+	if len(l.selected) == 0 {
+		l.status.SetText("请先点击选择要复制的日志")
+		return
+	}
+	var lines []string
+	for _, record := range l.all {
+		if l.selected[record.Seq] {
+			lines = append(lines, record.String())
+		}
+	}
+	l.copyLines(lines)
+}
+
+// copyAll 复制当前筛选出的全部日志
+func (l *LogWindow) copyAll() {
+	lines := make([]string, 0, len(l.records))
+	for _, record := range l.records {
+		lines = append(lines, record.String())
+	}
+	l.copyLines(lines)
+}
+
+func (l *LogWindow) copyLines(lines []string) {
+	if len(lines) == 0 {
+		l.status.SetText("没有可复制的日志")
+		return
+	}
+	l.app.Clipboard().SetContent(strings.Join(lines, "\n"))
+	l.status.SetText(fmt.Sprintf("已复制 %d 条日志", len(lines)))
+}
@@ -1,8 +1,12 @@
 package ui
 
 import (
+	"context"
 	_ "embed"
+	"errors"
+	"fmt"
 	"image/color"
+	"sync"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -14,6 +18,8 @@ import (
 
 	"tiktok_tool/capture"
 	"tiktok_tool/config"
+	"tiktok_tool/ipc"
+	"tiktok_tool/live"
 	"tiktok_tool/lkit"
 	"tiktok_tool/llog"
 )
@@ -48,6 +54,9 @@ var (
 	resourceFont = fyne.NewStaticResource("font.ttf", resourceTtf)
 )
 
+// uiLog 界面模块日志
+var uiLog = llog.Named("ui")
+
 type MainWindow struct {
 	window     fyne.Window
 	app        fyne.App
@@ -61,9 +70,22 @@ type MainWindow struct {
 	obsBtn       *widget.Button
 	autoBtn      *widget.Button
 
-	status     *widget.Label
-	restartBtn *widget.Button
-	settingBtn *widget.Button
+	status        *widget.Label
+	redactBanner  *widget.Label
+	restartBtn    *widget.Button
+	settingBtn    *widget.Button
+	profileSelect *widget.Select
+
+	// 推流监控, monitor 在抓包协程和界面线程中都会修改, 由 monitorMu 保护
+	monitorMu     sync.Mutex
+	monitor       *capture.Monitor
+	monitorWindow fyne.Window
+	healthGraph   *HealthGraph
+	healthLabel   *widget.Label
+	healthWarning bool // 上一个统计周期是否异常, 只在状态变化时记录日志
+
+	// 日志窗口
+	logWindow *LogWindow
 }
 
 type ChineseTheme struct{}
@@ -84,7 +106,8 @@ func (ChineseTheme) Size(n fyne.ThemeSizeName) float32 {
 	return theme.DefaultTheme().Size(n)
 }
 
-func NewMainWindow() {
+// NewMainWindow 创建并显示主窗口, request 为启动参数中的实例命令, 没有时为 nil
+func NewMainWindow(request *ipc.Request) {
 	myApp := app.NewWithID("com.lemon.tiktok_tool")
 	myApp.SetIcon(TikTokIconResource)
 	myApp.Settings().SetTheme(&ChineseTheme{})
@@ -94,8 +117,12 @@ func NewMainWindow() {
 	window.SetMaster()
 	window.CenterOnScreen()
 
-	if !capture.CheckNpcapInstalled() {
-		ShowInstallDialog(window)
+	if err := capture.CheckBackend(); err != nil {
+		if errors.Is(err, capture.ErrNpcapNotInstalled) {
+			ShowInstallDialog(window)
+		} else {
+			ShowBackendErrorDialog(window, err)
+		}
 		window.ShowAndRun()
 		return
 	}
@@ -109,28 +136,49 @@ func NewMainWindow() {
 		ipAddr:     widget.NewEntry(),
 	}
 
-	lkit.SafeGo(func() {
+	lkit.Go("ui.start_task", func(context.Context) {
 		w.startTask()
 	})
 
 	w.addSystemTray()
 	window.SetCloseIntercept(w.handleWindowClose)
 	w.setupUI()
+
+	// 启动时检查配置
+	w.checkConfig()
+
+	// 上次运行时崩溃过, 提示查看或导出崩溃报告
+	w.checkCrashReports()
+
+	// 再次启动程序时的命令会转发到这里执行
+	w.serveCommands(request)
+
+	// 启动时检查规则更新, 有更新时确认后应用
+	if ruleSettings := config.GetConfig().RuleSettings; ruleSettings != nil && ruleSettings.AutoCheck && ruleSettings.UpdateURL != "" {
+		checkRuleUpdate(window, ruleSettings.UpdateURL, true, func(updated []string) {
+			w.status.SetText(ruleAppliedText(updated))
+		})
+	}
+
+	// 配置修改后无需重启即可生效
+	config.Subscribe(func(_, _ *config.Config) {
+		fyne.Do(w.applyConfig)
+	})
 	window.ShowAndRun()
 }
 
 func (w *MainWindow) startTask() {
 	if config.GetConfig().BaseSettings.OpenLiveWhenStart == false ||
-		config.GetConfig().PathSettings.LiveCompanionPath == "" ||
-		config.GetConfig().PathSettings.OBSLaunchPath == "" {
+		config.GetConfig().Profile().PathSettings.LiveCompanionPath == "" ||
+		config.GetConfig().Profile().PathSettings.OBSLaunchPath == "" {
 		return
 	}
-	err1 := w.startLiveCompanion(false)
-	err2 := w.startOBS(false)
+	err1 := live.StartCompanion(false)
+	err2 := live.StartOBS(false)
 	if err1 == nil && err2 == nil {
 		return
 	}
-	llog.WarnF("程序启动时, 启动直播伴侣失败: %v, OBS失败: %v", err1, err2)
+	uiLog.Warn("程序启动时自启动程序失败", llog.NamedErr("live_companion_error", err1), llog.NamedErr("obs_error", err2))
 	fyne.Do(func() {
 		w.status.SetText("程序启动时, 自启动程序失败")
 	})
@@ -149,24 +197,51 @@ func (w *MainWindow) addSystemTray() {
 	menuItem1.Icon = TikTokIconResource
 
 	menuItem2 := fyne.NewMenuItem("启动直播伴侣", w.handleStartLiveCompanion)
-	if config.GetConfig().PathSettings.LiveCompanionPath == "" || !lkit.IsAdmin {
+	if config.GetConfig().Profile().PathSettings.LiveCompanionPath == "" || !lkit.IsAdmin {
 		menuItem2.Disabled = true
 	}
 	menuItem2.Icon = LiveIconResource
 
 	menuItem3 := fyne.NewMenuItem("启动OBS", func() {
-		_ = w.startOBS(false)
+		_ = live.StartOBS(false)
 	})
-	if config.GetConfig().PathSettings.OBSLaunchPath == "" {
+	if config.GetConfig().Profile().PathSettings.OBSLaunchPath == "" {
 		menuItem3.Disabled = true
 	}
 	menuItem3.Icon = OBSIconResource
 
+	menuItem4 := fyne.NewMenuItem("推流监控", w.showMonitorWindow)
+	menuItem6 := fyne.NewMenuItem("查看日志", w.showLogWindow)
+	menuItem7 := fyne.NewMenuItem("导出诊断包", func() {
+		w.window.Show()
+		ShowDiagnosticsDialog(w.window)
+	})
+
+	// 配置方案切换
+	cfg := config.GetConfig()
+	active := cfg.Profile().Name
+	profileItems := make([]*fyne.MenuItem, 0, len(cfg.Profiles))
+	for _, name := range cfg.ProfileNames() {
+		item := fyne.NewMenuItem(name, func() {
+			w.switchProfile(name)
+		})
+		item.Checked = name == active
+		profileItems = append(profileItems, item)
+	}
+	menuItem5 := fyne.NewMenuItem("切换方案", nil)
+	menuItem5.ChildMenu = fyne.NewMenu("", profileItems...)
+
 	m := fyne.NewMenu("tiktok_tool",
 		menuItem1,
 		fyne.NewMenuItemSeparator(),
 		menuItem2,
 		menuItem3,
+		fyne.NewMenuItemSeparator(),
+		menuItem4,
+		menuItem6,
+		menuItem5,
+		fyne.NewMenuItemSeparator(),
+		menuItem7,
 	)
 	desk.SetSystemTrayMenu(m)
 }
@@ -211,44 +286,24 @@ func (w *MainWindow) setupUI() {
 		w.status.SetText("已复制推流IP地址")
 	})
 
-	cfg := config.GetConfig()
-
 	// 抓包按钮
 	w.captureBtn = widget.NewButtonWithIcon("开始抓包", theme.MediaPlayIcon(), w.handleCapture)
 	w.captureBtn.Importance = widget.HighImportance
 
 	// 导入OBS配置按钮
 	w.importOBSBtn = widget.NewButtonWithIcon("导入OBS", theme.DocumentSaveIcon(), w.handleImportOBS)
-	if cfg.PathSettings.OBSConfigPath == "" {
-		w.importOBSBtn.Disable()
-	}
 
 	// 启动直播伴侣按钮
 	w.liveBtn = widget.NewButtonWithIcon("启动直播伴侣", LiveIconResource, w.handleStartLiveCompanion)
-	if cfg.PathSettings.LiveCompanionPath == "" {
-		w.liveBtn.Disable()
-		w.liveBtn.SetIcon(LiveIconResourceDis)
-	}
 
 	// 启动OBS
 	w.obsBtn = widget.NewButtonWithIcon("启动OBS", OBSIconResource, w.handleStartOBS)
-	if cfg.PathSettings.OBSLaunchPath == "" {
-		w.obsBtn.Disable()
-		w.obsBtn.SetIcon(OBSIconResourceDis)
-	}
 
 	// 自动推流按钮
-	w.autoBtn = widget.NewButtonWithIcon("一键开播", TikTokIconResourceDis, nil)
-	if lkit.IsAdmin &&
-		cfg.PathSettings.OBSConfigPath != "" &&
-		cfg.PathSettings.LiveCompanionPath != "" &&
-		cfg.PathSettings.OBSLaunchPath != "" &&
-		cfg.PathSettings.PluginScriptPath != "" {
-		w.autoBtn.SetIcon(TikTokIconResource)
-		w.autoBtn.OnTapped = w.handleAutoStart
-	} else {
-		w.autoBtn.Disable()
-	}
+	w.autoBtn = widget.NewButtonWithIcon("一键开播", TikTokIconResourceDis, w.handleAutoStart)
+
+	// 根据配置的路径设置按钮状态
+	w.refreshPathButtons()
 
 	serverContainer := container.NewBorder(nil, nil, nil, copyServerBtn, w.serverAddr)
 	streamContainer := container.NewBorder(nil, nil, nil, copyStreamBtn, w.streamKey)
@@ -272,10 +327,18 @@ func (w *MainWindow) setupUI() {
 	})
 	helpBtn.Importance = widget.LowImportance
 
+	// 创建日志按钮
+	logBtn := widget.NewButtonWithIcon("", theme.ListIcon(), w.showLogWindow)
+	logBtn.Importance = widget.LowImportance
+
 	// 创建设置按钮
 	w.settingBtn = widget.NewButtonWithIcon("设置", theme.SettingsIcon(), w.settingWindow)
 	w.settingBtn.Importance = widget.LowImportance
 
+	// 配置方案切换
+	w.profileSelect = widget.NewSelect(nil, nil)
+	w.refreshProfileSelect()
+
 	// 创建权限状态标签
 	permissionStatus := widget.NewLabel("User")
 	if lkit.IsAdmin {
@@ -292,12 +355,19 @@ func (w *MainWindow) setupUI() {
 		permissionStatus,
 		w.status,
 		layout.NewSpacer(),
+		w.profileSelect,
 		w.restartBtn,
+		logBtn,
 		helpBtn,
 		w.settingBtn,
 	)
 
+	// 关闭日志脱敏时显示警告
+	w.redactBanner = newRedactBanner()
+	w.refreshRedactBanner()
+
 	content := container.NewVBox(
+		w.redactBanner,
 		container.NewPadded(mainForm),
 		container.NewPadded(actionContainer),
 		container.NewPadded(openContainer),
@@ -310,21 +380,113 @@ func (w *MainWindow) setupUI() {
 	w.window.SetContent(container.NewPadded(content))
 }
 
+// refreshPathButtons 根据当前配置刷新依赖路径设置的按钮, 抓包过程中保持禁用的按钮不变
+func (w *MainWindow) refreshPathButtons() {
+	cfg := config.GetConfig().Profile().PathSettings
+
+	if cfg.OBSConfigPath == "" || capture.IsCapturing() {
+		w.importOBSBtn.Disable()
+	} else {
+		w.importOBSBtn.Enable()
+	}
+
+	if cfg.LiveCompanionPath == "" {
+		w.liveBtn.Disable()
+		w.liveBtn.SetIcon(LiveIconResourceDis)
+	} else {
+		w.liveBtn.Enable()
+		w.liveBtn.SetIcon(LiveIconResource)
+	}
+
+	if cfg.OBSLaunchPath == "" {
+		w.obsBtn.Disable()
+		w.obsBtn.SetIcon(OBSIconResourceDis)
+	} else {
+		w.obsBtn.Enable()
+		w.obsBtn.SetIcon(OBSIconResource)
+	}
+
+	if lkit.IsAdmin &&
+		cfg.OBSConfigPath != "" &&
+		cfg.LiveCompanionPath != "" &&
+		cfg.OBSLaunchPath != "" &&
+		cfg.PluginScriptPath != "" &&
+		!capture.IsCapturing() {
+		w.autoBtn.Enable()
+		w.autoBtn.SetIcon(TikTokIconResource)
+	} else {
+		w.autoBtn.Disable()
+		w.autoBtn.SetIcon(TikTokIconResourceDis)
+	}
+}
+
+// checkConfig 检查配置, 有问题时提示用户
+func (w *MainWindow) checkConfig() {
+	var names []string
+	if devices, err := capture.ListDevices(); err == nil {
+		names = make([]string, 0, len(devices))
+		for _, device := range devices {
+			names = append(names, device.Description)
+		}
+	}
+
+	problems := config.GetConfig().Validate(names)
+	if len(problems) == 0 {
+		return
+	}
+	for _, problem := range problems {
+		uiLog.Warn("配置问题", llog.String("field", problem.Field), llog.String("problem", problem.Message))
+	}
+	ShowConfigProblemsDialog(w.window, problems)
+}
+
+// refreshProfileSelect 刷新配置方案下拉框
+func (w *MainWindow) refreshProfileSelect() {
+	cfg := config.GetConfig()
+	w.profileSelect.OnChanged = nil
+	w.profileSelect.SetOptions(cfg.ProfileNames())
+	w.profileSelect.SetSelected(cfg.Profile().Name)
+	w.profileSelect.OnChanged = w.switchProfile
+}
+
+// switchProfile 切换配置方案
+func (w *MainWindow) switchProfile(name string) {
+	if name == config.GetConfig().Profile().Name {
+		return
+	}
+	if err := config.SwitchProfile(name); err != nil {
+		w.NewErrorDialog(fmt.Errorf("切换配置方案失败: %v", err))
+		w.refreshProfileSelect()
+		return
+	}
+	w.status.SetText("已切换到方案: " + name)
+	uiLog.Info("切换配置方案", llog.String("profile", name))
+}
+
+// refreshRedactBanner 根据日志是否脱敏显示或隐藏警告
+func (w *MainWindow) refreshRedactBanner() {
+	if llog.IsUnredacted() {
+		w.redactBanner.Show()
+	} else {
+		w.redactBanner.Hide()
+	}
+	if w.logWindow != nil {
+		w.logWindow.refreshRedactBanner()
+	}
+}
+
+// applyConfig 配置变化后刷新界面, 正则与网卡设置在下次抓包时生效
+func (w *MainWindow) applyConfig() {
+	w.refreshRedactBanner()
+	w.refreshPathButtons()
+	w.refreshProfileSelect()
+	w.addSystemTray()
+}
+
 func (w *MainWindow) settingWindow() {
 	w.window.Hide()
 	ShowSettingsWindow(w.app, func() { w.window.Show() }, func(text string) {
-		if lkit.IsAdmin {
-			text += "\n当前为管理员权限, 请手动重启应用"
-		}
-		dialog := w.NewCustomDialog("保存成功", "重启",
-			container.NewCenter(widget.NewLabel(text)),
-		)
-		closeButton := widget.NewButton("关闭程序", w.app.Quit)
-		restartButton := widget.NewButton("重启程序", w.restartApp)
-		if lkit.IsAdmin {
-			restartButton.Disable()
-		}
-		restartButton.Importance = widget.HighImportance
-		dialog.SetButtons([]fyne.CanvasObject{closeButton, restartButton})
+		w.status.SetText(text)
+		w.NewInfoDialog("保存成功", text)
 	})
 }
@@ -1,12 +1,10 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"os/exec"
-	"runtime"
-	"strings"
-	"syscall"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -17,6 +15,7 @@ import (
 
 	"tiktok_tool/capture"
 	"tiktok_tool/config"
+	"tiktok_tool/live"
 	"tiktok_tool/lkit"
 	"tiktok_tool/llog"
 )
@@ -35,25 +34,13 @@ func (w *MainWindow) resetCaptureBtn() {
 	w.settingBtn.Importance = widget.LowImportance
 	w.settingBtn.Refresh()
 
-	cfg := config.GetConfig().PathSettings
-	if cfg.OBSConfigPath != "" {
-		w.importOBSBtn.Enable()
-		w.importOBSBtn.Refresh()
-	} else {
-		w.importOBSBtn.Disable()
-		w.importOBSBtn.Refresh()
-	}
-
-	w.autoBtn.SetIcon(TikTokIconResource)
-	w.autoBtn.Enable()
-	w.autoBtn.Refresh()
+	w.refreshPathButtons()
 }
 
 func (w *MainWindow) handleCapture() {
-	if !capture.IsCapturing {
+	if capture.BeginCapture() {
 		// 开始抓包
-		capture.IsCapturing = true
-		capture.StopCapture = make(chan struct{})
+		w.stopPushMonitor()
 
 		// 更改按钮样式为停止状态
 		w.captureBtn.SetText("停止抓包")
@@ -75,8 +62,8 @@ func (w *MainWindow) handleCapture() {
 
 		w.status.SetText("正在抓包...")
 
-		llog.Debug("服务器地址正则表达式: ", config.GetConfig().BaseSettings.ServerRegex)
-		llog.Debug("推流码正则表达式: ", config.GetConfig().BaseSettings.StreamKeyRegex)
+		profile := config.GetConfig().Profile()
+		uiLog.Debug("使用正则开始抓包", llog.String("server_regex", profile.ServerRegex), llog.String("stream_key_regex", profile.StreamKeyRegex))
 
 		capture.StartCapture(
 			func(server string) {
@@ -93,6 +80,7 @@ func (w *MainWindow) handleCapture() {
 				fyne.Do(func() {
 					w.ipAddr.SetText(ip)
 				})
+				w.startPushMonitor(ip)
 			},
 			func(err error) {
 				fyne.Do(func() {
@@ -118,10 +106,8 @@ func (w *MainWindow) handleCapture() {
 
 // restartApp 重启应用
 func (w *MainWindow) restartApp() {
-	llog.Info("重启应用")
-	if capture.IsCapturing {
-		capture.StopCapturing()
-	}
+	uiLog.Info("重启应用")
+	capture.StopCapturing()
 
 	if lkit.IsAdmin {
 		w.NewErrorDialog(fmt.Errorf("当前为管理员权限, 请手动重启应用"))
@@ -135,10 +121,7 @@ func (w *MainWindow) restartApp() {
 	}
 
 	cmd := exec.Command(exe)
-
-	if runtime.GOOS == "windows" {
-		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-	}
+	lkit.HideWindow(cmd)
 
 	err = cmd.Start()
 	if err != nil {
@@ -163,11 +146,14 @@ func (w *MainWindow) handleWindowClose() {
 	w.window.Close()
 }
 
+// autoLog 一键开播模块日志
+var autoLog = llog.Named("auto")
+
 // handleAutoStart 处理一键开播功能
 // 流程：启动直播伴侣 -> 开始抓包 -> 模拟点击开始直播 -> 获取推流信息 -> 导入OBS -> 启动OBS -> 关闭直播伴侣
 func (w *MainWindow) handleAutoStart() {
 	// 检查所有必要的配置
-	if err := w.validateAutoStartConfig(); err != nil {
+	if err := live.ValidateOneClick(); err != nil {
 		w.NewErrorDialog(err)
 		return
 	}
@@ -186,61 +172,11 @@ func (w *MainWindow) handleAutoStart() {
 	confirmDialog.SetConfirmText("开始")
 }
 
-// validateAutoStartConfig 验证一键开播所需的配置
-func (w *MainWindow) validateAutoStartConfig() error {
-	cfg := config.GetConfig().PathSettings
-
-	// 检查直播伴侣路径
-	if strings.TrimSpace(cfg.LiveCompanionPath) == "" {
-		return fmt.Errorf("请先在设置中配置直播伴侣启动路径")
-	}
-	if _, err := os.Stat(cfg.LiveCompanionPath); os.IsNotExist(err) {
-		return fmt.Errorf("直播伴侣文件不存在：%s", cfg.LiveCompanionPath)
-	}
-
-	// 检查OBS路径
-	if strings.TrimSpace(cfg.OBSLaunchPath) == "" {
-		return fmt.Errorf("请先在设置中配置OBS启动路径")
-	}
-	if _, err := os.Stat(cfg.OBSLaunchPath); os.IsNotExist(err) {
-		return fmt.Errorf("OBS文件不存在：%s", cfg.OBSLaunchPath)
-	}
-
-	// 检查OBS配置路径
-	if strings.TrimSpace(cfg.OBSConfigPath) == "" {
-		return fmt.Errorf("请先在设置中配置OBS配置文件路径")
-	}
-	if _, err := os.Stat(cfg.OBSConfigPath); os.IsNotExist(err) {
-		return fmt.Errorf("OBS配置文件不存在：%s", cfg.OBSConfigPath)
-	}
-
-	// 检查auto.exe脚本路径
-	if strings.TrimSpace(cfg.PluginScriptPath) == "" {
-		return fmt.Errorf("请先在设置中配置自动化脚本路径")
-	}
-	if _, err := os.Stat(cfg.PluginScriptPath); os.IsNotExist(err) {
-		return fmt.Errorf("自动化脚本文件不存在：%s", cfg.PluginScriptPath)
-	}
-
-	// 检查是否已有程序在运行
-	if pid := isOBSRunning(); pid != -1 && config.GetConfig().BaseSettings.OBSWsIp == "" {
-		return fmt.Errorf("OBS已在运行，请先关闭后再使用一键开播")
-	}
-	// if pid := isLiveCompanionRunning(); pid != -1 {
-	// 	return fmt.Errorf("直播伴侣已在运行，请先关闭后再使用一键开播")
-	// }
-
-	// 检查是否正在抓包
-	if capture.IsCapturing {
-		return fmt.Errorf("当前正在抓包，请先停止抓包后再使用一键开播")
-	}
-
-	return nil
-}
-
 // executeAutoStartFlow 执行一键开播流程
 func (w *MainWindow) executeAutoStartFlow() {
-	llog.Debug("开始执行一键开播流程")
+	// 本次一键开播期间的日志都会带上流程ID
+	unbind := llog.Bind(llog.RunIDKey, llog.NewID())
+	autoLog.Debug("开始执行一键开播流程", llog.String("profile", config.GetConfig().Profile().Name))
 	// 创建进度对话框
 	progressLabel := widget.NewLabel("正在执行一键开播流程...")
 	progressBar := widget.NewProgressBar()
@@ -266,150 +202,61 @@ func (w *MainWindow) executeAutoStartFlow() {
 		w.status.SetText("一键开播流程已完成！")
 	}
 
-	// 在后台执行流程
-	lkit.SafeGo(func() {
-		w.autoStart(progressDialog, progressLabel, progressBar, onSuccess)
+	// 在后台执行流程, 程序退出时取消
+	lkit.Go("auto.one_click", func(ctx context.Context) {
+		defer unbind()
+		w.autoStart(ctx, progressDialog, progressLabel, progressBar, onSuccess)
 	})
 }
 
-func (w *MainWindow) autoStart(progressDialog *dialog.CustomDialog, progressLabel *widget.Label, progressBar *widget.ProgressBar, onSuccess func()) {
-	var progressError error
-	defer func() {
-		if progressError == nil {
-			return
-		}
-		capture.StopCapturing()
-		fyne.Do(func() {
-			progressDialog.Hide()
-			w.NewErrorDialog(progressError)
-		})
-	}()
-
-	// 启动直播伴侣
-	fyne.Do(func() {
-		progressLabel.SetText("正在启动直播伴侣...")
-		progressBar.SetValue(1.0 / 7.0)
-	})
-	if err := w.startLiveCompanion(false); err != nil {
-		progressError = err
-		return
+func (w *MainWindow) autoStart(ctx context.Context, progressDialog *dialog.CustomDialog, progressLabel *widget.Label, progressBar *widget.ProgressBar, onSuccess func()) {
+	hooks := live.OneClickHooks{
+		CaptureHooks: live.CaptureHooks{
+			OnServer: func(server string) {
+				fyne.DoAndWait(func() {
+					w.serverAddr.SetText(server)
+				})
+			},
+			OnStreamKey: func(streamKey string) {
+				fyne.DoAndWait(func() {
+					w.streamKey.SetText(streamKey)
+				})
+			},
+			OnStreamIP: func(ip string) {
+				fyne.DoAndWait(func() {
+					w.ipAddr.SetText(ip)
+				})
+				w.startPushMonitor(ip)
+			},
+		},
+		OnProgress: func(text string, value float64) {
+			fyne.Do(func() {
+				progressLabel.SetText(text)
+				progressBar.SetValue(value)
+			})
+		},
 	}
 
-	time.Sleep(1 * time.Second)
-
-	// 开始抓包
+	// 清空上次的推流信息
+	w.stopPushMonitor()
 	fyne.Do(func() {
-		progressLabel.SetText("正在抓包...")
-		progressBar.SetValue(2.0 / 7.0)
+		w.serverAddr.SetText("")
+		w.streamKey.SetText("")
 	})
 
-	onGetAll := make(chan struct{})
-	channelClosed := false
-	if err := w.startCaptureForAuto(func() {
-		if !channelClosed {
-			channelClosed = true
-			close(onGetAll)
+	if _, err := live.RunOneClick(ctx, hooks); err != nil {
+		// 程序退出时界面已关闭, 不再提示
+		if ctx.Err() != nil {
+			autoLog.Info("一键开播流程已取消", llog.Err(err))
+			return
 		}
-	}); err != nil {
-		progressError = err
-		return
-	}
-
-	// 模拟点击开始直播
-	fyne.Do(func() {
-		progressLabel.SetText("正在模拟点击开始直播...")
-		progressBar.SetValue(3.0 / 7.0)
-	})
-
-	if err := w.simulateClickStartLive(); err != nil {
-		progressError = err
-		return
-	}
-
-	timeout := time.After(20 * time.Second)
-	select {
-	case <-onGetAll:
-		llog.Debug("成功获取到推流信息: ", w.serverAddr.Text, w.streamKey.Text)
-	case <-timeout:
-		progressError = fmt.Errorf("获取推流信息超时，请检查配置文件或检查网络连接或重试")
-		return
-	}
-
-	time.Sleep(500 * time.Millisecond)
-
-	// 导入OBS配置
-	fyne.Do(func() {
-		progressLabel.SetText("正在导入OBS配置...")
-		progressBar.SetValue(4.0 / 7.0)
-	})
-	if err := w.importOBSConfigForAuto(); err != nil {
-		progressError = fmt.Errorf("导入OBS配置失败：%v", err)
-		return
-	}
-
-	// 启动OBS
-	fyne.Do(func() {
-		progressLabel.SetText("正在启动OBS...")
-		progressBar.SetValue(5.0 / 7.0)
-	})
-	if err := w.startOBS(false); err != nil {
-		progressError = err
-		return
-	}
-
-	// 关闭直播伴侣
-	fyne.Do(func() {
-		progressLabel.SetText("正在关闭直播伴侣...")
-		progressBar.SetValue(6.0 / 7.0)
-	})
-	if err := w.closeLiveCompanionForAuto(); err != nil {
-		progressError = err
+		fyne.Do(func() {
+			progressDialog.Hide()
+			w.NewErrorDialog(err)
+		})
 		return
 	}
 
 	// 完成
-	fyne.Do(func() {
-		progressLabel.SetText("一键开播完成！")
-		progressBar.SetValue(1)
-		onSuccess()
-	})
-}
-
-// startCaptureForAuto 为自动流程开始抓包
-func (w *MainWindow) startCaptureForAuto(onGetAll func()) error {
-	// 开始抓包
-	capture.IsCapturing = true
-	capture.StopCapture = make(chan struct{})
-
-	// 清空数据
-	fyne.Do(func() {
-		w.serverAddr.SetText("")
-		w.streamKey.SetText("")
-	})
-
-	var err error
-	// 启动抓包
-	capture.StartCapture(
-		func(server string) {
-			fyne.DoAndWait(func() {
-				w.serverAddr.SetText(server)
-			})
-		},
-		func(streamKey string) {
-			fyne.DoAndWait(func() {
-				w.streamKey.SetText(streamKey)
-			})
-		},
-		func(ip string) {
-			fyne.DoAndWait(func() {
-				w.ipAddr.SetText(ip)
-			})
-		},
-		func(err error) {
-			err = fmt.Errorf("抓包过程中发生错误: %v", err)
-		},
-		onGetAll,
-	)
-
-	return err
+	fyne.Do(onSuccess)
 }
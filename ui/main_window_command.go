@@ -0,0 +1,82 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+
+	"tiktok_tool/capture"
+	"tiktok_tool/config"
+	"tiktok_tool/ipc"
+	"tiktok_tool/live"
+	"tiktok_tool/llog"
+)
+
+// serveCommands 监听其他实例转发的命令, 启动参数中带有命令时在界面显示后执行
+func (w *MainWindow) serveCommands(request *ipc.Request) {
+	if err := ipc.Serve(w.handleCommand); err != nil {
+		uiLog.Warn("启动本地命令接口失败, 再次启动程序时无法转发命令", llog.Err(err))
+	}
+	if request == nil {
+		return
+	}
+	w.app.Lifecycle().SetOnStarted(func() {
+		if response := w.executeCommand(*request); !response.OK {
+			w.NewErrorDialog(fmt.Errorf("执行启动命令失败: %s", response.Error))
+		}
+	})
+}
+
+// handleCommand 执行其他实例转发的命令, 在界面线程中执行
+func (w *MainWindow) handleCommand(request ipc.Request) ipc.Response {
+	var response ipc.Response
+	fyne.DoAndWait(func() {
+		response = w.executeCommand(request)
+	})
+	return response
+}
+
+// executeCommand 执行命令, 指定了配置方案时先切换方案
+func (w *MainWindow) executeCommand(request ipc.Request) ipc.Response {
+	if request.Profile != "" && request.Profile != config.GetConfig().Profile().Name {
+		if capture.IsCapturing() {
+			return ipc.Failed(fmt.Errorf("正在抓包, 无法切换配置方案"))
+		}
+		if err := config.SwitchProfile(request.Profile); err != nil {
+			return ipc.Failed(err)
+		}
+		w.refreshProfileSelect()
+		w.status.SetText("已切换到方案: " + request.Profile)
+		uiLog.Info("切换配置方案", llog.String("profile", request.Profile))
+	}
+	profile := config.GetConfig().Profile().Name
+
+	switch request.Command {
+	case ipc.CommandShow:
+		w.window.Show()
+		w.window.RequestFocus()
+		return ipc.Succeeded("已显示窗口, 方案: " + profile)
+	case ipc.CommandStartCapture:
+		if capture.IsCapturing() {
+			return ipc.Succeeded("已在抓包中")
+		}
+		w.handleCapture()
+		return ipc.Succeeded("已开始抓包, 方案: " + profile)
+	case ipc.CommandStopCapture:
+		if !capture.IsCapturing() {
+			return ipc.Succeeded("当前未在抓包")
+		}
+		w.handleCapture()
+		return ipc.Succeeded("已停止抓包")
+	case ipc.CommandOneClick:
+		// 通过命令执行时不再确认, 配置有问题时返回错误
+		if err := live.ValidateOneClick(); err != nil {
+			return ipc.Failed(err)
+		}
+		w.window.Show()
+		w.executeAutoStartFlow()
+		return ipc.Succeeded("已开始一键开播, 方案: " + profile)
+	default:
+		return ipc.Failed(fmt.Errorf("不支持的命令: %s", request.Command))
+	}
+}
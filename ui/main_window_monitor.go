@@ -0,0 +1,141 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/capture"
+	"tiktok_tool/config"
+	"tiktok_tool/llog"
+)
+
+// 折线图保留最近60秒的数据
+const healthGraphPoints = 60
+
+// startPushMonitor 获取到推流IP后开始监控推流连接
+func (w *MainWindow) startPushMonitor(serverAddr string) {
+	cfg := config.GetConfig().MonitorSettings
+	if cfg == nil || (!cfg.Enable && !cfg.TrackSession) || serverAddr == "" {
+		return
+	}
+
+	w.monitorMu.Lock()
+	defer w.monitorMu.Unlock()
+	w.stopMonitorLocked()
+
+	var onSample func(capture.HealthSample)
+	if cfg.Enable {
+		onSample = func(sample capture.HealthSample) {
+			fyne.Do(func() {
+				w.updateHealth(sample)
+			})
+		}
+	}
+
+	var onEvent func(capture.PushEvent)
+	if cfg.TrackSession {
+		onEvent = func(event capture.PushEvent) {
+			fyne.Do(func() {
+				w.notifyPushEvent(event)
+			})
+		}
+	}
+
+	monitor, err := capture.StartMonitor(serverAddr, onSample, onEvent)
+	if err != nil {
+		uiLog.Warn("启动推流监控失败", llog.Err(err))
+		fyne.Do(func() {
+			w.status.SetText("启动推流监控失败")
+		})
+		return
+	}
+
+	w.monitor = monitor
+	if !cfg.Enable {
+		return
+	}
+	fyne.Do(func() {
+		w.showMonitorWindow()
+		w.healthGraph.Clear()
+		w.healthWarning = false
+		w.healthLabel.SetText("等待OBS开始推流...")
+	})
+}
+
+// stopPushMonitor 停止推流监控
+func (w *MainWindow) stopPushMonitor() {
+	w.monitorMu.Lock()
+	defer w.monitorMu.Unlock()
+	w.stopMonitorLocked()
+}
+
+// stopMonitorLocked 停止推流监控, 调用方需持有 monitorMu
+func (w *MainWindow) stopMonitorLocked() {
+	if w.monitor == nil {
+		return
+	}
+	w.monitor.Stop()
+	w.monitor = nil
+}
+
+// showMonitorWindow 显示推流监控窗口
+func (w *MainWindow) showMonitorWindow() {
+	if w.monitorWindow != nil {
+		w.monitorWindow.Show()
+		return
+	}
+
+	w.healthGraph = NewHealthGraph(healthGraphPoints)
+	w.healthLabel = widget.NewLabel("推流监控未启动")
+	w.healthLabel.Wrapping = fyne.TextWrapWord
+
+	window := w.app.NewWindow("推流监控")
+	window.Resize(fyne.NewSize(380, 220))
+	window.SetFixedSize(true)
+	window.SetCloseIntercept(window.Hide)
+	window.SetContent(container.NewPadded(container.NewBorder(nil, w.healthLabel, nil, nil, w.healthGraph)))
+	window.Show()
+
+	w.monitorWindow = window
+}
+
+// updateHealth 更新推流健康数据
+func (w *MainWindow) updateHealth(sample capture.HealthSample) {
+	if w.healthGraph == nil {
+		return
+	}
+
+	warning := len(sample.Warnings) > 0
+	w.healthGraph.Add(sample.BitrateKbps, warning)
+
+	rtt := "-"
+	if sample.RTT > 0 {
+		rtt = fmt.Sprintf("%dms", sample.RTT.Milliseconds())
+	}
+	text := fmt.Sprintf("码率: %.0fkbps  重传: %.1f%%  零窗口: %d  RTT: %s",
+		sample.BitrateKbps, sample.RetransPercent, sample.ZeroWindows, rtt)
+
+	if warning {
+		warnText := strings.Join(sample.Warnings, ", ")
+		text += "\n⚠ " + warnText
+		w.status.SetText("推流异常: " + sample.Warnings[0])
+		if !w.healthWarning {
+			uiLog.Warn("推流状态异常", llog.String("warnings", warnText), llog.Int("bitrate_kbps", int(sample.BitrateKbps)))
+		}
+	} else if w.healthWarning {
+		uiLog.Info("推流状态恢复正常", llog.Int("bitrate_kbps", int(sample.BitrateKbps)))
+	}
+	w.healthWarning = warning
+	w.healthLabel.SetText(text)
+}
+
+// notifyPushEvent 推流开始/中断/结束时通知用户
+func (w *MainWindow) notifyPushEvent(event capture.PushEvent) {
+	text := capture.FormatPushEvent(event)
+	w.status.SetText(event.Type.String() + ": " + event.Reason)
+	w.app.SendNotification(fyne.NewNotification(event.Type.String(), text))
+}
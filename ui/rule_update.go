@@ -0,0 +1,99 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/lkit"
+	"tiktok_tool/llog"
+	"tiktok_tool/rules"
+)
+
+// 检查规则包更新的超时时间
+const ruleCheckTimeout = 20 * time.Second
+
+// checkRuleUpdate 检查规则包更新, 有更新时显示更新说明, 确认后应用
+// quiet 为true时(启动时自动检查)没有更新或检查失败不提示
+func checkRuleUpdate(window fyne.Window, url string, quiet bool, onApplied func(updated []string)) {
+	lkit.Go("rules.check", func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, ruleCheckTimeout)
+		defer cancel()
+
+		update, err := rules.Check(ctx, url)
+		if err != nil {
+			uiLog.Warn("检查规则更新失败", llog.Err(err))
+		}
+		fyne.Do(func() {
+			switch {
+			case err != nil:
+				if !quiet {
+					showRuleError(window, fmt.Errorf("检查规则更新失败: %v", err))
+				}
+			case update == nil:
+				if !quiet {
+					infoDialog := dialog.NewInformation("检查规则更新", "当前规则已是最新", window)
+					infoDialog.Resize(SettingsWindowDialogSize)
+					infoDialog.Show()
+				}
+			default:
+				showRuleUpdateDialog(window, update, onApplied)
+			}
+		})
+	})
+}
+
+// showRuleUpdateDialog 显示规则包的更新说明和正则变化, 确认后应用
+func showRuleUpdateDialog(window fyne.Window, update *rules.Update, onApplied func(updated []string)) {
+	from := "内置规则"
+	if update.Current != nil {
+		from = fmt.Sprintf("版本 %d", update.Current.Version)
+	}
+	title := widget.NewLabel(fmt.Sprintf("%s -> 版本 %d (发布于 %s)",
+		from, update.Pack.Version, update.Pack.PublishedAt.Local().Format(time.DateTime)))
+	title.TextStyle = fyne.TextStyle{Bold: true}
+
+	items := container.NewVBox(widget.NewRichTextFromMarkdown(update.Pack.Changelog))
+	for _, change := range update.Changes() {
+		label := widget.NewLabel(fmt.Sprintf("%s:\n  %s\n→ %s", change.Key, change.Old, change.New))
+		label.Wrapping = fyne.TextWrapBreak
+		items.Add(label)
+	}
+	note := widget.NewLabel("只会更新使用默认正则的配置方案, 自定义的正则保持不变, 更新后可在正则设置中回滚")
+	note.Wrapping = fyne.TextWrapWord
+
+	content := container.NewBorder(title, note, nil, nil, container.NewVScroll(items))
+	confirmDialog := dialog.NewCustomConfirm("发现规则更新", "更新", "暂不更新", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		updated, err := rules.Apply(update)
+		if err != nil {
+			showRuleError(window, err)
+			return
+		}
+		onApplied(updated)
+	}, window)
+	confirmDialog.Resize(ProblemDialogSize)
+	confirmDialog.Show()
+}
+
+// showRuleError 显示规则更新错误
+func showRuleError(window fyne.Window, err error) {
+	errorDialog := dialog.NewError(err, window)
+	errorDialog.Resize(SettingsWindowDialogSize)
+	errorDialog.Show()
+}
+
+// ruleAppliedText 规则更新或回滚后的提示
+func ruleAppliedText(updated []string) string {
+	if len(updated) == 0 {
+		return "规则已更新, 当前方案均使用自定义正则, 未修改"
+	}
+	return fmt.Sprintf("规则已更新, 已更新方案: %v, 将在下次抓包时生效", updated)
+}
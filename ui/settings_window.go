@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -9,11 +10,12 @@ import (
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
-	"github.com/google/gopacket/pcap"
 
+	"tiktok_tool/capture"
 	"tiktok_tool/config"
 )
 
@@ -53,10 +55,16 @@ type SettingsWindow struct {
 	// 网卡
 	networkList     *widget.CheckGroup
 	selectedDevices []string
+	deviceNames     []string // 当前可用的网卡, 获取失败时为nil
+	pushMonitor     *widget.Check
+	trackSession    *widget.Check
 
 	// 正则
 	serverRegex    *widget.Entry
 	streamKeyRegex *widget.Entry
+	ruleUpdateURL  *widget.Entry
+	ruleAutoCheck  *widget.Check
+	ruleVersion    *widget.Label
 
 	// 路径
 	obsLaunchPath     *widget.Entry
@@ -70,9 +78,17 @@ type SettingsWindow struct {
 	pluginWaitAfterFound    *NumericalEntry
 	pluginTimeout           *NumericalEntry
 
+	// 配置方案
+	profileList  *widget.List
+	profileNames []string
+
 	// 日志配置
-	logToFile *widget.Check
-	logLevel  *widget.Select
+	logToFile        *widget.Check
+	logLevel         *widget.Select
+	logUnredacted    *widget.Check
+	logRedactWarning *widget.Label
+	logRemoteURL     *widget.Entry
+	logRemoteLevel   *widget.Select
 
 	// 窗口行为配置
 	minimizeOnClose   *widget.Check
@@ -112,12 +128,15 @@ func (w *SettingsWindow) close() {
 // setupUI 设置用户界面
 func (w *SettingsWindow) setupUI() {
 	// 获取所有网卡
-	devices, _ := pcap.FindAllDevs()
+	devices, err := capture.ListDevices()
 
 	names := make([]string, 0)
 	for _, device := range devices {
 		names = append(names, device.Description)
 	}
+	if err == nil {
+		w.deviceNames = names
+	}
 
 	// 创建网卡列表
 	alreadyCheck := make([]string, 0)
@@ -126,53 +145,75 @@ func (w *SettingsWindow) setupUI() {
 	})
 	w.networkList.SetSelected(w.selectedDevices)
 
-	// 创建正则表达式输入框
+	// 创建推流监控开关
+	w.pushMonitor = widget.NewCheck("获取到推流地址后监控推流连接状态", nil)
+	w.trackSession = widget.NewCheck("跟踪推流开始/中断/结束并记录推流时长", nil)
+	if cfg := config.GetConfig().MonitorSettings; cfg != nil {
+		w.pushMonitor.SetChecked(cfg.Enable)
+		w.trackSession.SetChecked(cfg.TrackSession)
+	}
+
+	// 以下输入框编辑当前配置方案
 	cfg := config.GetConfig()
+	profile := cfg.Profile()
+
+	// 创建正则表达式输入框
 	w.serverRegex = widget.NewMultiLineEntry()
-	w.serverRegex.SetText(cfg.BaseSettings.ServerRegex)
+	w.serverRegex.SetText(profile.ServerRegex)
 	w.serverRegex.Wrapping = fyne.TextWrapBreak
 	w.serverRegex.Resize(fyne.NewSize(w.serverRegex.Size().Width, 80))
 
 	w.streamKeyRegex = widget.NewMultiLineEntry()
-	w.streamKeyRegex.SetText(cfg.BaseSettings.StreamKeyRegex)
+	w.streamKeyRegex.SetText(profile.StreamKeyRegex)
 	w.streamKeyRegex.Wrapping = fyne.TextWrapBreak
 	w.streamKeyRegex.Resize(fyne.NewSize(w.streamKeyRegex.Size().Width, 80))
 
+	// 创建规则包更新控件
+	w.ruleUpdateURL = widget.NewEntry()
+	w.ruleUpdateURL.SetPlaceHolder("规则包更新地址")
+	w.ruleAutoCheck = widget.NewCheck("启动时检查规则更新", nil)
+	if rules := cfg.RuleSettings; rules != nil {
+		w.ruleUpdateURL.SetText(rules.UpdateURL)
+		w.ruleAutoCheck.SetChecked(rules.AutoCheck)
+	}
+	w.ruleVersion = widget.NewLabel("")
+	w.refreshRuleVersion()
+
 	// 创建OBS启动路径输入框
 	w.obsLaunchPath = widget.NewEntry()
-	w.obsLaunchPath.SetText(cfg.PathSettings.OBSLaunchPath)
+	w.obsLaunchPath.SetText(profile.PathSettings.OBSLaunchPath)
 	w.obsLaunchPath.SetPlaceHolder("请选择OBS启动路径 (obs64.exe)")
 	w.obsLaunchPath.Disable()
 
 	// 创建OBS配置路径输入框
 	w.obsConfigPath = widget.NewEntry()
-	w.obsConfigPath.SetText(cfg.PathSettings.OBSConfigPath)
+	w.obsConfigPath.SetText(profile.PathSettings.OBSConfigPath)
 	w.obsConfigPath.SetPlaceHolder("请选择OBS配置文件路径 (service.json)")
 	w.obsConfigPath.Disable()
 
 	// 创建直播伴侣路径输入框
 	w.liveCompanionPath = widget.NewEntry()
-	w.liveCompanionPath.SetText(cfg.PathSettings.LiveCompanionPath)
+	w.liveCompanionPath.SetText(profile.PathSettings.LiveCompanionPath)
 	w.liveCompanionPath.SetPlaceHolder("请选择直播伴侣启动路径 (直播伴侣 Launcher.exe)")
 	w.liveCompanionPath.Disable()
 
 	// 创建自动化插件脚本路径输入框
 	w.pluginScriptPath = widget.NewEntry()
-	w.pluginScriptPath.SetText(cfg.PathSettings.PluginScriptPath)
+	w.pluginScriptPath.SetText(profile.PathSettings.PluginScriptPath)
 	w.pluginScriptPath.SetPlaceHolder("请选择自动化插件脚本路径 (auto.exe)")
 	w.pluginScriptPath.Disable()
 
 	// 创建插件相关配置控件
 	w.pluginCheckInterval = NewNumericalEntry()
-	w.pluginCheckInterval.SetText(lkit.AnyToStr(cfg.ScriptSettings.PluginCheckInterval))
+	w.pluginCheckInterval.SetText(lkit.AnyToStr(profile.ScriptSettings.PluginCheckInterval))
 	w.pluginCheckInterval.SetPlaceHolder("插件检查间隔 (秒)")
 
 	w.pluginWaitAfterFound = NewNumericalEntry()
-	w.pluginWaitAfterFound.SetText(lkit.AnyToStr(cfg.ScriptSettings.PluginWaitAfterFound))
+	w.pluginWaitAfterFound.SetText(lkit.AnyToStr(profile.ScriptSettings.PluginWaitAfterFound))
 	w.pluginWaitAfterFound.SetPlaceHolder("插件检测到后等待时间 (秒)")
 
 	w.pluginTimeout = NewNumericalEntry()
-	w.pluginTimeout.SetText(lkit.AnyToStr(cfg.ScriptSettings.PluginTimeout))
+	w.pluginTimeout.SetText(lkit.AnyToStr(profile.ScriptSettings.PluginTimeout))
 	w.pluginTimeout.SetPlaceHolder("插件超时时间 (秒)")
 
 	// 创建下载按钮
@@ -191,6 +232,32 @@ func (w *SettingsWindow) setupUI() {
 		w.logLevel.SetSelected("info")
 	}
 
+	w.logRedactWarning = widget.NewLabel("⚠ 日志中将显示推流码等敏感信息, 调试完成后请关闭, 请勿将日志发送给他人")
+	w.logRedactWarning.Importance = widget.DangerImportance
+	w.logRedactWarning.Wrapping = fyne.TextWrapWord
+	w.logRedactWarning.Hide()
+	w.logUnredacted = widget.NewCheck("不脱敏调试", func(checked bool) {
+		if checked {
+			w.logRedactWarning.Show()
+		} else {
+			w.logRedactWarning.Hide()
+		}
+	})
+	if cfg.LogConfig != nil {
+		w.logUnredacted.SetChecked(cfg.LogConfig.Unredacted)
+	}
+
+	w.logRemoteURL = widget.NewEntry()
+	w.logRemoteURL.SetPlaceHolder("为空时不发送, 例如 https://example.com/logs 或 udp://192.168.1.10:514")
+	w.logRemoteLevel = widget.NewSelect([]string{"debug", "info", "warn", "error"}, nil)
+	w.logRemoteLevel.SetSelected("warn")
+	if cfg.LogConfig != nil {
+		w.logRemoteURL.SetText(cfg.LogConfig.RemoteURL)
+		if cfg.LogConfig.RemoteLevel != "" {
+			w.logRemoteLevel.SetSelected(cfg.LogConfig.RemoteLevel)
+		}
+	}
+
 	// 创建窗口行为配置控件
 	w.minimizeOnClose = widget.NewCheck("关闭窗口时最小化到托盘", nil)
 	w.minimizeOnClose.SetChecked(cfg.BaseSettings.MinimizeOnClose)
@@ -200,17 +267,17 @@ func (w *SettingsWindow) setupUI() {
 
 	// 创建OBS WebSocket配置控件
 	w.obsWsIp = widget.NewEntry()
-	w.obsWsIp.SetText(cfg.BaseSettings.OBSWsIp)
+	w.obsWsIp.SetText(profile.OBSWsIp)
 	w.obsWsIp.SetPlaceHolder("IP(本机:127.0.0.1)")
 
 	w.obsWsPort = widget.NewEntry()
-	if cfg.BaseSettings.OBSWsPort != 0 {
-		w.obsWsPort.SetText(lkit.AnyToStr(cfg.BaseSettings.OBSWsPort))
+	if profile.OBSWsPort != 0 {
+		w.obsWsPort.SetText(lkit.AnyToStr(profile.OBSWsPort))
 	}
 	w.obsWsPort.SetPlaceHolder("端口(默认:4455)")
 
 	w.obsWsPassword = widget.NewEntry()
-	w.obsWsPassword.SetText(cfg.BaseSettings.OBSWsPassword)
+	w.obsWsPassword.SetText(profile.OBSWsPassword)
 	w.obsWsPassword.SetPlaceHolder("密码")
 
 	// 创建标签页内容
@@ -220,6 +287,7 @@ func (w *SettingsWindow) setupUI() {
 	scriptTab := w.createScriptTab()
 	pathTab := w.createPathTab(&alreadyCheck)
 	windowTab := w.createWindowTab()
+	profileTab := w.createProfileTab()
 
 	// 创建标签容器
 	tabs := container.NewAppTabs(
@@ -229,6 +297,7 @@ func (w *SettingsWindow) setupUI() {
 		container.NewTabItemWithIcon("脚本设置", theme.ComputerIcon(), scriptTab),
 		container.NewTabItemWithIcon("路径设置", theme.SettingsIcon(), pathTab),
 		container.NewTabItemWithIcon("窗口行为", theme.WindowMaximizeIcon(), windowTab),
+		container.NewTabItemWithIcon("配置方案", theme.AccountIcon(), profileTab),
 	)
 	tabs.SetTabLocation(container.TabLocationTop)
 
@@ -242,11 +311,17 @@ func (w *SettingsWindow) setupUI() {
 		w.close()
 	})
 
+	effectiveBtn := widget.NewButtonWithIcon("显示生效配置", theme.InfoIcon(), w.showEffectiveConfig)
+
+	backupBtn := widget.NewButtonWithIcon("恢复历史配置", theme.HistoryIcon(), w.showBackups)
+
 	// 创建按钮容器
 	buttonContainer := container.New(
-		layout.NewGridLayout(2),
+		layout.NewGridLayout(4),
 		saveBtn,
 		cancelBtn,
+		effectiveBtn,
+		backupBtn,
 	)
 
 	// 设置内容
@@ -255,11 +330,30 @@ func (w *SettingsWindow) setupUI() {
 
 // saveSettings 保存设置并进行验证
 func (w *SettingsWindow) saveSettings(checks []string) {
-	// 验证插件设置
-	if err := w.validatePluginSettings(); err != nil {
-		w.NewErrorDialog(err)
+	newSettings, problems := w.buildSettings(checks)
+	w.markProblems(problems)
+
+	if config.HasError(problems) {
+		w.NewCustomDialog("配置有误, 请修改后保存", "返回修改", newProblemList(problems)).Resize(ProblemDialogSize)
 		return
 	}
+	if len(problems) > 0 {
+		confirmDialog := dialog.NewCustomConfirm("配置存在问题", "仍然保存", "返回修改", newProblemList(problems),
+			func(ok bool) {
+				if ok {
+					w.persistSettings(newSettings)
+				}
+			}, w.window)
+		confirmDialog.Resize(ProblemDialogSize)
+		confirmDialog.Show()
+		return
+	}
+	w.persistSettings(newSettings)
+}
+
+// buildSettings 根据界面内容生成新的配置并检查
+func (w *SettingsWindow) buildSettings(checks []string) (*config.Config, []config.Problem) {
+	var problems []config.Problem
 
 	// 获取当前配置以保留其他日志设置
 	currentConfig := config.GetConfig()
@@ -272,77 +366,125 @@ func (w *SettingsWindow) saveSettings(checks []string) {
 	updatedLogConfig := *logConfig
 	updatedLogConfig.File = w.logToFile.Checked
 	updatedLogConfig.Level = w.logLevel.Selected
-
-	// 创建新的设置
-	newSettings := &config.Config{
-		BaseSettings: &config.BaseSettings{
-			NetworkInterfaces: checks,
-			ServerRegex:       strings.TrimSpace(w.serverRegex.Text),
-			StreamKeyRegex:    strings.TrimSpace(w.streamKeyRegex.Text),
-			MinimizeOnClose:   w.minimizeOnClose.Checked,
-			OpenLiveWhenStart: w.openLiveWhenStart.Checked,
-			OBSWsIp:           strings.TrimSpace(w.obsWsIp.Text),
-			OBSWsPort:         lkit.Str2Int32(w.obsWsPort.Text),
-			OBSWsPassword:     strings.TrimSpace(w.obsWsPassword.Text),
-		},
-		PathSettings: &config.PathSettings{
-			OBSLaunchPath:     strings.TrimSpace(w.obsLaunchPath.Text),
-			OBSConfigPath:     strings.TrimSpace(w.obsConfigPath.Text),
-			LiveCompanionPath: strings.TrimSpace(w.liveCompanionPath.Text),
-			PluginScriptPath:  strings.TrimSpace(w.pluginScriptPath.Text),
-		},
-		ScriptSettings: &config.ScriptSettings{
-			PluginCheckInterval:  lkit.Str2Int32(w.pluginCheckInterval.Text),
-			PluginWaitAfterFound: lkit.Str2Int32(w.pluginWaitAfterFound.Text),
-			PluginTimeout:        lkit.Str2Int32(w.pluginTimeout.Text),
-		},
-		LogConfig: &updatedLogConfig,
+	updatedLogConfig.Unredacted = w.logUnredacted.Checked
+	updatedLogConfig.RemoteURL = strings.TrimSpace(w.logRemoteURL.Text)
+	updatedLogConfig.RemoteLevel = w.logRemoteLevel.Selected
+
+	// 更新推流监控配置, 告警阈值保持不变
+	monitorConfig := currentConfig.MonitorSettings
+	if monitorConfig == nil {
+		monitorConfig = config.DefaultConfig.MonitorSettings
+	}
+	updatedMonitorConfig := *monitorConfig
+	updatedMonitorConfig.Enable = w.pushMonitor.Checked
+	updatedMonitorConfig.TrackSession = w.trackSession.Checked
+
+	// 在当前配置的基础上更新设置, 保留界面中未提供的配置项
+	newSettings := currentConfig.Clone()
+	newSettings.BaseSettings.NetworkInterfaces = checks
+	newSettings.BaseSettings.MinimizeOnClose = w.minimizeOnClose.Checked
+	newSettings.BaseSettings.OpenLiveWhenStart = w.openLiveWhenStart.Checked
+	newSettings.MonitorSettings = &updatedMonitorConfig
+	newSettings.LogConfig = &updatedLogConfig
+	newSettings.RuleSettings = &config.RuleSettings{
+		UpdateURL: strings.TrimSpace(w.ruleUpdateURL.Text),
+		AutoCheck: w.ruleAutoCheck.Checked,
 	}
 
-	// 保存设置
-	if err := config.SaveSettings(newSettings); err != nil {
-		w.NewErrorDialog(err)
-		return
+	// 更新当前配置方案
+	profile := newSettings.Profile()
+	profile.ServerRegex = strings.TrimSpace(w.serverRegex.Text)
+	profile.StreamKeyRegex = strings.TrimSpace(w.streamKeyRegex.Text)
+	profile.OBSWsIp = strings.TrimSpace(w.obsWsIp.Text)
+	profile.OBSWsPort = parseIntField(&problems, ".obs_ws_port", "端口", w.obsWsPort.Text)
+	profile.OBSWsPassword = strings.TrimSpace(w.obsWsPassword.Text)
+	profile.PathSettings = &config.PathSettings{
+		OBSLaunchPath:     strings.TrimSpace(w.obsLaunchPath.Text),
+		OBSConfigPath:     strings.TrimSpace(w.obsConfigPath.Text),
+		LiveCompanionPath: strings.TrimSpace(w.liveCompanionPath.Text),
+		PluginScriptPath:  strings.TrimSpace(w.pluginScriptPath.Text),
+	}
+	profile.ScriptSettings = &config.ScriptSettings{
+		PluginCheckInterval:  parseIntField(&problems, ".script.plugin_check_interval", "插件检查间隔", w.pluginCheckInterval.Text),
+		PluginWaitAfterFound: parseIntField(&problems, ".script.plugin_wait_after_found", "等待时间", w.pluginWaitAfterFound.Text),
+		PluginTimeout:        parseIntField(&problems, ".script.plugin_timeout", "超时时间", w.pluginTimeout.Text),
 	}
 
-	w.close()
-	w.saveCallback("设置已保存，请重启软件以应用更改")
+	// 新建或删除的配置方案
+	newSettings.Profiles = w.applyProfileChanges(newSettings.Profiles, profile)
+
+	return newSettings, append(problems, newSettings.Validate(w.deviceNames)...)
 }
 
-// validatePluginSettings 验证插件设置
-func (w *SettingsWindow) validatePluginSettings() error {
-	// 验证插件检查间隔
-	checkIntervalText := strings.TrimSpace(w.pluginCheckInterval.Text)
-	if checkIntervalText == "" {
-		return fmt.Errorf("插件检查间隔不能为空")
+// parseIntField 解析配置方案中的整数输入框, 内容为空时为0, 不是数字时记录问题
+func parseIntField(problems *[]config.Problem, key, name, text string) int32 {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0
 	}
-	checkInterval := lkit.Str2Int32(checkIntervalText)
-	if checkInterval < 1 {
-		return fmt.Errorf("插件检查间隔不可小于1秒")
+	value, err := strconv.ParseInt(text, 10, 32)
+	if err != nil {
+		*problems = append(*problems, config.Problem{
+			Field:   config.ProfileSection + key,
+			Message: name + "必须为数字",
+			Level:   config.ProblemError,
+		})
 	}
+	return int32(value)
+}
 
-	// 验证等待时间
-	waitTimeText := strings.TrimSpace(w.pluginWaitAfterFound.Text)
-	if waitTimeText == "" {
-		return fmt.Errorf("等待时间不可为空")
+// markProblems 在有问题的输入框上显示错误标记
+func (w *SettingsWindow) markProblems(problems []config.Problem) {
+	entries := w.fieldEntries()
+	for _, entry := range entries {
+		entry.SetValidationError(nil)
 	}
-	waitTime := lkit.Str2Int32(waitTimeText)
-	if waitTime < 0 {
-		return fmt.Errorf("等待时间不能为负数")
+	for _, problem := range problems {
+		if entry, ok := entries[problem.Field]; ok {
+			entry.SetValidationError(errors.New(problem.Message))
+		}
 	}
+}
 
-	// 验证超时时间
-	timeoutText := strings.TrimSpace(w.pluginTimeout.Text)
-	if timeoutText == "" {
-		return fmt.Errorf("超时时间不能为空")
+// fieldEntries 配置项键名对应的输入框
+func (w *SettingsWindow) fieldEntries() map[string]*widget.Entry {
+	entries := map[string]*widget.Entry{
+		"profile.server_regex":                   w.serverRegex,
+		"profile.stream_key_regex":               w.streamKeyRegex,
+		"profile.obs_ws_ip":                      w.obsWsIp,
+		"profile.obs_ws_port":                    w.obsWsPort,
+		"profile.path.obs_launch_path":           w.obsLaunchPath,
+		"profile.path.obs_config_path":           w.obsConfigPath,
+		"profile.path.live_companion_path":       w.liveCompanionPath,
+		"profile.path.plugin_script_path":        w.pluginScriptPath,
+		"profile.script.plugin_check_interval":   &w.pluginCheckInterval.Entry,
+		"profile.script.plugin_wait_after_found": &w.pluginWaitAfterFound.Entry,
+		"profile.script.plugin_timeout":          &w.pluginTimeout.Entry,
+		"rules.update_url":                       w.ruleUpdateURL,
+		"log.remote_url":                         w.logRemoteURL,
 	}
-	timeout := lkit.Str2Int32(timeoutText)
-	if timeout < 5 {
-		return fmt.Errorf("超时时间不可小于5秒")
+	for _, entry := range entries {
+		// 只有设置了校验函数的输入框才会显示错误标记, 修改内容后标记自动清除
+		if entry.Validator == nil {
+			entry.Validator = func(string) error { return nil }
+		}
 	}
-	if timeout > 300 {
-		return fmt.Errorf("超时时间不可大于300秒")
+	return entries
+}
+
+// persistSettings 保存并应用配置
+func (w *SettingsWindow) persistSettings(newSettings *config.Config) {
+	// 保存设置
+	if err := config.SaveSettings(newSettings); err != nil {
+		w.NewErrorDialog(err)
+		return
 	}
 
-	return nil
+	if err := config.ReloadConfig(); err != nil {
+		w.NewErrorDialog(fmt.Errorf("设置已保存, 但应用失败: %v", err))
+		return
+	}
+
+	w.close()
+	w.saveCallback("设置已保存并生效, 正则与网卡设置将在下次抓包时生效")
 }
@@ -0,0 +1,61 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/config"
+)
+
+// showBackups 显示历史配置列表, 选择后恢复
+func (w *SettingsWindow) showBackups() {
+	backups := config.ListBackups()
+	if len(backups) == 0 {
+		w.NewInfoDialog("恢复历史配置", "暂无历史配置, 每次保存配置时会保留保存前的配置")
+		return
+	}
+
+	list := widget.NewList(
+		func() int {
+			return len(backups)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			backup := backups[id]
+			object.(*widget.Label).SetText(fmt.Sprintf("%d. %s 保存的配置", backup.Index, backup.ModTime.Format(time.DateTime)))
+		},
+	)
+
+	help := widget.NewLabel(fmt.Sprintf("最多保留最近 %d 次保存前的配置, 恢复前的配置也会加入历史配置", config.BackupCount))
+	help.Wrapping = fyne.TextWrapWord
+
+	var backupDialog *dialog.CustomDialog
+	list.OnSelected = func(id widget.ListItemID) {
+		backup := backups[id]
+		list.UnselectAll()
+		w.NewConfirmDialog("恢复历史配置",
+			fmt.Sprintf("确定要恢复 %s 保存的配置吗？\n当前未保存的修改将会丢失", backup.ModTime.Format(time.DateTime)),
+			func(ok bool) {
+				if !ok {
+					return
+				}
+				if err := config.RestoreBackup(backup); err != nil {
+					w.NewErrorDialog(fmt.Errorf("恢复历史配置失败: %v", err))
+					return
+				}
+				backupDialog.Hide()
+				w.close()
+				w.saveCallback("已恢复历史配置")
+			})
+	}
+
+	backupDialog = w.NewCustomDialog("恢复历史配置", "关闭", container.NewBorder(help, nil, nil, nil, list))
+	backupDialog.Resize(fyne.NewSize(450, 320))
+}
@@ -0,0 +1,235 @@
+package ui
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/config"
+	"tiktok_tool/lkit"
+)
+
+// exportBundle 导出配置包, 可选择是否包含密码
+func (w *SettingsWindow) exportBundle() {
+	includeSecrets := widget.NewCheck("包含OBS WebSocket密码(使用主密码加密)", nil)
+	content := container.NewVBox(
+		widget.NewLabel("配置包包含全部配置方案和规则包, 路径会按OBS程序目录、APPDATA等目录改写,\n导入时按新电脑的目录还原。"),
+		includeSecrets,
+	)
+
+	confirmDialog := dialog.NewCustomConfirm("导出配置", "选择保存位置", "取消", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		if includeSecrets.Checked && os.Getenv(config.MasterPasswordEnv) == "" {
+			w.NewErrorDialog(fmt.Errorf("导出密码需要先设置环境变量 %s 作为主密码, 导入时使用相同的主密码解密", config.MasterPasswordEnv))
+			return
+		}
+		w.saveBundle(includeSecrets.Checked)
+	}, w.window)
+	confirmDialog.Resize(SettingsWindowDialogSize)
+	confirmDialog.Show()
+}
+
+// saveBundle 选择保存位置并写入配置包
+func (w *SettingsWindow) saveBundle(includeSecrets bool) {
+	fileDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			w.NewErrorDialog(err)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if err = config.ExportBundle(writer, config.GetConfig(), includeSecrets); err != nil {
+			w.NewErrorDialog(fmt.Errorf("导出配置失败: %v", err))
+			return
+		}
+		w.NewInfoDialog("导出成功", "配置已导出到: "+writer.URI().Path())
+	}, w.window)
+	fileDialog.SetFileName("tiktok_tool" + config.BundleExt)
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{config.BundleExt}))
+	fileDialog.Show()
+}
+
+// importBundle 选择并读取配置包, 重新检测路径后显示差异确认导入
+func (w *SettingsWindow) importBundle() {
+	fileDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			w.NewErrorDialog(err)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		content, err := io.ReadAll(reader)
+		if err != nil {
+			w.NewErrorDialog(fmt.Errorf("读取配置包失败: %v", err))
+			return
+		}
+		bundle, err := config.ReadBundle(bytes.NewReader(content), int64(len(content)))
+		if err != nil {
+			w.NewErrorDialog(fmt.Errorf("导入配置失败: %v", err))
+			return
+		}
+
+		progressDialog := w.NewCustomWithoutButtons(
+			"检测路径中",
+			container.NewCenter(widget.NewLabel("正在检测本机的OBS、直播伴侣路径，请稍候...")),
+		)
+		lkit.Go("ui.redetect_paths", func(context.Context) {
+			notes := redetectPaths(bundle.Config)
+			fyne.Do(func() {
+				progressDialog.Hide()
+				w.confirmImport(bundle, notes)
+			})
+		})
+	}, w.window)
+	fileDialog.SetFilter(storage.NewExtensionFileFilter([]string{config.BundleExt}))
+	fileDialog.SetConfirmText("导入")
+	fileDialog.SetDismissText("取消")
+	fileDialog.Show()
+}
+
+// redetectPaths 重新检测本机不存在的路径, 返回检测结果说明
+// 先检测OBS启动路径, 再按检测后的OBS程序目录展开 ${OBS_DIR}, 最后检查其他路径
+func redetectPaths(cfg *config.Config) []string {
+	notes := make([]string, 0)
+	found := make(map[string]string)
+	find := func(fileName string) string {
+		if path, ok := found[fileName]; ok {
+			return path
+		}
+		found[fileName] = lkit.FindFileInAllDrives(fileName)
+		return found[fileName]
+	}
+
+	type check struct {
+		name   string
+		path   *string
+		detect func() string // 为nil时无法自动检测
+	}
+	redetect := func(profile *config.Profile, checks []check) {
+		for _, check := range checks {
+			if *check.path == "" {
+				continue
+			}
+			if _, err := os.Stat(*check.path); err == nil {
+				continue
+			}
+			if check.detect != nil {
+				if detected := check.detect(); detected != "" {
+					notes = append(notes, fmt.Sprintf("方案 %s 的%s已重新检测为: %s", profile.Name, check.name, detected))
+					*check.path = detected
+					continue
+				}
+			}
+			notes = append(notes, fmt.Sprintf("方案 %s 的%s在本机不存在, 请导入后手动设置: %s", profile.Name, check.name, *check.path))
+		}
+	}
+
+	for _, profile := range cfg.Profiles {
+		if paths := profile.PathSettings; paths != nil {
+			redetect(profile, []check{
+				{"OBS启动路径", &paths.OBSLaunchPath, func() string { return find("obs64.exe") }},
+			})
+		}
+	}
+
+	// OBS启动路径检测后展开依赖OBS程序目录的路径
+	config.ExpandPaths(cfg)
+
+	for _, profile := range cfg.Profiles {
+		if paths := profile.PathSettings; paths != nil {
+			redetect(profile, []check{
+				{"OBS配置文件路径", &paths.OBSConfigPath, GetDefaultOBSConfigPath},
+				{"直播伴侣启动路径", &paths.LiveCompanionPath, func() string { return find("直播伴侣 Launcher.exe") }},
+				{"自动化脚本路径", &paths.PluginScriptPath, nil},
+			})
+		}
+	}
+	return notes
+}
+
+// confirmImport 显示导入前后的配置差异, 确认后保存
+func (w *SettingsWindow) confirmImport(bundle *config.Bundle, notes []string) {
+	changes := config.Diff(config.GetConfig(), bundle.Config)
+	headers := []string{"配置项", "当前", "导入后"}
+
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(changes) + 1, len(headers)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.TableCellID, object fyne.CanvasObject) {
+			label := object.(*widget.Label)
+			if id.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText(headers[id.Col])
+				return
+			}
+
+			label.TextStyle = fyne.TextStyle{}
+			change := changes[id.Row-1]
+			switch id.Col {
+			case 0:
+				label.SetText(change.Key)
+			case 1:
+				label.SetText(change.Old)
+			case 2:
+				label.SetText(change.New)
+			}
+		},
+	)
+	table.SetColumnWidth(0, 240)
+	table.SetColumnWidth(1, 200)
+	table.SetColumnWidth(2, 200)
+
+	if bundle.Manifest.SecretsOmitted {
+		notes = append(notes, "配置包不包含密码, 同名方案将保留本机已保存的密码")
+	}
+	if len(bundle.Rules) > 0 {
+		notes = append(notes, fmt.Sprintf("将导入 %d 个规则包文件", len(bundle.Rules)))
+	}
+	if len(changes) == 0 {
+		notes = append(notes, "配置项与当前配置相同")
+	}
+	for _, problem := range bundle.Problems {
+		notes = append(notes, problem.String())
+	}
+
+	noteLabel := widget.NewLabel(strings.Join(notes, "\n"))
+	noteLabel.Wrapping = fyne.TextWrapWord
+	content := container.NewBorder(noteLabel, nil, nil, nil, table)
+
+	confirmDialog := dialog.NewCustomConfirm("确认导入配置", "导入", "取消", content, func(ok bool) {
+		if !ok {
+			return
+		}
+		if err := config.ApplyBundle(bundle); err != nil {
+			w.NewErrorDialog(fmt.Errorf("导入配置失败: %v", err))
+			return
+		}
+		w.close()
+		w.saveCallback("配置已导入并生效")
+	}, w.window)
+	confirmDialog.Resize(fyne.NewSize(700, 480))
+	confirmDialog.Show()
+}
@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/config"
+)
+
+// showEffectiveConfig 显示当前生效的配置及每一项的来源
+func (w *SettingsWindow) showEffectiveConfig() {
+	fields := config.Effective()
+	headers := []string{"配置项", "值", "来源"}
+
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(fields) + 1, len(headers)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.TableCellID, object fyne.CanvasObject) {
+			label := object.(*widget.Label)
+			if id.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText(headers[id.Col])
+				return
+			}
+
+			label.TextStyle = fyne.TextStyle{}
+			field := fields[id.Row-1]
+			switch id.Col {
+			case 0:
+				label.SetText(field.Key)
+			case 1:
+				label.SetText(field.Value)
+			case 2:
+				label.SetText(field.Source.String())
+			}
+		},
+	)
+	table.SetColumnWidth(0, 220)
+	table.SetColumnWidth(1, 220)
+	table.SetColumnWidth(2, 90)
+
+	effectiveDialog := w.NewCustomDialog("生效配置", "关闭", table)
+	effectiveDialog.Resize(fyne.NewSize(580, 400))
+}
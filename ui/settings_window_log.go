@@ -1,10 +1,14 @@
 package ui
 
 import (
+	"fmt"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/llog"
 )
 
 // createLogTab 创建日志设置标签页
@@ -16,6 +20,14 @@ func (w *SettingsWindow) createLogTab() fyne.CanvasObject {
 			widget.NewLabel("日志等级:"),
 			w.logLevel,
 		),
+		w.logUnredacted,
+		w.logRedactWarning,
+	)
+
+	// 远程日志, 将多台直播电脑的日志集中发送到日志收集服务
+	remoteForm := widget.NewForm(
+		widget.NewFormItem("远程日志地址", w.logRemoteURL),
+		widget.NewFormItem("远程日志等级", container.NewHBox(w.logRemoteLevel, remoteLogStatus())),
 	)
 
 	// 添加日志等级说明
@@ -24,12 +36,32 @@ func (w *SettingsWindow) createLogTab() fyne.CanvasObject {
 		"* **info**: 一般信息日志（默认等级）\n" +
 		"* **warn**: 警告信息\n" +
 		"* **error**: 仅记录错误信息\n" +
-		"### 注意:日志配置更改后需重启软件")
+		"### 日志脱敏\n\n" +
+		"日志中的推流签名、直播流ID和OBS WebSocket密码默认会被隐藏, 勾选不脱敏调试后会显示完整内容\n\n" +
+		"### 远程日志\n\n" +
+		"设置地址后会将不低于远程日志等级的日志批量发送到 HTTP 接口(POST JSON)或 syslog(UDP), 网络不可用时暂存在日志目录下, 恢复后补发\n\n" +
+		"### 注意:日志配置保存后立即生效")
 
 	// 创建容器
 	return container.NewVBox(
 		logConfigContainer,
+		remoteForm,
 		layout.NewSpacer(),
 		logLevelHelp,
 	)
 }
+
+// remoteLogStatus 显示远程日志的发送状态
+func remoteLogStatus() *widget.Label {
+	status, ok := llog.GetRemoteStatus()
+	if !ok {
+		return widget.NewLabel("未开启")
+	}
+	label := widget.NewLabel(fmt.Sprintf("已发送 %d 条, 丢弃 %d 条", status.Sent, status.Dropped))
+	if status.LastErr != "" {
+		label.SetText(fmt.Sprintf("发送失败, 暂存 %d KB: %s", status.Spooled/1024, status.LastErr))
+		label.Importance = widget.WarningImportance
+		label.Truncation = fyne.TextTruncateEllipsis
+	}
+	return label
+}
@@ -11,16 +11,20 @@ import (
 func (w *SettingsWindow) createNetworkTab() fyne.CanvasObject {
 	// 创建网卡列表容器
 	networkScroll := container.NewScroll(w.networkList)
-	networkScroll.SetMinSize(fyne.NewSize(500, 250))
+	networkScroll.SetMinSize(fyne.NewSize(500, 170))
 
 	// 添加说明文本
 	networkHelp := widget.NewRichTextFromMarkdown("### 网卡选择说明\n\n" +
 		"选择需要监听的网卡，抓包功能将监听所选网卡的网络流量。\n\n" +
-		"如果不确定使用哪个网卡，可以选择多个网卡同时监听。")
+		"如果不确定使用哪个网卡，可以选择多个网卡同时监听。\n\n" +
+		"开启推流监控后，获取到推流地址时会持续统计上传码率、重传、零窗口和RTT，异常时在状态栏告警；" +
+		"跟踪推流会在推流开始、中断、结束时通知，并将每次推流时长记录到 config/push_sessions.jsonl。")
 
 	// 创建容器
 	return container.NewVBox(
 		networkScroll,
+		w.pushMonitor,
+		w.trackSession,
 		layout.NewSpacer(),
 		networkHelp,
 	)
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -13,6 +14,7 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
+	"tiktok_tool/appdir"
 	"tiktok_tool/config"
 	"tiktok_tool/lkit"
 )
@@ -107,7 +109,7 @@ func (w *SettingsWindow) resetToDefaults() {
 			return
 		}
 
-		configPath := filepath.Join(config.CfgFilePath, config.CfgFileName)
+		configPath := config.FilePath()
 		if _, err := os.Stat(configPath); err == nil {
 			// 配置文件在 config 目录中
 			if err := os.Remove(configPath); err != nil {
@@ -115,8 +117,8 @@ func (w *SettingsWindow) resetToDefaults() {
 				return
 			}
 		} else {
-			// 检查当前目录
-			configPath = filepath.Join(config.CfgFileName)
+			// 检查数据根目录
+			configPath = appdir.Join(config.CfgFileName)
 			if _, err = os.Stat(configPath); err == nil {
 				if err = os.Remove(configPath); err != nil {
 					w.NewErrorDialog(fmt.Errorf("删除配置文件失败: %v", err))
@@ -125,8 +127,13 @@ func (w *SettingsWindow) resetToDefaults() {
 			}
 		}
 
+		if err := config.ReloadConfig(); err != nil {
+			w.NewErrorDialog(fmt.Errorf("应用默认配置失败: %v", err))
+			return
+		}
+
 		w.close()
-		w.saveCallback("已恢复默认配置，请重启软件以应用更改")
+		w.saveCallback("已恢复默认配置")
 	})
 }
 
@@ -149,7 +156,7 @@ func (w *SettingsWindow) browseOBSConfig() {
 	fileDialog.SetDismissText("取消选择")
 
 	for {
-		if path := config.GetConfig().PathSettings.OBSConfigPath; path != "" {
+		if path := config.GetConfig().Profile().PathSettings.OBSConfigPath; path != "" {
 			pathDir := lkit.GetPathDir(path)
 			if _, err := os.Stat(pathDir); err == nil {
 				if uri := storage.NewFileURI(pathDir); uri != nil {
@@ -236,7 +243,7 @@ func (w *SettingsWindow) browseLiveCompanionConfig() {
 	fileDialog.SetConfirmText("确定选择")
 	fileDialog.SetDismissText("取消选择")
 
-	if path := config.GetConfig().PathSettings.LiveCompanionPath; path != "" {
+	if path := config.GetConfig().Profile().PathSettings.LiveCompanionPath; path != "" {
 		pathDir := lkit.GetPathDir(path)
 		if _, err := os.Stat(pathDir); err == nil {
 			if uri := storage.NewFileURI(pathDir); uri != nil {
@@ -257,7 +264,7 @@ func (w *SettingsWindow) autoDetectLiveCompanionConfig() {
 		container.NewCenter(widget.NewLabel("正在搜索直播伴侣安装路径，请稍候...")),
 	)
 
-	lkit.SafeGo(func() {
+	lkit.Go("ui.find_live_companion", func(context.Context) {
 		result, title, info := "", "", ""
 		defer func() {
 			fyne.Do(func() {
@@ -295,7 +302,7 @@ func (w *SettingsWindow) browseOBSLaunchConfig() {
 	fileDialog.SetConfirmText("确定选择")
 	fileDialog.SetDismissText("取消选择")
 
-	if path := config.GetConfig().PathSettings.OBSLaunchPath; path != "" {
+	if path := config.GetConfig().Profile().PathSettings.OBSLaunchPath; path != "" {
 		pathDir := lkit.GetPathDir(path)
 		if _, err := os.Stat(pathDir); err == nil {
 			if uri := storage.NewFileURI(pathDir); uri != nil {
@@ -318,7 +325,7 @@ func (w *SettingsWindow) autoDetectOBSLaunchConfig() {
 
 	resultChan := make(chan string)
 
-	lkit.SafeGo(func() {
+	lkit.Go("ui.find_obs", func(context.Context) {
 		resultChan <- lkit.FindFileInAllDrives("obs64.exe")
 	})
 
@@ -0,0 +1,131 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/config"
+)
+
+// createProfileTab 创建配置方案标签页
+func (w *SettingsWindow) createProfileTab() fyne.CanvasObject {
+	cfg := config.GetConfig()
+	active := cfg.Profile().Name
+	w.profileNames = cfg.ProfileNames()
+
+	selected := -1
+	w.profileList = widget.NewList(
+		func() int {
+			return len(w.profileNames)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("")
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			name := w.profileNames[id]
+			if name == active {
+				name += " (当前)"
+			}
+			object.(*widget.Label).SetText(name)
+		},
+	)
+	w.profileList.OnSelected = func(id widget.ListItemID) {
+		selected = id
+	}
+
+	addBtn := widget.NewButtonWithIcon("新建方案", theme.ContentAddIcon(), w.addProfile)
+	removeBtn := widget.NewButtonWithIcon("删除方案", theme.DeleteIcon(), func() {
+		if selected < 0 || selected >= len(w.profileNames) {
+			w.NewErrorDialog(fmt.Errorf("请先选择要删除的方案"))
+			return
+		}
+		if w.profileNames[selected] == active {
+			w.NewErrorDialog(fmt.Errorf("不能删除当前使用的方案"))
+			return
+		}
+		w.profileNames = slices.Delete(w.profileNames, selected, selected+1)
+		selected = -1
+		w.profileList.UnselectAll()
+		w.profileList.Refresh()
+	})
+
+	exportBtn := widget.NewButtonWithIcon("导出配置", theme.UploadIcon(), w.exportBundle)
+	importBtn := widget.NewButtonWithIcon("导入配置", theme.DownloadIcon(), w.importBundle)
+
+	profileScroll := container.NewScroll(w.profileList)
+	profileScroll.SetMinSize(fyne.NewSize(500, 140))
+
+	profileHelp := widget.NewRichTextFromMarkdown("### 配置方案说明\n\n" +
+		"每个直播账号/直播间可使用独立的方案, 方案包含路径、OBS WebSocket、正则以及一键开播脚本设置, " +
+		"其他标签页中的这些设置只作用于当前方案。\n\n" +
+		"新建方案会复制当前方案的设置, 保存后可在主界面或托盘菜单中切换方案。\n\n" +
+		"OBS WebSocket密码等敏感设置会加密保存。\n\n" +
+		"导出配置会生成包含全部方案和规则包的配置包, 用于在新电脑上导入, 导入前会显示配置差异并重新检测OBS、直播伴侣路径。")
+	profileHelp.Wrapping = fyne.TextWrapWord
+
+	return container.NewVBox(
+		profileScroll,
+		container.New(layout.NewGridLayout(2), addBtn, removeBtn),
+		container.New(layout.NewGridLayout(2), exportBtn, importBtn),
+		layout.NewSpacer(),
+		profileHelp,
+	)
+}
+
+// addProfile 输入名称新建配置方案
+func (w *SettingsWindow) addProfile() {
+	entry := widget.NewEntry()
+	entry.SetPlaceHolder("例如: 主号、小号")
+
+	formDialog := dialog.NewForm("新建方案", "确定", "取消",
+		[]*widget.FormItem{widget.NewFormItem("方案名称", entry)},
+		func(ok bool) {
+			if !ok {
+				return
+			}
+			name := strings.TrimSpace(entry.Text)
+			if name == "" {
+				w.NewErrorDialog(fmt.Errorf("方案名称不能为空"))
+				return
+			}
+			if slices.Contains(w.profileNames, name) {
+				w.NewErrorDialog(fmt.Errorf("方案已存在: %s", name))
+				return
+			}
+			w.profileNames = append(w.profileNames, name)
+			w.profileList.Refresh()
+		}, w.window)
+	formDialog.Resize(SettingsWindowDialogSize)
+	formDialog.Show()
+}
+
+// applyProfileChanges 按标签页中的方案列表生成保存的方案, 新建的方案复制当前方案的设置
+func (w *SettingsWindow) applyProfileChanges(profiles []*config.Profile, current *config.Profile) []*config.Profile {
+	if w.profileNames == nil {
+		return profiles
+	}
+
+	result := make([]*config.Profile, 0, len(w.profileNames))
+	for _, name := range w.profileNames {
+		index := slices.IndexFunc(profiles, func(profile *config.Profile) bool {
+			return profile.Name == name
+		})
+		if index >= 0 {
+			result = append(result, profiles[index])
+			continue
+		}
+
+		profile := current.Clone()
+		profile.Name = name
+		result = append(result, profile)
+	}
+	return result
+}
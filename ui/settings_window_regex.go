@@ -1,10 +1,18 @@
 package ui
 
 import (
+	"errors"
+	"fmt"
+	"strings"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/config"
+	"tiktok_tool/rules"
 )
 
 // createRegexTab 创建正则设置标签页
@@ -13,18 +21,72 @@ func (w *SettingsWindow) createRegexTab() fyne.CanvasObject {
 	regexForm := widget.NewForm(
 		widget.NewFormItem("服务器地址正则", w.serverRegex),
 		widget.NewFormItem("推流码正则", w.streamKeyRegex),
+		widget.NewFormItem("规则更新地址", w.ruleUpdateURL),
+		widget.NewFormItem("", container.NewHBox(w.ruleAutoCheck, layout.NewSpacer(), w.ruleVersion)),
 	)
 
+	// 创建规则更新按钮
+	checkBtn := widget.NewButtonWithIcon("检查规则更新", theme.ViewRefreshIcon(), func() {
+		checkRuleUpdate(w.window, strings.TrimSpace(w.ruleUpdateURL.Text), false, func(updated []string) {
+			w.refreshRegex()
+			w.NewInfoDialog("更新成功", ruleAppliedText(updated))
+		})
+	})
+	rollbackBtn := widget.NewButtonWithIcon("回滚规则", theme.HistoryIcon(), w.rollbackRules)
+
 	// 添加说明文本
 	regexHelp := widget.NewRichTextFromMarkdown("### 正则表达式说明\n\n" +
 		"* **服务器地址正则**：用于匹配抓包数据中的推流服务器地址\n" +
 		"* **推流码正则**：用于匹配抓包数据中的推流密钥\n\n" +
-		"正则表达式需要包含一个捕获组，用于提取匹配的内容。")
+		"正则表达式需要包含一个捕获组，用于提取匹配的内容。\n\n" +
+		"官方修改推流格式后会发布新的规则, 更新规则只会修改使用默认正则的方案。")
 
 	// 创建容器
 	return container.NewVBox(
 		regexForm,
+		container.New(layout.NewGridLayout(2), checkBtn, rollbackBtn),
 		layout.NewSpacer(),
 		regexHelp,
 	)
 }
+
+// rollbackRules 回滚到上一个规则包
+func (w *SettingsWindow) rollbackRules() {
+	w.NewConfirmDialog("回滚规则", "确定要回滚到上一个版本的规则吗？", func(ok bool) {
+		if !ok {
+			return
+		}
+		updated, err := rules.Rollback()
+		if errors.Is(err, rules.ErrNoPrevious) {
+			w.NewInfoDialog("回滚规则", "没有可回滚的规则")
+			return
+		}
+		if err != nil {
+			w.NewErrorDialog(fmt.Errorf("回滚规则失败: %v", err))
+			return
+		}
+		w.refreshRegex()
+		w.NewInfoDialog("回滚成功", fmt.Sprintf("已回滚规则, 已更新方案: %v", updated))
+	})
+}
+
+// refreshRegex 规则更新后刷新当前方案的正则和规则版本
+func (w *SettingsWindow) refreshRegex() {
+	profile := config.GetConfig().Profile()
+	w.serverRegex.SetText(profile.ServerRegex)
+	w.streamKeyRegex.SetText(profile.StreamKeyRegex)
+	w.refreshRuleVersion()
+}
+
+// refreshRuleVersion 显示当前使用的规则版本
+func (w *SettingsWindow) refreshRuleVersion() {
+	pack, err := rules.Current()
+	switch {
+	case err != nil:
+		w.ruleVersion.SetText("规则无效, 使用内置规则")
+	case pack == nil:
+		w.ruleVersion.SetText("当前规则: 内置规则")
+	default:
+		w.ruleVersion.SetText(fmt.Sprintf("当前规则: 版本 %d", pack.Version))
+	}
+}
@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"net/http"
@@ -17,6 +18,7 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 
+	"tiktok_tool/appdir"
 	"tiktok_tool/config"
 	"tiktok_tool/lkit"
 )
@@ -38,7 +40,7 @@ func (w *SettingsWindow) createScriptTab() fyne.CanvasObject {
 	})
 
 	// 创建下载按钮
-	if config.GetConfig().PathSettings.PluginScriptPath != "" {
+	if config.GetConfig().Profile().PathSettings.PluginScriptPath != "" {
 		w.pluginScriptDownloadBtn.Disable()
 	} else {
 		w.pluginScriptDownloadBtn.Importance = widget.SuccessImportance
@@ -60,7 +62,7 @@ func (w *SettingsWindow) createScriptTab() fyne.CanvasObject {
 	)
 
 	// 插件设置
-	defaultConfig := config.DefaultConfig.ScriptSettings
+	defaultConfig := config.DefaultProfile.ScriptSettings
 	resetCheckIntervalBtn := widget.NewButtonWithIcon("重置间隔", theme.MediaReplayIcon(), func() {
 		w.pluginCheckInterval.SetText(lkit.AnyToStr(defaultConfig.PluginCheckInterval))
 	})
@@ -99,12 +101,9 @@ func (w *SettingsWindow) createScriptTab() fyne.CanvasObject {
 	)
 }
 
+// getPluginDir 插件目录, 位于数据根目录下
 func (w *SettingsWindow) getPluginDir() (string, error) {
-	currentDir, err := os.Getwd()
-	if err != nil {
-		return "", err
-	}
-	return filepath.Join(currentDir, "plugin"), nil
+	return appdir.Join("plugin"), nil
 }
 
 // detectPlugin 检测插件目录下的文件
@@ -202,8 +201,8 @@ func (w *SettingsWindow) downloadAutoExe() {
 		progressBar,
 	))
 
-	// 在后台执行下载
-	lkit.SafeGo(func() {
+	// 在后台执行下载, 程序退出时取消
+	lkit.Go("plugin.download", func(ctx context.Context) {
 		// 确保plugin目录存在
 		pluginDir, err := w.getPluginDir()
 		if err != nil {
@@ -229,7 +228,15 @@ func (w *SettingsWindow) downloadAutoExe() {
 		fyne.Do(func() {
 			progressLabel.SetText("正在下载...")
 		})
-		resp, err := http.Get(downloadURL)
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, downloadURL, nil)
+		if err != nil {
+			fyne.Do(func() {
+				progressDialog.Hide()
+				w.NewErrorDialog(fmt.Errorf("下载失败: %v", err))
+			})
+			return
+		}
+		resp, err := http.DefaultClient.Do(req)
 		if err != nil {
 			fyne.Do(func() {
 				progressDialog.Hide()
@@ -313,7 +320,7 @@ func (w *SettingsWindow) browsePluginScriptConfig() {
 	fileDialog.SetDismissText("取消选择")
 
 	for {
-		if path := config.GetConfig().PathSettings.PluginScriptPath; path != "" {
+		if path := config.GetConfig().Profile().PathSettings.PluginScriptPath; path != "" {
 			pathDir := lkit.GetPathDir(path)
 			if _, err := os.Stat(pathDir); err == nil {
 				if uri := storage.NewFileURI(pathDir); uri != nil {
@@ -324,12 +331,9 @@ func (w *SettingsWindow) browsePluginScriptConfig() {
 				}
 			}
 		}
-		// 设置默认路径为当前目录下的plugin文件夹
-		currentDir, err := os.Getwd()
-		if err != nil {
-			break
-		}
-		pluginDir := filepath.Join(currentDir, "plugin")
+		// 设置默认路径为数据根目录下的plugin文件夹
+		currentDir := appdir.Root()
+		pluginDir := appdir.Join("plugin")
 		if _, err := os.Stat(pluginDir); err == nil {
 			if uri := storage.NewFileURI(pluginDir); uri != nil {
 				if lister, err := storage.ListerForURI(uri); err == nil {
@@ -338,7 +342,7 @@ func (w *SettingsWindow) browsePluginScriptConfig() {
 				}
 			}
 		}
-		// 如果plugin目录不存在，使用当前目录作为默认路径
+		// 如果plugin目录不存在，使用数据根目录作为默认路径
 		if uri := storage.NewFileURI(currentDir); uri != nil {
 			if lister, err := storage.ListerForURI(uri); err == nil {
 				fileDialog.SetLocation(lister)
@@ -0,0 +1,122 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+
+	"tiktok_tool/lkit"
+)
+
+// 后台任务窗口的刷新间隔
+const taskRefreshInterval = time.Second
+
+// 后台任务表格的列
+var taskColumns = []struct {
+	title string
+	width float32
+}{
+	{"名称", 260},
+	{"状态", 80},
+	{"运行时长", 90},
+	{"重启策略", 90},
+	{"重启", 50},
+	{"崩溃", 50},
+	{"最近崩溃原因", 260},
+}
+
+// showTaskWindow 显示当前运行的后台任务, 用于排查任务未退出或反复崩溃的问题
+func showTaskWindow(app fyne.App) {
+	taskWindow := app.NewWindow("后台任务")
+	var tasks []lkit.TaskInfo
+
+	table := widget.NewTableWithHeaders(
+		func() (int, int) {
+			return len(tasks), len(taskColumns)
+		},
+		func() fyne.CanvasObject {
+			label := widget.NewLabel("")
+			label.Truncation = fyne.TextTruncateEllipsis
+			return label
+		},
+		func(id widget.TableCellID, cell fyne.CanvasObject) {
+			if id.Row >= len(tasks) {
+				return
+			}
+			cell.(*widget.Label).SetText(taskCell(tasks[id.Row], id.Col))
+		},
+	)
+	table.ShowHeaderColumn = false
+	table.CreateHeader = func() fyne.CanvasObject {
+		return widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	}
+	table.UpdateHeader = func(id widget.TableCellID, cell fyne.CanvasObject) {
+		if id.Row < 0 && id.Col >= 0 {
+			cell.(*widget.Label).SetText(taskColumns[id.Col].title)
+		}
+	}
+	for i, column := range taskColumns {
+		table.SetColumnWidth(i, column.width)
+	}
+
+	status := widget.NewLabel("")
+	refresh := func() {
+		tasks = lkit.Tasks()
+		status.SetText(fmt.Sprintf("共 %d 个后台任务", len(tasks)))
+		table.Refresh()
+	}
+	refresh()
+
+	stop := make(chan struct{})
+	taskWindow.SetOnClosed(func() {
+		close(stop)
+	})
+	lkit.Go("ui.task_window", func(ctx context.Context) {
+		ticker := time.NewTicker(taskRefreshInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				fyne.Do(func() {
+					select {
+					case <-stop:
+					default:
+						refresh()
+					}
+				})
+			}
+		}
+	})
+
+	taskWindow.SetContent(container.NewBorder(nil, status, nil, nil, table))
+	taskWindow.Resize(fyne.NewSize(900, 400))
+	taskWindow.Show()
+}
+
+// taskCell 任务表格单元格内容
+func taskCell(task lkit.TaskInfo, col int) string {
+	switch col {
+	case 0:
+		return task.Name
+	case 1:
+		return task.State
+	case 2:
+		return time.Since(task.Started).Truncate(time.Second).String()
+	case 3:
+		return task.Policy.String()
+	case 4:
+		return fmt.Sprint(task.Restarts)
+	case 5:
+		return fmt.Sprint(task.Panics)
+	default:
+		return task.LastPanic
+	}
+}